@@ -0,0 +1,208 @@
+// Command gen scaffolds a new resource following the project's layering: model,
+// migration, repository, service, handler and their tests, plus the route and i18n
+// wiring. Usage (from the repository root):
+//
+//	go run ./cmd/gen [-force] [-dry-run] [-no-tests] Product name:string:required sku:string:unique,size=32 price:float
+//
+// Fields are name:kind[:modifier,...] with kinds string, text, int, int64, uint,
+// float, bool, time and json, and modifiers required, unique, index and size=N.
+// Running it again is safe: unchanged files are left alone and files that differ
+// from what would be generated are only overwritten with -force.
+package main
+
+import (
+	"bytes"
+	"embed"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var templatesFS embed.FS
+
+var templates = template.Must(template.New("").ParseFS(templatesFS, "templates/*.tmpl"))
+
+func main() {
+	force := flag.Bool("force", false, "overwrite files that differ from the generated ones")
+	dryRun := flag.Bool("dry-run", false, "only print what would change")
+	noTests := flag.Bool("no-tests", false, "do not generate tests")
+	root := flag.String("root", ".", "repository root")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gen [flags] Name field:kind[:modifiers] ...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	res, err := newResource(flag.Arg(0), flag.Args()[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 1. Work out every change before touching the tree
+	changes, err := plan(*root, res, !*noTests)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var conflicts int
+	for _, ch := range changes {
+		if ch.action == actionConflict && !*force {
+			conflicts++
+		}
+	}
+
+	// 2. Report, and write unless something would be overwritten
+	for _, ch := range changes {
+		action := ch.action
+		if action == actionConflict && *force {
+			action = actionOverwrite
+		}
+		fmt.Printf("%-10s %s\n", action, ch.path)
+	}
+	if conflicts > 0 {
+		log.Fatalf("%d file(s) already exist with different content; rerun with -force to overwrite", conflicts)
+	}
+	if *dryRun {
+		return
+	}
+	written, err := apply(changes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if written == 0 {
+		fmt.Println("\nNothing to do.")
+		return
+	}
+	fmt.Printf("\nAdded %s. Translate the new %s keys in es.json and my.json, which got the English text.\n",
+		res.Path, res.Name)
+}
+
+const (
+	actionCreate    = "create"
+	actionUpdate    = "update"
+	actionUnchanged = "unchanged"
+	actionConflict  = "conflict"
+	actionOverwrite = "overwrite"
+)
+
+type change struct {
+	path    string
+	content []byte
+	action  string
+}
+
+// plan renders the resource files and the edits to the shared ones
+func plan(root string, res *resource, tests bool) ([]change, error) {
+	files := []struct{ template, path string }{
+		{"model.go.tmpl", "internal/models/%s.go"},
+		{"repository.go.tmpl", "internal/repository/%s_repo.go"},
+		{"service.go.tmpl", "internal/service/%s_service.go"},
+		{"handler.go.tmpl", "internal/handlers/%s_handler.go"},
+	}
+	if tests {
+		files = append(files,
+			struct{ template, path string }{"repository_test.go.tmpl", "internal/repository/%s_repo_test.go"},
+			struct{ template, path string }{"handler_test.go.tmpl", "internal/handlers/%s_handler_test.go"},
+		)
+		// The service test checks required fields, so it needs at least one
+		if len(res.RequiredFields()) > 0 {
+			files = append(files, struct{ template, path string }{"service_test.go.tmpl", "internal/service/%s_service_test.go"})
+		}
+	}
+
+	var changes []change
+	for _, f := range files {
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, f.template, res); err != nil {
+			return nil, err
+		}
+		content, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.template, err)
+		}
+		path := filepath.Join(root, fmt.Sprintf(f.path, res.File))
+		ch, err := fileChange(path, content)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, ch)
+	}
+
+	ch, err := migrationChange(root, res)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, ch)
+
+	wiring, err := wire(root, res)
+	if err != nil {
+		return nil, err
+	}
+	return append(changes, wiring...), nil
+}
+
+// apply writes the files that changed and returns how many there were
+func apply(changes []change) (int, error) {
+	written := 0
+	for _, ch := range changes {
+		if ch.action == actionUnchanged {
+			continue
+		}
+		written++
+		if err := os.MkdirAll(filepath.Dir(ch.path), 0o755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(ch.path, ch.content, 0o644); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// migrationChange renders the migration creating the table. Its file name starts with
+// the time it was first generated, so later runs look the file up by table instead.
+func migrationChange(root string, res *resource) (change, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, "migration.go.tmpl", res); err != nil {
+		return change{}, err
+	}
+	content, err := format.Source(buf.Bytes())
+	if err != nil {
+		return change{}, fmt.Errorf("migration.go.tmpl: %w", err)
+	}
+
+	dir := filepath.Join(root, "internal/migrations")
+	existing, err := filepath.Glob(filepath.Join(dir, "*_create_"+res.Table+".go"))
+	if err != nil {
+		return change{}, err
+	}
+	path := filepath.Join(dir, time.Now().UTC().Format("20060102150405")+"_create_"+res.Table+".go")
+	if len(existing) > 0 {
+		path = existing[0]
+	}
+	return fileChange(path, content)
+}
+
+// fileChange compares content with what is on disk
+func fileChange(path string, content []byte) (change, error) {
+	existing, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return change{path: path, content: content, action: actionCreate}, nil
+	case err != nil:
+		return change{}, err
+	case bytes.Equal(existing, content):
+		return change{path: path, action: actionUnchanged}, nil
+	default:
+		return change{path: path, content: content, action: actionConflict}, nil
+	}
+}
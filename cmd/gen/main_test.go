@@ -0,0 +1,143 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestGeneratedResourceBuilds generates a resource with every field kind into a copy
+// of the repository, which must then build and vet, and be left alone by a second run
+func TestGeneratedResourceBuilds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a copy of the repository")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	root := copyRepository(t)
+
+	res, err := newResource("StockItem", []string{
+		"name:string:required",
+		"sku:string:unique,size=32",
+		"description:text",
+		"quantity:int:required",
+		"views:int64",
+		"owner_id:uint:index",
+		"price:float:required",
+		"active:bool",
+		"released_at:time:required",
+		"discontinued_at:time",
+		"attributes:json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. First run creates the files and wires the resource in
+	changes, err := plan(root, res, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range changes {
+		if ch.action != actionCreate && ch.action != actionUpdate {
+			t.Errorf("first run: %s %s", ch.action, ch.path)
+		}
+	}
+	if _, err := apply(changes); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. The tree still compiles, generated tests included
+	for _, args := range [][]string{{"build", "./..."}, {"vet", "./..."}} {
+		cmd := exec.Command(goBin, args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %v: %v\n%s", args, err, out)
+		}
+	}
+
+	// 3. Running it again changes nothing
+	changes, err = plan(root, res, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range changes {
+		if ch.action != actionUnchanged {
+			t.Errorf("second run: %s %s", ch.action, ch.path)
+		}
+	}
+
+	// 4. A generated file edited by hand is reported, not overwritten
+	model := filepath.Join(root, "internal/models/stock_item.go")
+	if err := os.WriteFile(model, []byte("package models\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changes, err = plan(root, res, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range changes {
+		want := actionUnchanged
+		if ch.path == model {
+			want = actionConflict
+		}
+		if ch.action != want {
+			t.Errorf("after editing the model: %s %s, want %s", ch.action, ch.path, want)
+		}
+	}
+}
+
+// copyRepository copies the Go sources of the repository into a temporary directory;
+// the vendored modules are linked rather than copied
+func copyRepository(t *testing.T) string {
+	t.Helper()
+	src, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := t.TempDir()
+
+	for _, name := range []string{"go.mod", "go.sum", "cmd", "internal", "pkg"} {
+		err := filepath.Walk(filepath.Join(src, name), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(src, path)
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+			}
+			return copyFile(path, filepath.Join(dst, rel))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(src, "vendor"), filepath.Join(dst, "vendor")); err != nil {
+		t.Fatal(err)
+	}
+	return dst
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
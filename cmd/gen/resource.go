@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jinzhu/inflection"
+	"gorm.io/gorm/schema"
+)
+
+// Field kinds accepted in the field list, with their Go types
+var fieldKinds = map[string]string{
+	"string": "string",
+	"text":   "string",
+	"int":    "int",
+	"int64":  "int64",
+	"uint":   "uint",
+	"float":  "float64",
+	"bool":   "bool",
+	"time":   "time.Time",
+	"json":   "JSONMap",
+}
+
+// Go initialisms, so `owner_id` becomes OwnerID like GORM expects
+var initialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "API": true, "IP": true, "UUID": true, "HTTP": true, "JSON": true,
+}
+
+// Columns and fields the generated model already has
+var reservedFields = map[string]bool{
+	"Model": true, "ID": true, "CreatedAt": true, "UpdatedAt": true, "DeletedAt": true, "DeletedID": true,
+}
+
+var identPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+var naming = schema.NamingStrategy{}
+
+// resource is what the templates are rendered with, e.g. for "order_item":
+// Name OrderItem, Var orderItem, File order_item, Table order_items,
+// Path /order-items, Label order item, LabelPlural order items
+type resource struct {
+	Name        string
+	Var         string
+	File        string
+	Table       string
+	Path        string
+	Label       string
+	LabelPlural string
+	Fields      []field
+}
+
+type field struct {
+	Name     string // Go field name
+	Column   string // column and JSON name
+	Kind     string // key of fieldKinds
+	Type     string // Go type
+	Required bool
+	Unique   bool
+	Index    bool
+	Size     int // maximum length of string fields
+}
+
+func newResource(name string, specs []string) (*resource, error) {
+	words := splitWords(name)
+	if len(words) == 0 || !identPattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid resource name %q", name)
+	}
+
+	r := &resource{Name: pascal(words, false)}
+	r.Var = strings.ToLower(r.Name[:1]) + r.Name[1:]
+	r.File = strings.Join(words, "_")
+	r.Table = naming.TableName(r.Name)
+	pluralWords := append(append([]string{}, words[:len(words)-1]...), inflection.Plural(words[len(words)-1]))
+	r.Path = "/" + strings.Join(pluralWords, "-")
+	r.Label = strings.Join(words, " ")
+	r.LabelPlural = strings.Join(pluralWords, " ")
+
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		f, err := parseField(spec)
+		if err != nil {
+			return nil, err
+		}
+		if seen[f.Name] {
+			return nil, fmt.Errorf("field %q is listed twice", f.Column)
+		}
+		seen[f.Name] = true
+		r.Fields = append(r.Fields, f)
+	}
+	return r, nil
+}
+
+// parseField reads `name:kind[:modifier,...]`, e.g. `sku:string:required,unique,size=32`
+func parseField(spec string) (field, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 || !identPattern.MatchString(parts[0]) {
+		return field{}, fmt.Errorf("invalid field %q, expected name:kind[:modifiers]", spec)
+	}
+
+	words := splitWords(parts[0])
+	f := field{Name: pascal(words, true), Kind: parts[1]}
+	f.Column = naming.ColumnName("", f.Name)
+	if reservedFields[f.Name] {
+		return field{}, fmt.Errorf("field %q is already part of every model", parts[0])
+	}
+	goType, ok := fieldKinds[f.Kind]
+	if !ok {
+		return field{}, fmt.Errorf("field %q has unknown kind %q", parts[0], f.Kind)
+	}
+	f.Type = goType
+	if f.Kind == "string" {
+		f.Size = 255
+	}
+
+	if len(parts) == 3 {
+		for _, modifier := range strings.Split(parts[2], ",") {
+			switch {
+			case modifier == "required":
+				f.Required = true
+			case modifier == "unique":
+				f.Unique = true
+			case modifier == "index":
+				f.Index = true
+			case strings.HasPrefix(modifier, "size=") && f.Kind == "string":
+				size, err := strconv.Atoi(strings.TrimPrefix(modifier, "size="))
+				if err != nil || size < 1 || size > 16383 {
+					return field{}, fmt.Errorf("field %q has an invalid size", parts[0])
+				}
+				f.Size = size
+			default:
+				return field{}, fmt.Errorf("field %q has unknown modifier %q", parts[0], modifier)
+			}
+		}
+	}
+
+	switch {
+	case f.Required && (f.Kind == "bool" || f.Kind == "json"):
+		return field{}, fmt.Errorf("%s field %q cannot be required", f.Kind, parts[0])
+	case (f.Unique || f.Index) && (f.Kind == "text" || f.Kind == "json"):
+		return field{}, fmt.Errorf("%s field %q cannot be indexed", f.Kind, parts[0])
+	}
+	if f.Kind == "time" && !f.Required {
+		f.Type = "*time.Time"
+	}
+	return f, nil
+}
+
+// GormTag is the gorm struct tag of the field. Columns are NOT NULL unless optional
+// times, so they can be sorted on with keyset pagination.
+func (f field) GormTag(table string) string {
+	var settings []string
+	switch f.Kind {
+	case "string":
+		settings = append(settings, fmt.Sprintf("size:%d", f.Size))
+	case "text", "json":
+		settings = append(settings, "type:text")
+	}
+	if f.Unique {
+		settings = append(settings, fmt.Sprintf("uniqueIndex:%s", f.liveIndex(table)))
+	} else if f.Index {
+		settings = append(settings, "index")
+	}
+	if f.Type != "*time.Time" {
+		settings = append(settings, "not null")
+	}
+	return strings.Join(settings, ";")
+}
+
+// liveIndex is the (column, deleted_id) unique index, see models.User
+func (f field) liveIndex(table string) string {
+	return fmt.Sprintf("idx_%s_%s_live", table, f.Column)
+}
+
+// ColumnType is the Go type of the field in its migration, which only needs the column
+func (f field) ColumnType() string {
+	if f.Kind == "json" {
+		return "string"
+	}
+	return f.Type
+}
+
+// Sortable fields are compared by keyset pagination, so they must be NOT NULL
+func (f field) Sortable() bool {
+	return f.Type != "*time.Time" && f.Kind != "text" && f.Kind != "json"
+}
+
+func (f field) Searchable() bool {
+	return f.Kind == "string" || f.Kind == "text"
+}
+
+func (f field) Filterable() bool {
+	return f.Kind != "json"
+}
+
+// SampleValue is a Go literal that fits the column, for the generated repository test
+func (f field) SampleValue() string {
+	switch f.Kind {
+	case "string", "text":
+		return `"x"`
+	case "float":
+		return "1.5"
+	case "bool":
+		return "true"
+	case "time":
+		return "time.Now()"
+	}
+	return "1"
+}
+
+// SampleFields are the fields the repository test sets: the required and the unique
+// ones. Optional times are pointers and may stay NULL.
+func (r *resource) SampleFields() []field {
+	var fields []field
+	for _, f := range r.Fields {
+		if (f.Required || f.Unique) && f.Type != "*time.Time" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// MigrationName names the functions of the table's migration, e.g. CreateOrderItems
+func (r *resource) MigrationName() string {
+	return "Create" + pascal(strings.Split(r.Table, "_"), false)
+}
+
+func (r *resource) UniqueFields() []field {
+	var fields []field
+	for _, f := range r.Fields {
+		if f.Unique {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (r *resource) RequiredFields() []field {
+	var fields []field
+	for _, f := range r.Fields {
+		if f.Required {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (r *resource) SizedFields() []field {
+	var fields []field
+	for _, f := range r.Fields {
+		if f.Size > 0 {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (r *resource) SearchColumns() []string {
+	var columns []string
+	for _, f := range r.Fields {
+		if f.Searchable() {
+			columns = append(columns, r.Table+"."+f.Column)
+		}
+	}
+	return columns
+}
+
+// DeletedIDTag puts deleted_id into the unique index of every unique field
+func (r *resource) DeletedIDTag() string {
+	var settings []string
+	for _, f := range r.UniqueFields() {
+		settings = append(settings, "uniqueIndex:"+f.liveIndex(r.Table))
+	}
+	return strings.Join(append(settings, "not null", "default:0"), ";")
+}
+
+func (r *resource) HasKind(kinds ...string) bool {
+	for _, f := range r.Fields {
+		for _, kind := range kinds {
+			if f.Kind == kind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *resource) HasRequiredKind(kinds ...string) bool {
+	for _, f := range r.RequiredFields() {
+		for _, kind := range kinds {
+			if f.Kind == kind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitWords turns "OrderItem", "order_item" or "order-item" into [order item]
+func splitWords(name string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+	runes := []rune(name)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = nil
+		}
+	}
+	for i, c := range runes {
+		switch {
+		case c == '_' || c == '-':
+			flush()
+			continue
+		case unicode.IsUpper(c) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))):
+			flush()
+		}
+		word = append(word, c)
+	}
+	flush()
+	return words
+}
+
+func pascal(words []string, useInitialisms bool) string {
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); useInitialisms && initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
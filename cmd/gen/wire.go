@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Marker comment in the router; generated routes go right above it
+const routesMarker = "// cmd/gen adds generated resources above this line"
+
+// wire adds the resource to the router and the translations
+func wire(root string, res *resource) ([]change, error) {
+	var changes []change
+
+	routes := fmt.Sprintf("\t// %s (generated by cmd/gen)\n"+
+		"\t%sHandler := handlers.New%sHandler(service.New%sService(repository.New%sRepository(db)))\n"+
+		"\t%sHandler.RegisterRoutes(v1, authMiddleware, presenceMiddleware)\n\n",
+		capitalize(res.LabelPlural), res.Var, res.Name, res.Name, res.Name, res.Var)
+	ch, err := insertAboveMarker(filepath.Join(root, "internal/router/router.go"), routesMarker, routes)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, ch)
+
+	for _, lang := range []string{"en", "es", "my"} {
+		ch, err := addTranslations(filepath.Join(root, "internal/i18n", lang+".json"), translations(res))
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, ch)
+	}
+	return changes, nil
+}
+
+// insertAboveMarker adds snippet before the marker line, unless the file has it already
+func insertAboveMarker(path, marker, snippet string) (change, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return change{}, err
+	}
+	if bytes.Contains(content, []byte(snippet)) {
+		return change{path: path, action: actionUnchanged}, nil
+	}
+
+	i := bytes.Index(content, []byte(marker))
+	if i < 0 {
+		return change{}, fmt.Errorf("%s: marker %q not found", path, marker)
+	}
+	lineStart := bytes.LastIndexByte(content[:i], '\n') + 1
+
+	var out bytes.Buffer
+	out.Write(content[:lineStart])
+	out.WriteString(snippet)
+	out.Write(content[lineStart:])
+	return change{path: path, content: out.Bytes(), action: actionUpdate}, nil
+}
+
+// translations are the keys CrudHandler looks up for the resource, see handlers.CrudHandler
+func translations(res *resource) [][2]string {
+	label := capitalize(res.Label)
+	keys := [][2]string{
+		{res.Name + "Created", label + " created"},
+		{res.Name + "List", "List of " + res.LabelPlural},
+		{res.Name + "Retrieved", label + " retrieved"},
+		{res.Name + "Updated", label + " updated"},
+		{res.Name + "Deleted", label + " deleted"},
+		{res.Name + "PermanentlyDeleted", label + " permanently deleted"},
+		{res.Name + "Restored", label + " restored"},
+		{res.Name + "NotFound", label + " not found"},
+		{"Invalid" + res.Name + "ID", "Invalid " + res.Label + " ID"},
+	}
+	if len(res.UniqueFields()) > 0 {
+		keys = append(keys, [2]string{res.Name + "Conflict", "A " + res.Label + " with these values already exists"})
+	}
+	return keys
+}
+
+// addTranslations appends the missing keys at the end of a translation file,
+// keeping its formatting; existing translations are never changed
+func addTranslations(path string, keys [][2]string) (change, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return change{}, err
+	}
+	var existing map[string]string
+	if err := json.Unmarshal(content, &existing); err != nil {
+		return change{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Indentation of the file's first key
+	indent := "  "
+	if start := bytes.IndexByte(content, '"'); start > 0 {
+		line := content[bytes.LastIndexByte(content[:start], '\n')+1 : start]
+		indent = string(line)
+	}
+
+	var lines []string
+	for _, kv := range keys {
+		if _, ok := existing[kv[0]]; ok {
+			continue
+		}
+		key, _ := json.Marshal(kv[0])
+		value, _ := json.Marshal(kv[1])
+		lines = append(lines, fmt.Sprintf("%s%s: %s", indent, key, value))
+	}
+	if len(lines) == 0 {
+		return change{path: path, action: actionUnchanged}, nil
+	}
+
+	end := bytes.LastIndexByte(content, '}')
+	body := strings.TrimRight(string(content[:end]), " \t\r\n")
+	if !strings.HasSuffix(body, "{") {
+		body += ","
+	}
+	out := body + "\n\n" + strings.Join(lines, ",\n") + "\n}\n"
+	if !json.Valid([]byte(out)) {
+		return change{}, fmt.Errorf("%s: could not add translations", path)
+	}
+	return change{path: path, content: []byte(out), action: actionUpdate}, nil
+}
@@ -0,0 +1,218 @@
+// Command migrate applies, reverts and inspects the versioned schema migrations of
+// internal/migrations. Usage (from the repository root):
+//
+//	go run ./cmd/migrate [-dry-run] up [N]       apply all pending migrations, or the next N
+//	go run ./cmd/migrate [-dry-run] down N       revert the last N applied migrations
+//	go run ./cmd/migrate status                  list migrations and whether they are applied
+//	go run ./cmd/migrate create NAME             add internal/migrations/<timestamp>_NAME.go
+//	go run ./cmd/migrate force [-unapplied] VERSION
+//	                                             record VERSION as applied (or not) without running it
+//
+// With -dry-run, up and down print the SQL they would execute instead of running it.
+// The database settings come from the same environment as the server.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/migrations"
+)
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "print the SQL of up / down instead of running it")
+	dir := flag.String("dir", "internal/migrations", "directory create writes migrations to")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: migrate [flags] up [N] | down N | status | create NAME | force [-unapplied] VERSION")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, args := flag.Arg(0), flag.Args()[1:]
+
+	// create only writes a file, it needs no database
+	if command == "create" {
+		if len(args) != 1 {
+			usageError("create needs a NAME, e.g. add_user_nickname")
+		}
+		path, err := create(*dir, args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Created", path)
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+	db, err := config.SetupDatabase(cfg.Database)
+	if err != nil {
+		log.Fatalf("DB setup error: %v", err)
+	}
+	migrator := migrations.NewMigrator(db)
+	if *dryRun {
+		migrator.DryRun = os.Stdout
+	}
+
+	switch command {
+	case "up":
+		limit := 0
+		if len(args) > 0 {
+			limit = positive(args[0])
+		}
+		count, err := migrator.Up(limit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		report(count, "applied", *dryRun)
+
+	case "down":
+		if len(args) != 1 {
+			usageError("down needs the number of migrations to revert, e.g. down 1")
+		}
+		count, err := migrator.Down(positive(args[0]))
+		if err != nil {
+			log.Fatal(err)
+		}
+		report(count, "reverted", *dryRun)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		printStatus(statuses)
+
+	case "force":
+		fs := flag.NewFlagSet("force", flag.ExitOnError)
+		unapplied := fs.Bool("unapplied", false, "remove the record instead of marking it applied")
+		fs.Parse(args)
+		if fs.NArg() != 1 {
+			usageError("force needs a VERSION")
+		}
+		version, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+		if err != nil {
+			usageError("invalid version " + fs.Arg(0))
+		}
+		if err := migrator.Force(version, !*unapplied); err != nil {
+			log.Fatal(err)
+		}
+		if *unapplied {
+			fmt.Printf("Recorded %d as not applied\n", version)
+		} else {
+			fmt.Printf("Recorded %d as applied\n", version)
+		}
+
+	default:
+		usageError("unknown command " + command)
+	}
+}
+
+func report(count int, done string, dryRun bool) {
+	switch {
+	case dryRun:
+		fmt.Printf("-- dry run: %d migration(s) would be %s\n", count, done)
+	case count == 0:
+		fmt.Println("Nothing to do.")
+	default:
+		fmt.Printf("%d migration(s) %s\n", count, done)
+	}
+}
+
+func printStatus(statuses []migrations.MigrationStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		status := "pending"
+		switch {
+		case s.Dirty:
+			status = "dirty"
+		case s.Missing:
+			status = "applied, not in this build"
+		case s.Applied:
+			status = "applied"
+		}
+		appliedAt := ""
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+	}
+	w.Flush()
+}
+
+// create writes an empty migration named <UTC timestamp>_<name>.go
+func create(dir, name string) (string, error) {
+	name = strings.ToLower(strings.ReplaceAll(name, "-", "_"))
+	if !namePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid migration name %q, use letters, digits and underscores", name)
+	}
+	// The step functions are named after the migration, so names must be unique
+	if existing, _ := filepath.Glob(filepath.Join(dir, "*_"+name+".go")); len(existing) > 0 {
+		return "", fmt.Errorf("%s already exists", existing[0])
+	}
+
+	var camel strings.Builder
+	for _, word := range strings.Split(name, "_") {
+		if word != "" {
+			camel.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(up%[1]s, down%[1]s)
+}
+
+func up%[1]s(tx *gorm.DB) error {
+	return nil
+}
+
+func down%[1]s(tx *gorm.DB) error {
+	return nil
+}
+`, camel.String())
+	content, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, time.Now().UTC().Format("20060102150405")+"_"+name+".go")
+	return path, os.WriteFile(path, content, 0o644)
+}
+
+func positive(arg string) int {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		usageError("expected a positive number, got " + arg)
+	}
+	return n
+}
+
+func usageError(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	flag.Usage()
+	os.Exit(2)
+}
@@ -0,0 +1,120 @@
+// Command seed fills the database with seed data, see package seed. Usage (from the
+// repository root, after `go run ./cmd/migrate up`):
+//
+//	go run ./cmd/seed [-set dev] [FILE|DIR ...]   apply a seed set, then the given fixtures
+//	go run ./cmd/seed create-admin [-name NAME] [-email EMAIL]
+//	                                             prompt for credentials and create an admin
+//
+// Sets are base, dev, test and demo. The database settings come from the same
+// environment as the server.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/migrations"
+	"golang-api-template/internal/seed"
+
+	"golang.org/x/term"
+	"gorm.io/gorm"
+)
+
+// Same minimum as accepted invitations
+const minPasswordLength = 8
+
+func main() {
+	set := flag.String("set", seed.SetDev, fmt.Sprintf("seed set to apply, one of %v", seed.Sets()))
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: seed [-set SET] [FILE|DIR ...] | create-admin [-name NAME] [-email EMAIL]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+	db, err := config.SetupDatabase(cfg.Database)
+	if err != nil {
+		log.Fatalf("DB setup error: %v", err)
+	}
+	if pending, err := migrations.NewMigrator(db).Pending(); err != nil {
+		log.Fatalf("Error checking migrations: %v", err)
+	} else if len(pending) > 0 {
+		log.Fatalf("%d pending migration(s), run `go run ./cmd/migrate up` first", len(pending))
+	}
+
+	if flag.Arg(0) == "create-admin" {
+		createAdmin(db, flag.Args()[1:])
+		return
+	}
+
+	if err := seed.Run(db, *set, flag.Args()...); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Seeded the %s set\n", *set)
+}
+
+func createAdmin(db *gorm.DB, args []string) {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	name := fs.String("name", "", "name of the admin, prompted for when empty")
+	email := fs.String("email", "", "email of the admin, prompted for when empty")
+	fs.Parse(args)
+
+	in := bufio.NewReader(os.Stdin)
+	if *name == "" {
+		*name = prompt(in, "Name: ")
+	}
+	if *email == "" {
+		*email = prompt(in, "Email: ")
+	}
+	if *name == "" || !strings.Contains(*email, "@") {
+		log.Fatal("A name and a valid email are required")
+	}
+
+	password := promptPassword(in, "Password: ")
+	if len(password) < minPasswordLength {
+		log.Fatalf("The password must have at least %d characters", minPasswordLength)
+	}
+	if promptPassword(in, "Repeat password: ") != password {
+		log.Fatal("The passwords do not match")
+	}
+
+	user, err := seed.CreateAdmin(db, *name, *email, password)
+	if errors.Is(err, seed.ErrUserExists) {
+		log.Fatalf("%s already has an account", *email)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Created admin %s (ID %d)\n", user.Email, user.ID)
+}
+
+func prompt(in *bufio.Reader, label string) string {
+	fmt.Print(label)
+	line, _ := in.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// promptPassword reads without echo on a terminal, and a plain line when the
+// input is piped, e.g. from a provisioning script
+func promptPassword(in *bufio.Reader, label string) string {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return prompt(in, label)
+	}
+	fmt.Print(label)
+	password, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return string(password)
+}
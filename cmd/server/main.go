@@ -2,17 +2,22 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"time"
 
 	"golang-api-template/internal/config"
 	"golang-api-template/internal/migrations"
 	"golang-api-template/internal/router"
+	"golang-api-template/internal/seed"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
 func main() {
+	seedSet := flag.String("seed", "", "apply a seed set (base, dev, test or demo) after migrating")
+	flag.Parse()
+
 	// Load main config
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -20,12 +25,24 @@ func main() {
 	}
 
 	// Setup DB
-	db, err := config.SetupDatabase(cfg)
+	db, err := config.SetupDatabase(cfg.Database)
 	if err != nil {
 		log.Fatalf("DB setup error: %v", err)
 	}
-	if err := migrations.AutoMigrateDatabase(db); err != nil {
-		log.Fatalf("Error migrating database: %v", err)
+	migrator := migrations.NewMigrator(db)
+	if cfg.Database.MigrateOnStart {
+		if _, err := migrator.Up(0); err != nil {
+			log.Fatalf("Error migrating database: %v", err)
+		}
+	} else if pending, err := migrator.Pending(); err != nil {
+		log.Fatalf("Error checking migrations: %v", err)
+	} else if len(pending) > 0 {
+		log.Printf("Warning: %d pending migration(s), run `go run ./cmd/migrate up`", len(pending))
+	}
+	if *seedSet != "" {
+		if err := seed.Run(db, *seedSet); err != nil {
+			log.Fatalf("Error seeding database: %v", err)
+		}
 	}
 
 	// emailConfig := config.GetEmailConfig()
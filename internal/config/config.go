@@ -1,24 +1,24 @@
 package config
 
 import (
-	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
 )
 
 type Config struct {
-	DBHost     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	DBPort     string
-	Port       string
+	Port string
+
+	// Public base URL of the API, used to build links in emails
+	AppURL string
+
+	// Reverse proxies (IPs or CIDRs) whose X-Forwarded-* headers are believed, for the
+	// client IP and for page links. Empty trusts none.
+	TrustedProxies []string
 
 	// JWT secrets & expirations
 	JWTAccessSecret       string
@@ -27,8 +27,51 @@ type Config struct {
 	RefreshTokenExpireHrs int
 	RestTokenExpireInMin  int
 
+	// Invite links are JWTs signed with their own secret
+	InvitationSecret    string
+	InvitationExpireHrs int
+
+	// Lifetime of "act as" tokens issued to support staff
+	ImpersonationTokenExpireMin int
+
+	// Step-up re-authentication: how recent the login must be for sensitive
+	// operations, and how long the elevated token from /auth/reauthenticate lives
+	StepUpMaxAgeSec      int
+	StepUpTokenExpireMin int
+
+	// Email changes: how long the new address has to confirm, and how long
+	// the old address can still revert the change
+	EmailChangeExpireHrs int
+	EmailChangeRevertHrs int
+
+	// Account lifecycle: grace period before a scheduled deletion runs, how often
+	// due deletions are purged, and how long AuthMiddleware caches an account's status
+	AccountDeletionGraceDays int
+	AccountPurgeInterval     time.Duration
+	AccountStatusCacheTTL    time.Duration
+
+	// GDPR data exports are kept in the storage backend and removed after DataExportTTLHrs
+	DataExportTTLHrs int
+
+	// Avatars larger than AvatarMaxBytes are rejected; the rest are resized to AvatarSize pixels square
+	AvatarMaxBytes int64
+	AvatarSize     int
+
+	// A user counts as online for this long after their last request / heartbeat
+	PresenceTTLSec int
+
+	// Optional offline GeoIP city database (MaxMind .mmdb format) used for login alerts
+	GeoIPDBPath string
+
 	// ... possibly more fields
-	Redis *RedisConfig
+	Database   *DatabaseConfig
+	Redis      *RedisConfig
+	OIDC       *OIDCConfig
+	LDAP       *LDAPConfig
+	Cookie     *CookieConfig
+	Realtime   *RealtimeConfig
+	Storage    *StorageConfig
+	Pagination *PaginationConfig
 }
 
 func LoadConfig() (*Config, error) {
@@ -39,14 +82,32 @@ func LoadConfig() (*Config, error) {
 	accessExp, _ := strconv.Atoi(getEnv("ACCESS_TOKEN_EXPIRE_MIN", "15"))
 	refreshExp, _ := strconv.Atoi(getEnv("REFRESH_TOKEN_EXPIRE_HOUR", "72"))
 	resetTokenExpire, _ := strconv.Atoi(getEnv("RESET_TOKEN_EXPIRY_MIN", "15"))
+	invitationExp, _ := strconv.Atoi(getEnv("INVITATION_EXPIRE_HOUR", "72"))
+	impersonationExp, _ := strconv.Atoi(getEnv("IMPERSONATION_TOKEN_EXPIRE_MIN", "10"))
+	presenceTTL, _ := strconv.Atoi(getEnv("PRESENCE_TTL_SEC", "900"))
+	stepUpMaxAge, _ := strconv.Atoi(getEnv("STEP_UP_MAX_AGE_SEC", "300"))
+	stepUpExp, _ := strconv.Atoi(getEnv("STEP_UP_TOKEN_EXPIRE_MIN", "5"))
+	deletionGrace, _ := strconv.Atoi(getEnv("ACCOUNT_DELETION_GRACE_DAYS", "30"))
+	purgeIntervalMin, _ := strconv.Atoi(getEnv("ACCOUNT_PURGE_INTERVAL_MIN", "60"))
+	statusCacheSec, _ := strconv.Atoi(getEnv("ACCOUNT_STATUS_CACHE_SEC", "30"))
+	avatarMax, _ := strconv.ParseInt(getEnv("AVATAR_MAX_BYTES", "5242880"), 10, 64)
+	avatarSize, _ := strconv.Atoi(getEnv("AVATAR_SIZE", "256"))
+	exportTTL, _ := strconv.Atoi(getEnv("DATA_EXPORT_TTL_HOUR", "72"))
+	emailChangeExp, _ := strconv.Atoi(getEnv("EMAIL_CHANGE_EXPIRE_HOUR", "24"))
+	emailChangeRevert, _ := strconv.Atoi(getEnv("EMAIL_CHANGE_REVERT_HOUR", "168"))
+
+	var trustedProxies []string
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
 
 	cfg := &Config{
-		DBHost:     getEnv("DB_HOST", "127.0.0.1"),
-		DBUser:     getEnv("DB_USER", "root"),
-		DBPassword: getEnv("DB_PASSWORD", ""),
-		DBName:     getEnv("DB_NAME", "demo"),
-		DBPort:     getEnv("DB_PORT", "3306"),
-		Port:       getEnv("PORT", "8080"),
+		Port: getEnv("PORT", "8080"),
+
+		AppURL:         strings.TrimRight(getEnv("APP_URL", "http://localhost:8080"), "/"),
+		TrustedProxies: trustedProxies,
 
 		JWTAccessSecret:       getEnv("JWT_ACCESS_SECRET", "access-secret-example"),
 		JWTRefreshSecret:      getEnv("JWT_REFRESH_SECRET", "refresh-secret-example"),
@@ -54,24 +115,39 @@ func LoadConfig() (*Config, error) {
 		AccessTokenExpireMin:  accessExp,
 		RefreshTokenExpireHrs: refreshExp,
 
-		Redis: LoadRedisConfig(), // from redis.go
-	}
-	return cfg, nil
-}
+		InvitationSecret:    getEnv("INVITATION_SECRET", "invitation-secret-example"),
+		InvitationExpireHrs: invitationExp,
 
-func SetupDatabase(cfg *Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.DBUser,
-		cfg.DBPassword,
-		cfg.DBHost,
-		cfg.DBPort,
-		cfg.DBName,
-	)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return nil, err
+		ImpersonationTokenExpireMin: impersonationExp,
+
+		StepUpMaxAgeSec:      stepUpMaxAge,
+		StepUpTokenExpireMin: stepUpExp,
+
+		EmailChangeExpireHrs: emailChangeExp,
+		EmailChangeRevertHrs: emailChangeRevert,
+
+		AccountDeletionGraceDays: deletionGrace,
+		AccountPurgeInterval:     time.Duration(purgeIntervalMin) * time.Minute,
+		AccountStatusCacheTTL:    time.Duration(statusCacheSec) * time.Second,
+
+		DataExportTTLHrs: exportTTL,
+
+		AvatarMaxBytes: avatarMax,
+		AvatarSize:     avatarSize,
+
+		PresenceTTLSec: presenceTTL,
+		GeoIPDBPath:    getEnv("GEOIP_DB_PATH", ""),
+
+		Database:   LoadDatabaseConfig(),   // from database.go
+		Redis:      LoadRedisConfig(),      // from redis.go
+		OIDC:       LoadOIDCConfig(),       // from oidc.go
+		LDAP:       LoadLDAPConfig(),       // from ldap.go
+		Cookie:     LoadCookieConfig(),     // from cookie.go
+		Realtime:   LoadRealtimeConfig(),   // from realtime.go
+		Storage:    LoadStorageConfig(),    // from storage.go
+		Pagination: LoadPaginationConfig(), // from pagination.go
 	}
-	return db, nil
+	return cfg, nil
 }
 
 // func AutoMigrate(db *gorm.DB) {
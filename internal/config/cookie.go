@@ -0,0 +1,46 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CookieConfig controls the optional cookie-based token transport for browser clients
+type CookieConfig struct {
+	Enabled  bool
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+
+	AccessName  string
+	RefreshName string
+	RefreshPath string // refresh cookie is only sent to the auth endpoints
+	CSRFName    string
+	CSRFHeader  string
+}
+
+// LoadCookieConfig from environment variables
+func LoadCookieConfig() *CookieConfig {
+	return &CookieConfig{
+		Enabled:     getEnv("AUTH_COOKIE_ENABLED", "false") == "true",
+		Domain:      getEnv("AUTH_COOKIE_DOMAIN", ""),
+		Secure:      getEnv("AUTH_COOKIE_SECURE", "true") == "true",
+		SameSite:    parseSameSite(getEnv("AUTH_COOKIE_SAMESITE", "lax")),
+		AccessName:  getEnv("AUTH_COOKIE_ACCESS_NAME", "access_token"),
+		RefreshName: getEnv("AUTH_COOKIE_REFRESH_NAME", "refresh_token"),
+		RefreshPath: getEnv("AUTH_COOKIE_REFRESH_PATH", "/api/v1/auth"),
+		CSRFName:    getEnv("AUTH_COOKIE_CSRF_NAME", "csrf_token"),
+		CSRFHeader:  getEnv("AUTH_CSRF_HEADER", "X-CSRF-Token"),
+	}
+}
+
+func parseSameSite(val string) http.SameSite {
+	switch strings.ToLower(val) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
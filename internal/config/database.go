@@ -0,0 +1,279 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Database drivers, the values of DB_DRIVER
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite" // needs cgo
+)
+
+// SQLiteMemory as the SQLite database name keeps the whole database in memory, e.g. for tests
+const SQLiteMemory = ":memory:"
+
+// ReplicaResolver names the read replicas in the GORM resolver. Queries sent with
+// db.Clauses(dbresolver.Use(ReplicaResolver)) read from a replica; everything else,
+// and everything inside a transaction, uses the primary.
+const ReplicaResolver = "read_replicas"
+
+// DatabaseConfig holds the database connection details
+type DatabaseConfig struct {
+	Driver   string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string // database name, or the file for SQLite
+	SSLMode  string // PostgreSQL only
+
+	// DSN, when set, is passed to the driver as is instead of the fields above
+	DSN string
+
+	// Apply pending migrations when the server starts; turn off to run cmd/migrate
+	// as a separate deploy step instead
+	MigrateOnStart bool
+
+	// Connection pool, for the primary and each replica. Zero keeps the database/sql
+	// default: no limit on open connections and no maximum lifetime.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ReplicaHosts are read replicas as "host" or "host:port", with the same user,
+	// password and database name as the primary; see ReplicaResolver
+	ReplicaHosts []string
+
+	// QueryTimeout bounds each statement whose context has no deadline yet; 0 disables it
+	QueryTimeout time.Duration
+
+	// ConnectTimeout is how long SetupDatabase keeps retrying while the database is
+	// not reachable, e.g. when it starts together with the app; 0 tries once
+	ConnectTimeout time.Duration
+}
+
+// LoadDatabaseConfig from environment variables; the defaults depend on DB_DRIVER
+func LoadDatabaseConfig() *DatabaseConfig {
+	driver := strings.ToLower(getEnv("DB_DRIVER", DriverMySQL))
+	maxOpen, _ := strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "25"))
+	maxIdle, _ := strconv.Atoi(getEnv("DB_MAX_IDLE_CONNS", "10"))
+	lifetimeMin, _ := strconv.Atoi(getEnv("DB_CONN_MAX_LIFETIME_MIN", "30"))
+	idleTimeMin, _ := strconv.Atoi(getEnv("DB_CONN_MAX_IDLE_TIME_MIN", "5"))
+	queryTimeoutSec, _ := strconv.Atoi(getEnv("DB_QUERY_TIMEOUT_SEC", "10"))
+	connectTimeoutSec, _ := strconv.Atoi(getEnv("DB_CONNECT_TIMEOUT_SEC", "60"))
+
+	var replicas []string
+	for _, host := range strings.Split(os.Getenv("DB_REPLICA_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			replicas = append(replicas, host)
+		}
+	}
+
+	port, user, name := "3306", "root", "demo"
+	switch driver {
+	case DriverPostgres:
+		port, user = "5432", "postgres"
+	case DriverSQLite:
+		name = "storage/demo.db"
+	}
+
+	return &DatabaseConfig{
+		Driver:         driver,
+		Host:           getEnv("DB_HOST", "127.0.0.1"),
+		Port:           getEnv("DB_PORT", port),
+		User:           getEnv("DB_USER", user),
+		Password:       os.Getenv("DB_PASSWORD"),
+		Name:           getEnv("DB_NAME", name),
+		SSLMode:        getEnv("DB_SSLMODE", "disable"),
+		DSN:            os.Getenv("DB_DSN"),
+		MigrateOnStart: getEnv("DB_MIGRATE_ON_START", "true") == "true",
+
+		MaxOpenConns:    maxOpen,
+		MaxIdleConns:    maxIdle,
+		ConnMaxLifetime: time.Duration(lifetimeMin) * time.Minute,
+		ConnMaxIdleTime: time.Duration(idleTimeMin) * time.Minute,
+		ReplicaHosts:    replicas,
+		QueryTimeout:    time.Duration(queryTimeoutSec) * time.Second,
+		ConnectTimeout:  time.Duration(connectTimeoutSec) * time.Second,
+	}
+}
+
+// Dialector returns the GORM dialector for the configured driver
+func (c *DatabaseConfig) Dialector() (gorm.Dialector, error) {
+	switch c.Driver {
+	case DriverMySQL:
+		dsn := c.DSN
+		if dsn == "" {
+			dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+				c.User, c.Password, c.Host, c.Port, c.Name)
+		}
+		return mysql.Open(dsn), nil
+
+	case DriverPostgres:
+		dsn := c.DSN
+		if dsn == "" {
+			u := url.URL{
+				Scheme:   "postgres",
+				User:     url.UserPassword(c.User, c.Password),
+				Host:     c.Host + ":" + c.Port,
+				Path:     c.Name,
+				RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+			}
+			dsn = u.String()
+		}
+		return postgres.Open(dsn), nil
+
+	case DriverSQLite:
+		dsn := c.DSN
+		if dsn == "" {
+			// Foreign keys are off by default in SQLite; the busy timeout makes
+			// concurrent writers wait for each other instead of failing
+			dsn = "file:" + c.Name + "?_foreign_keys=on&_busy_timeout=5000"
+			if c.Name == SQLiteMemory {
+				dsn = "file::memory:?_foreign_keys=on"
+			}
+		}
+		return sqlite.Open(dsn), nil
+
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q, expected %s, %s or %s", c.Driver, DriverMySQL, DriverPostgres, DriverSQLite)
+	}
+}
+
+// replica is the configuration of the replica at host, which may include a port
+func (c *DatabaseConfig) replica(host string) *DatabaseConfig {
+	replica := *c
+	replica.Host, replica.DSN, replica.ReplicaHosts = host, "", nil
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		replica.Host, replica.Port = h, port
+	}
+	return &replica
+}
+
+// SetupDatabase opens the configured database and its replicas, retrying with a
+// growing delay for up to cfg.ConnectTimeout while they are not reachable
+func SetupDatabase(cfg *DatabaseConfig) (*gorm.DB, error) {
+	if len(cfg.ReplicaHosts) > 0 && cfg.Driver == DriverSQLite {
+		return nil, fmt.Errorf("DB_REPLICA_HOSTS is not supported with %s", DriverSQLite)
+	}
+	if cfg.Driver == DriverSQLite && cfg.DSN == "" && cfg.Name != SQLiteMemory {
+		if err := os.MkdirAll(filepath.Dir(cfg.Name), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	// A bad DB_DRIVER will not get better by waiting
+	if _, err := cfg.Dialector(); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(cfg.ConnectTimeout)
+	wait := 500 * time.Millisecond
+	for {
+		db, err := openDatabase(cfg)
+		if err == nil {
+			return db, nil
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return nil, err
+		}
+		log.Printf("database: %v; retrying in %s", err, wait)
+		time.Sleep(wait)
+		wait = min(2*wait, 10*time.Second)
+	}
+}
+
+// applyPool sets the pool settings that are not zero; a zero MaxIdleConns would
+// otherwise mean no idle connections at all
+func (c *DatabaseConfig) applyPool(sqlDB *sql.DB) {
+	if c.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	if c.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	}
+}
+
+// openDatabase makes one attempt at connecting to the database and its replicas
+func openDatabase(cfg *DatabaseConfig) (*gorm.DB, error) {
+	dialector, err := cfg.Dialector()
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	// The pool must not outlive a failed attempt
+	fail := func(err error) (*gorm.DB, error) {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	// 1. Pool settings. Every connection to ":memory:" opens a new, empty database,
+	// so that keeps to one connection that is never closed.
+	cfg.applyPool(sqlDB)
+	if cfg.Driver == DriverSQLite && cfg.Name == SQLiteMemory && cfg.DSN == "" {
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	}
+
+	// 2. Read replicas
+	if len(cfg.ReplicaHosts) > 0 {
+		var replicas []gorm.Dialector
+		for _, host := range cfg.ReplicaHosts {
+			replica, err := cfg.replica(host).Dialector()
+			if err != nil {
+				return fail(err)
+			}
+			replicas = append(replicas, replica)
+		}
+		resolver := dbresolver.Register(dbresolver.Config{Replicas: replicas}, ReplicaResolver)
+		if err := db.Use(resolver); err != nil {
+			return fail(fmt.Errorf("read replica: %w", err))
+		}
+		resolver.Call(func(pool gorm.ConnPool) error {
+			if sqlDB, ok := pool.(*sql.DB); ok {
+				cfg.applyPool(sqlDB)
+			}
+			return nil
+		})
+	}
+
+	// 3. Query timeouts
+	if cfg.QueryTimeout > 0 {
+		if err := db.Use(queryTimeout(cfg.QueryTimeout)); err != nil {
+			return fail(err)
+		}
+	}
+	return db, nil
+}
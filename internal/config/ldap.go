@@ -0,0 +1,64 @@
+package config
+
+import (
+	"log"
+	"strings"
+)
+
+// LDAPConfig holds the settings for authenticating against an LDAP / Active Directory server
+type LDAPConfig struct {
+	Enabled            bool
+	URL                string // e.g. ldaps://dc.example.com:636
+	StartTLS           bool
+	InsecureSkipVerify bool
+
+	// Service account used to look up users before binding as them
+	BindDN       string
+	BindPassword string
+
+	BaseDN         string
+	UserFilter     string // %s is replaced with the escaped login, e.g. (mail=%s)
+	EmailAttribute string
+	NameAttribute  string
+	GroupAttribute string
+
+	// GroupRoles maps a (lower-cased) group DN to the name of a models.Role
+	GroupRoles map[string]string
+}
+
+// LoadLDAPConfig from environment variables
+func LoadLDAPConfig() *LDAPConfig {
+	return &LDAPConfig{
+		Enabled:            getEnv("LDAP_ENABLED", "false") == "true",
+		URL:                getEnv("LDAP_URL", "ldap://127.0.0.1:389"),
+		StartTLS:           getEnv("LDAP_START_TLS", "false") == "true",
+		InsecureSkipVerify: getEnv("LDAP_INSECURE_SKIP_VERIFY", "false") == "true",
+		BindDN:             getEnv("LDAP_BIND_DN", ""),
+		BindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+		BaseDN:             getEnv("LDAP_BASE_DN", ""),
+		UserFilter:         getEnv("LDAP_USER_FILTER", "(mail=%s)"),
+		EmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		NameAttribute:      getEnv("LDAP_NAME_ATTRIBUTE", "cn"),
+		GroupAttribute:     getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+		GroupRoles:         parseGroupRoles(getEnv("LDAP_GROUP_ROLE_MAP", "")),
+	}
+}
+
+// parseGroupRoles reads "groupDN:role;groupDN:role" pairs.
+// The last colon separates the role, because DNs never contain one.
+func parseGroupRoles(raw string) map[string]string {
+	groupRoles := map[string]string{}
+	for _, pair := range strings.Split(raw, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		idx := strings.LastIndex(pair, ":")
+		if idx <= 0 || idx == len(pair)-1 {
+			log.Printf("Ignoring malformed LDAP_GROUP_ROLE_MAP entry %q", pair)
+			continue
+		}
+		groupRoles[strings.ToLower(strings.TrimSpace(pair[:idx]))] = strings.TrimSpace(pair[idx+1:])
+	}
+	return groupRoles
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// OIDCConfig holds the settings for the built-in OpenID Connect provider
+type OIDCConfig struct {
+	Issuer            string
+	SigningKeyPath    string
+	IDTokenExpireMin  int
+	AuthCodeExpireSec int
+}
+
+// LoadOIDCConfig from environment variables
+func LoadOIDCConfig() *OIDCConfig {
+	idTokenExp, _ := strconv.Atoi(getEnv("OIDC_ID_TOKEN_EXPIRE_MIN", "60"))
+	codeExp, _ := strconv.Atoi(getEnv("OIDC_AUTH_CODE_EXPIRE_SEC", "60"))
+
+	return &OIDCConfig{
+		Issuer:            getEnv("OIDC_ISSUER", "http://localhost:8080"),
+		SigningKeyPath:    os.Getenv("OIDC_SIGNING_KEY_PATH"), // empty => ephemeral key
+		IDTokenExpireMin:  idTokenExp,
+		AuthCodeExpireSec: codeExp,
+	}
+}
+
+// LoadSigningKey reads the RSA private key used to sign ID tokens.
+// When no path is configured a throw-away key is generated, which means
+// relying parties have to refetch the JWKS after every restart.
+func (c *OIDCConfig) LoadSigningKey() (*rsa.PrivateKey, error) {
+	if c.SigningKeyPath == "" {
+		log.Printf("OIDC_SIGNING_KEY_PATH not set, generating an ephemeral signing key")
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}
+
+	data, err := os.ReadFile(c.SigningKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read oidc signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("oidc signing key is not PEM encoded")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("oidc signing key must be an RSA key")
+		}
+		return rsaKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported oidc signing key type %q", block.Type)
+	}
+}
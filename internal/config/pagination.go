@@ -0,0 +1,45 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Envelope formats of list responses
+const (
+	PaginationFormatLaravel = "laravel" // Laravel paginator fields: data, current_page, next_page_url, ...
+	PaginationFormatMinimal = "minimal" // {"items": [...], "pagination": {...}}
+)
+
+// PaginationConfig controls list endpoints
+type PaginationConfig struct {
+	DefaultLimit int
+	MaxLimit     int    // larger `limit` values are lowered to this
+	Format       string // see the PaginationFormat* constants
+
+	// List cursors (keyset pagination) are signed so clients cannot forge them
+	CursorSecret string
+
+	// BaseURL prefixes the page links unless the request came through one of the
+	// TRUSTED_PROXIES; the Host and X-Forwarded-* headers of anyone else are ignored
+	BaseURL string
+}
+
+// LoadPaginationConfig from environment variables
+func LoadPaginationConfig() *PaginationConfig {
+	defaultLimit, _ := strconv.Atoi(getEnv("PAGINATION_DEFAULT_LIMIT", "10"))
+	maxLimit, _ := strconv.Atoi(getEnv("PAGINATION_MAX_LIMIT", "100"))
+
+	format := getEnv("PAGINATION_FORMAT", PaginationFormatMinimal)
+	if format != PaginationFormatLaravel {
+		format = PaginationFormatMinimal
+	}
+
+	return &PaginationConfig{
+		DefaultLimit: defaultLimit,
+		MaxLimit:     maxLimit,
+		Format:       format,
+		CursorSecret: getEnv("PAGINATION_CURSOR_SECRET", "cursor-secret-example"),
+		BaseURL:      strings.TrimRight(getEnv("APP_URL", "http://localhost:8080"), "/"),
+	}
+}
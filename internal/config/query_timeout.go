@@ -0,0 +1,80 @@
+package config
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// queryTimeout is a GORM plugin that gives each statement a deadline of its own,
+// unless its context already has one or opted out with WithoutQueryTimeout
+type queryTimeout time.Duration
+
+const queryTimeoutSetting = "query_timeout"
+
+type noQueryTimeoutKey struct{}
+
+// WithoutQueryTimeout marks ctx so statements using it run without DB_QUERY_TIMEOUT,
+// e.g. migrations altering large tables
+func WithoutQueryTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noQueryTimeoutKey{}, true)
+}
+
+// queryTimeoutState is what ends a statement's deadline again. Nested statements, e.g.
+// association saves, get a copy of the settings, so it remembers whose deadline it is.
+type queryTimeoutState struct {
+	stmt   *gorm.Statement
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+func (queryTimeout) Name() string {
+	return "query_timeout"
+}
+
+// Initialize registers the callbacks. Row queries are left out: they hand back open
+// rows, which cancelling the deadline at the end of the callback would close.
+func (t queryTimeout) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("*").Register("query_timeout:start", t.start),
+		cb.Create().After("*").Register("query_timeout:stop", t.stop),
+		cb.Query().Before("*").Register("query_timeout:start", t.start),
+		cb.Query().After("*").Register("query_timeout:stop", t.stop),
+		cb.Update().Before("*").Register("query_timeout:start", t.start),
+		cb.Update().After("*").Register("query_timeout:stop", t.stop),
+		cb.Delete().Before("*").Register("query_timeout:start", t.start),
+		cb.Delete().After("*").Register("query_timeout:stop", t.stop),
+		cb.Raw().Before("*").Register("query_timeout:start", t.start),
+		cb.Raw().After("*").Register("query_timeout:stop", t.stop),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t queryTimeout) start(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if _, ok := ctx.Deadline(); ok || ctx.Value(noQueryTimeoutKey{}) != nil {
+		return
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(t))
+	db.Statement.Settings.Store(queryTimeoutSetting, queryTimeoutState{stmt: db.Statement, parent: ctx, cancel: cancel})
+	db.Statement.Context = timeoutCtx
+}
+
+// stop restores the original context, since a chain such as Count then Find reuses
+// the statement. A nested statement must not end the deadline of its parent.
+func (queryTimeout) stop(db *gorm.DB) {
+	v, ok := db.Statement.Settings.Load(queryTimeoutSetting)
+	if !ok || v.(queryTimeoutState).stmt != db.Statement {
+		return
+	}
+	db.Statement.Settings.Delete(queryTimeoutSetting)
+	state := v.(queryTimeoutState)
+	state.cancel()
+	db.Statement.Context = state.parent
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RealtimeConfig holds the WebSocket / SSE push settings
+type RealtimeConfig struct {
+	AllowedOrigins []string // browser origins allowed to open WebSockets; empty = same origin
+	ClientBuffer   int      // events a client may lag behind before it is disconnected
+	SweepInterval  time.Duration
+
+	TicketTTL       time.Duration // lifetime of the single-use tickets that open a stream
+	RecheckInterval time.Duration // how often open streams re-check the account and token expiry
+}
+
+// LoadRealtimeConfig from environment variables
+func LoadRealtimeConfig() *RealtimeConfig {
+	buffer, _ := strconv.Atoi(getEnv("REALTIME_CLIENT_BUFFER", "64"))
+	sweepSec, _ := strconv.Atoi(getEnv("REALTIME_PRESENCE_SWEEP_SEC", "30"))
+	ticketSec, _ := strconv.Atoi(getEnv("REALTIME_TICKET_TTL_SEC", "30"))
+	recheckSec, _ := strconv.Atoi(getEnv("REALTIME_RECHECK_SEC", "60"))
+
+	var origins []string
+	for _, origin := range strings.Split(getEnv("REALTIME_ALLOWED_ORIGINS", ""), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return &RealtimeConfig{
+		AllowedOrigins: origins,
+		ClientBuffer:   buffer,
+		SweepInterval:  time.Duration(sweepSec) * time.Second,
+
+		TicketTTL:       time.Duration(ticketSec) * time.Second,
+		RecheckInterval: time.Duration(recheckSec) * time.Second,
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StorageConfig selects where uploaded files are stored and how they are served
+type StorageConfig struct {
+	Driver    string // "local" or "s3"
+	LocalDir  string // root directory of the local driver
+	PublicURL string // where keys under "public/" are served; derived from the driver when empty
+	AppURL    string // base URL of this API, used in signed download links
+
+	// Download links served by the API are signed with SigningSecret and expire after SignedURLTTL
+	SigningSecret string
+	SignedURLTTL  time.Duration
+
+	// Uploads larger than UploadMaxBytes or of another (sniffed) type are rejected
+	UploadMaxBytes     int64
+	UploadAllowedTypes []string
+
+	S3 *S3Config
+}
+
+// S3Config points the s3 driver at AWS or any S3-compatible server such as MinIO
+type S3Config struct {
+	Endpoint  string // host[:port], e.g. s3.amazonaws.com or localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	PathStyle bool // MinIO and most self-hosted servers need path-style bucket URLs
+}
+
+// LoadStorageConfig from environment variables
+func LoadStorageConfig() *StorageConfig {
+	ttlMin, _ := strconv.Atoi(getEnv("STORAGE_URL_EXPIRE_MIN", "15"))
+	maxBytes, _ := strconv.ParseInt(getEnv("UPLOAD_MAX_BYTES", "20971520"), 10, 64)
+
+	var types []string
+	for _, t := range strings.Split(getEnv("UPLOAD_ALLOWED_TYPES", "image/jpeg,image/png,image/gif,image/webp,application/pdf,text/plain"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+
+	return &StorageConfig{
+		Driver:             getEnv("STORAGE_DRIVER", "local"),
+		LocalDir:           getEnv("STORAGE_LOCAL_DIR", "storage/files"),
+		PublicURL:          getEnv("STORAGE_PUBLIC_URL", ""),
+		AppURL:             strings.TrimRight(getEnv("APP_URL", "http://localhost:8080"), "/"),
+		SigningSecret:      getEnv("STORAGE_SIGNING_SECRET", "storage-secret-example"),
+		SignedURLTTL:       time.Duration(ttlMin) * time.Minute,
+		UploadMaxBytes:     maxBytes,
+		UploadAllowedTypes: types,
+		S3: &S3Config{
+			Endpoint:  getEnv("STORAGE_S3_ENDPOINT", "s3.amazonaws.com"),
+			Region:    getEnv("STORAGE_S3_REGION", "us-east-1"),
+			Bucket:    getEnv("STORAGE_S3_BUCKET", ""),
+			AccessKey: getEnv("STORAGE_S3_ACCESS_KEY", ""),
+			SecretKey: getEnv("STORAGE_S3_SECRET_KEY", ""),
+			UseSSL:    getEnv("STORAGE_S3_USE_SSL", "true") == "true",
+			PathStyle: getEnv("STORAGE_S3_PATH_STYLE", "false") == "true",
+		},
+	}
+}
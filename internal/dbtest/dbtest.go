@@ -0,0 +1,67 @@
+// Package dbtest gives tests a migrated database. By default each Open is a fresh
+// in-memory SQLite database. To run the same tests against MySQL or PostgreSQL, set
+// TEST_DB_DRIVER and TEST_DB_DSN:
+//
+//	TEST_DB_DRIVER=postgres TEST_DB_DSN="postgres://postgres@localhost/app_test?sslmode=disable" go test ./...
+//
+// Such a database is shared, so each test gets a transaction that is rolled back
+// when the test ends.
+package dbtest
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/migrations"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	shared     *gorm.DB
+	sharedErr  error
+	sharedOnce sync.Once
+)
+
+// Open returns a database with every migration applied
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	cfg := &config.DatabaseConfig{
+		Driver: os.Getenv("TEST_DB_DRIVER"),
+		DSN:    os.Getenv("TEST_DB_DSN"),
+	}
+	if cfg.Driver == "" || cfg.Driver == config.DriverSQLite && cfg.DSN == "" {
+		db, err := open(&config.DatabaseConfig{Driver: config.DriverSQLite, Name: config.SQLiteMemory})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+
+	sharedOnce.Do(func() { shared, sharedErr = open(cfg) })
+	if sharedErr != nil {
+		t.Fatal(sharedErr)
+	}
+	tx := shared.Begin()
+	if tx.Error != nil {
+		t.Fatal(tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+func open(cfg *config.DatabaseConfig) (*gorm.DB, error) {
+	db, err := config.SetupDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db.Logger = logger.Discard
+	if _, err := migrations.NewMigrator(db).Up(0); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AccountHandler struct {
+	accountService service.AccountService
+}
+
+func NewAccountHandler(as service.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: as}
+}
+
+// ChangeStatus moves an account to another lifecycle state, e.g. suspends or reactivates it
+func (h *AccountHandler) ChangeStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidUserID"))
+		return
+	}
+	var req struct {
+		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.accountService.ChangeStatus(ctx, utils.AuthID(ctx), uint(userID), req.Status, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.Error(c, http.StatusNotFound, i18n.T(c, "UserNotFound"))
+		case errors.Is(err, service.ErrInvalidStatusTransition):
+			response.Error(c, http.StatusUnprocessableEntity, i18n.T(c, "InvalidStatusTransition"))
+		case errors.Is(err, service.ErrCannotChangeOwnStatus):
+			response.Error(c, http.StatusForbidden, i18n.T(c, "CannotChangeOwnStatus"))
+		default:
+			response.Error(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "AccountStatusChanged"), user)
+}
+
+// ScheduleDeletion closes the current user's account; logging in again before
+// deletion_scheduled_at cancels it
+func (h *AccountHandler) ScheduleDeletion(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := h.accountService.ScheduleOwnDeletion(ctx, utils.AuthID(ctx))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "AccountDeletionScheduled"), gin.H{
+		"status":                user.Status,
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	authService  service.AuthService
+	auditService service.AuditService
+}
+
+func NewAdminHandler(as service.AuthService, audit service.AuditService) *AdminHandler {
+	return &AdminHandler{
+		authService:  as,
+		auditService: audit,
+	}
+}
+
+// Impersonate issues a short-lived "act as" token so support staff can reproduce user issues
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	ctx := c.Request.Context()
+	targetID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidUserID"))
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// The reason is optional, so an empty body is fine
+	_ = c.ShouldBindJSON(&req)
+
+	actorID := utils.AuthID(ctx)
+	token, err := h.authService.Impersonate(ctx, actorID, uint(targetID))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrCannotImpersonateSelf), errors.Is(err, service.ErrCannotImpersonatePrivileged):
+			response.Error(c, http.StatusForbidden, err.Error())
+		default:
+			response.Error(c, http.StatusNotFound, i18n.T(c, "UserNotFound"))
+		}
+		return
+	}
+
+	entry := &models.AuditLog{
+		ActorID:   actorID,
+		UserID:    uint(targetID),
+		Action:    "impersonation.start",
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Status:    http.StatusOK,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   req.Reason,
+	}
+	if err := h.auditService.Record(ctx, entry); err != nil {
+		// Refuse to hand out an impersonation token we could not account for
+		log.Printf("failed to audit impersonation: %v", err)
+		response.Error(c, http.StatusInternalServerError, fmt.Errorf("failed to audit impersonation: %w", err).Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "ImpersonationStarted"), gin.H{
+		"access_token": token,
+		"user_id":      targetID,
+		"actor_id":     actorID,
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"golang-api-template/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// wantsCookies reports whether the client asked for cookie transport
+// (`X-Auth-Transport: cookie`) and the server allows it.
+// Bearer clients never send the header, so they keep getting tokens in the body.
+func (h *AuthHandler) wantsCookies(c *gin.Context) bool {
+	return h.cfg.Cookie.Enabled && strings.EqualFold(c.GetHeader("X-Auth-Transport"), "cookie")
+}
+
+// setAuthCookies stores the tokens in HttpOnly cookies and rotates the CSRF token.
+// The CSRF token is returned so the SPA can keep it in memory as well.
+func (h *AuthHandler) setAuthCookies(c *gin.Context, accessToken, refreshToken string) (string, error) {
+	csrfToken, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	accessTTL := time.Minute * time.Duration(h.cfg.AccessTokenExpireMin)
+	refreshTTL := time.Hour * time.Duration(h.cfg.RefreshTokenExpireHrs)
+
+	h.setCookie(c, h.cfg.Cookie.AccessName, accessToken, "/", accessTTL, true)
+	if refreshToken != "" {
+		h.setCookie(c, h.cfg.Cookie.RefreshName, refreshToken, h.cfg.Cookie.RefreshPath, refreshTTL, true)
+	}
+	// Readable by JavaScript on purpose: it is echoed back in the CSRF header
+	h.setCookie(c, h.cfg.Cookie.CSRFName, csrfToken, "/", refreshTTL, false)
+
+	return csrfToken, nil
+}
+
+func (h *AuthHandler) clearAuthCookies(c *gin.Context) {
+	h.setCookie(c, h.cfg.Cookie.AccessName, "", "/", -1, true)
+	h.setCookie(c, h.cfg.Cookie.RefreshName, "", h.cfg.Cookie.RefreshPath, -1, true)
+	h.setCookie(c, h.cfg.Cookie.CSRFName, "", "/", -1, false)
+}
+
+// refreshTokenFromRequest reads the refresh token from the JSON body, falling back to the cookie
+func (h *AuthHandler) refreshTokenFromRequest(c *gin.Context) string {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// The body is optional for cookie clients
+	_ = c.ShouldBindJSON(&req)
+	if req.RefreshToken != "" {
+		return req.RefreshToken
+	}
+	if h.cfg.Cookie.Enabled {
+		if token, err := c.Cookie(h.cfg.Cookie.RefreshName); err == nil {
+			return token
+		}
+	}
+	return ""
+}
+
+func (h *AuthHandler) setCookie(c *gin.Context, name, value, path string, ttl time.Duration, httpOnly bool) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   h.cfg.Cookie.Domain,
+		Secure:   h.cfg.Cookie.Secure,
+		HttpOnly: httpOnly,
+		SameSite: h.cfg.Cookie.SameSite,
+	}
+	if ttl < 0 {
+		cookie.MaxAge = -1
+		cookie.Expires = time.Unix(0, 0)
+	} else {
+		cookie.MaxAge = int(ttl.Seconds())
+	}
+	http.SetCookie(c.Writer, cookie)
+}
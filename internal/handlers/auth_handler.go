@@ -1,25 +1,41 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"time"
 
+	"golang-api-template/internal/config"
 	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/middlewares"
+	"golang-api-template/internal/models"
 	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
 	"golang-api-template/pkg/response"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	authService service.AuthService
+	authService  service.AuthService
+	loginHistory service.LoginHistoryService
+	profiles     service.ProfileService
+	cfg          *config.Config
 }
 
-func NewAuthHandler(as service.AuthService) *AuthHandler {
-	return &AuthHandler{authService: as}
+func NewAuthHandler(as service.AuthService, lh service.LoginHistoryService, ps service.ProfileService, cfg *config.Config) *AuthHandler {
+	return &AuthHandler{
+		authService:  as,
+		loginHistory: lh,
+		profiles:     ps,
+		cfg:          cfg,
+	}
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
@@ -30,19 +46,38 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, user, err := h.authService.Login(req.Email, req.Password)
+	accessToken, refreshToken, user, err := h.authService.Login(ctx, req.Email, req.Password)
+	h.recordLogin(c, req.Email, user, err)
+	if key, inactive := middlewares.AccountErrorKey(err); inactive {
+		response.Error(c, http.StatusForbidden, i18n.T(c, key))
+		return
+	}
 	if err != nil {
 		// Use our Error response with a 401 status code
 		response.Error(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	err = h.authService.TrackUserLogin(user.ID)
+	err = h.authService.TrackUserLogin(ctx, user.ID)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, fmt.Errorf("failed to track user login: %w", err).Error())
 		return
 	}
 
+	// Browser clients get HttpOnly cookies instead of tokens in the body
+	if h.wantsCookies(c) {
+		csrfToken, err := h.setAuthCookies(c, accessToken, refreshToken)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		response.Success(c, http.StatusOK, "Login successful", gin.H{
+			"csrf_token": csrfToken,
+			"user":       user,
+		})
+		return
+	}
+
 	// Use our Success response with 200 status code
 	response.Success(c, http.StatusOK, "Login successful", gin.H{
 		"access_token":  accessToken,
@@ -52,20 +87,38 @@ func (h *AuthHandler) Login(c *gin.Context) {
 }
 
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	var req struct {
-		RefreshToken string `json:"refresh_token"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, err.Error())
+	ctx := c.Request.Context()
+	refreshToken := h.refreshTokenFromRequest(c)
+	if refreshToken == "" {
+		response.Error(c, http.StatusBadRequest, "refresh_token is required")
 		return
 	}
 
-	newAccess, newRefresh, err := h.authService.RefreshToken(req.RefreshToken)
+	newAccess, newRefresh, err := h.authService.RefreshToken(ctx, refreshToken)
 	if err != nil {
+		if h.wantsCookies(c) {
+			h.clearAuthCookies(c)
+		}
+		if key, inactive := middlewares.AccountErrorKey(err); inactive {
+			response.Error(c, http.StatusForbidden, i18n.T(c, key))
+			return
+		}
 		response.Error(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 
+	if h.wantsCookies(c) {
+		csrfToken, err := h.setAuthCookies(c, newAccess, newRefresh)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		response.Success(c, http.StatusOK, "Token refreshed", gin.H{
+			"csrf_token": csrfToken,
+		})
+		return
+	}
+
 	response.Success(c, http.StatusOK, "Token refreshed", gin.H{
 		"access_token":  newAccess,
 		"refresh_token": newRefresh,
@@ -73,32 +126,115 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 }
 
 func (h *AuthHandler) Logout(c *gin.Context) {
+	ctx := c.Request.Context()
+	refreshToken := h.refreshTokenFromRequest(c)
+	if refreshToken == "" && !h.wantsCookies(c) {
+		response.Error(c, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	if refreshToken != "" {
+		if err := h.authService.Logout(ctx, refreshToken); err != nil {
+			response.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if h.wantsCookies(c) {
+		h.clearAuthCookies(c)
+	}
+
+	response.Success(c, http.StatusOK, "Logout successful", nil)
+}
+
+// LoginHistory lists the authenticated user's login attempts, newest first
+func (h *AuthHandler) LoginHistory(c *gin.Context) {
+	ctx := c.Request.Context()
+	pagination := utils.ParsePagination(c)
+	events, page, err := h.loginHistory.GetLoginHistory(ctx, utils.AuthID(ctx), pagination)
+	if err != nil {
+		listError(c, err)
+		return
+	}
+
+	utils.PaginatedResponse(c, http.StatusOK, i18n.T(c, "ListOfLogins"), events, pagination, page)
+}
+
+// Reauthenticate confirms the current user's password (or MFA code) and returns a
+// short-lived access token that satisfies RequireRecentAuth
+func (h *AuthHandler) Reauthenticate(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req struct {
-		RefreshToken string `json:"refresh_token"`
+		Password string `json:"password"`
+		MFACode  string `json:"mfa_code"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if req.Password == "" && req.MFACode == "" {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "PasswordOrMFACodeRequired"))
+		return
+	}
 
-	err := h.authService.Logout(req.RefreshToken)
+	token, err := h.authService.Reauthenticate(ctx, utils.AuthID(ctx), req.Password, req.MFACode)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		switch {
+		case errors.Is(err, service.ErrMFANotEnabled):
+			response.Error(c, http.StatusBadRequest, i18n.T(c, "MFANotEnabled"))
+		case errors.Is(err, service.ErrInvalidCredentials):
+			response.Error(c, http.StatusUnauthorized, i18n.T(c, "InvalidCredentials"))
+		default:
+			response.Error(c, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	response.Success(c, http.StatusOK, "Logout successful", nil)
+	if h.wantsCookies(c) {
+		// The elevated token replaces the access cookie; the refresh cookie stays as it is
+		h.setCookie(c, h.cfg.Cookie.AccessName, token, "/", time.Duration(h.cfg.StepUpTokenExpireMin)*time.Minute, true)
+		response.Success(c, http.StatusOK, i18n.T(c, "Reauthenticated"), gin.H{
+			"expires_in": h.cfg.StepUpTokenExpireMin * 60,
+		})
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "Reauthenticated"), gin.H{
+		"access_token": token,
+		"expires_in":   h.cfg.StepUpTokenExpireMin * 60,
+	})
+}
+
+// recordLogin stores the attempt in the login history; it never fails the login itself
+func (h *AuthHandler) recordLogin(c *gin.Context, email string, user *models.User, loginErr error) {
+	attempt := service.LoginAttempt{
+		Email:          email,
+		Method:         "password",
+		IP:             c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		DeviceID:       c.GetHeader("X-Device-ID"),
+		Lang:           c.GetString("locale"),
+	}
+	if loginErr != nil {
+		attempt.FailureReason = loginErr.Error()
+	} else {
+		attempt.User = user
+		attempt.Method = user.AuthSource
+	}
+
+	if err := h.loginHistory.RecordLogin(c.Request.Context(), attempt); err != nil {
+		log.Printf("failed to record login for %s: %v", email, err)
+	}
 }
 
 func (h *AuthHandler) GetAuthUser(c *gin.Context) {
-	// you can use here to get user
-	userId, exists := c.Get("AuthID")
-	if !exists {
-		response.Error(c, http.StatusInternalServerError, "Error getting user ID")
+	ctx := c.Request.Context()
+	user, err := h.authService.GetAuthUser(ctx)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	fmt.Println("User ID:", userId)
-	user, err := h.authService.GetAuthUser(c)
+	profile, err := h.profiles.GetProfile(ctx, user.ID)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -107,6 +243,14 @@ func (h *AuthHandler) GetAuthUser(c *gin.Context) {
 		ID:    user.ID,
 		Name:  user.Name,
 		Email: user.Email,
+		Profile: &response.ProfileResponse{
+			DisplayName:  profile.DisplayName,
+			Phone:        profile.Phone,
+			Timezone:     profile.Timezone,
+			Locale:       profile.Locale,
+			AvatarURL:    profile.AvatarURL,
+			CustomFields: profile.CustomFields,
+		},
 	}
 
 	response.Success(c, http.StatusOK, i18n.T(c, "UserFound"), userResponse)
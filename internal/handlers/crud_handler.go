@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/repository"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CrudHandler serves the standard REST routes of a model backed by a service.Crud.
+// Request bodies are bound straight into the model, so only fields with a json tag
+// the client may set should be exported to JSON.
+//
+// Messages use the model's own i18n keys when they exist, e.g. "ProductCreated",
+// "ProductNotFound" or "InvalidProductID", and the generic "Record*" keys otherwise.
+type CrudHandler[T any] struct {
+	service service.Crud[T]
+	name    string // model type name, prefix of its i18n keys
+}
+
+func NewCrudHandler[T any](s service.Crud[T]) *CrudHandler[T] {
+	return &CrudHandler[T]{service: s, name: reflect.TypeOf(new(T)).Elem().Name()}
+}
+
+// RegisterCrudRoutes adds the REST routes of a model under path, see CrudHandler.RegisterRoutes
+func RegisterCrudRoutes[T any](group *gin.RouterGroup, path string, s service.Crud[T], handlers ...gin.HandlerFunc) *CrudHandler[T] {
+	h := NewCrudHandler(s)
+	h.RegisterRoutes(group, path, handlers...)
+	return h
+}
+
+// RegisterRoutes adds these routes under path, with handlers (e.g. auth and
+// permission middlewares) in front of each of them:
+//
+//	POST   path              create
+//	GET    path              list, with filters, sort, search, paging and `?trashed=`
+//	GET    path/:id          get
+//	PUT    path/:id          update
+//	DELETE path/:id          move to the trash, `?force=true` removes it for good
+//	POST   path/:id/restore  restore from the trash
+func (h *CrudHandler[T]) RegisterRoutes(group *gin.RouterGroup, path string, handlers ...gin.HandlerFunc) {
+	route := func(handler gin.HandlerFunc) []gin.HandlerFunc {
+		return append(append([]gin.HandlerFunc{}, handlers...), handler)
+	}
+
+	group.POST(path, route(h.Create)...)
+	group.GET(path, route(h.List)...)
+	group.GET(path+"/:id", route(h.Get)...)
+	group.PUT(path+"/:id", route(h.Update)...)
+	group.DELETE(path+"/:id", route(h.Delete)...)
+	group.POST(path+"/:id/restore", route(h.Restore)...)
+}
+
+func (h *CrudHandler[T]) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	entity := new(T)
+	if err := c.ShouldBindJSON(entity); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.Create(ctx, entity); err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, h.message(c, "Created"), entity)
+}
+
+func (h *CrudHandler[T]) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	trashed := c.Query("trashed")
+	if !repository.ValidTrashed(trashed) {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidTrashedFilter"))
+		return
+	}
+
+	pagination := utils.ParsePagination(c)
+	items, page, err := h.service.List(ctx, pagination, trashed)
+	if err != nil {
+		listError(c, err)
+		return
+	}
+
+	utils.PaginatedResponse(c, http.StatusOK, h.message(c, "List"), items, pagination, page)
+}
+
+func (h *CrudHandler[T]) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.recordID(c)
+	if !ok {
+		return
+	}
+
+	entity, err := h.service.Get(ctx, id)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, h.message(c, "Retrieved"), entity)
+}
+
+// Update binds the body onto the stored record, so fields left out keep their value
+func (h *CrudHandler[T]) Update(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.recordID(c)
+	if !ok {
+		return
+	}
+
+	entity, err := h.service.Get(ctx, id)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if err := c.ShouldBindJSON(entity); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.Update(ctx, id, entity); err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, h.message(c, "Updated"), entity)
+}
+
+func (h *CrudHandler[T]) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.recordID(c)
+	if !ok {
+		return
+	}
+
+	force := c.Query("force") == "true"
+	if err := h.service.Delete(ctx, id, force); err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	if force {
+		response.Success(c, http.StatusOK, h.message(c, "PermanentlyDeleted"), nil)
+		return
+	}
+	response.Success(c, http.StatusOK, h.message(c, "Deleted"), nil)
+}
+
+func (h *CrudHandler[T]) Restore(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.recordID(c)
+	if !ok {
+		return
+	}
+
+	entity, err := h.service.Restore(ctx, id)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, h.message(c, "Restored"), entity)
+}
+
+func (h *CrudHandler[T]) recordID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		key := "Invalid" + h.name + "ID"
+		if !i18n.Has(key) {
+			key = "InvalidRecordID"
+		}
+		response.Error(c, http.StatusBadRequest, i18n.T(c, key))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// message translates the model's key for suffix, falling back to the generic one
+func (h *CrudHandler[T]) message(c *gin.Context, suffix string) string {
+	if key := h.name + suffix; i18n.Has(key) {
+		return i18n.T(c, key)
+	}
+	return i18n.T(c, "Record"+suffix)
+}
+
+// fail answers a failed CRUD operation, including the errors hooks return
+func (h *CrudHandler[T]) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(c, http.StatusNotFound, h.message(c, "NotFound"))
+	case errors.Is(err, service.ErrConflict):
+		response.Error(c, http.StatusConflict, h.message(c, "Conflict"))
+	case errors.Is(err, service.ErrInvalidInput):
+		response.Error(c, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, repository.ErrNotSoftDeletable):
+		response.Error(c, http.StatusBadRequest, h.message(c, "NotSoftDeletable"))
+	default:
+		response.Error(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type EmailChangeHandler struct {
+	emailChangeService service.EmailChangeService
+}
+
+func NewEmailChangeHandler(ecs service.EmailChangeService) *EmailChangeHandler {
+	return &EmailChangeHandler{emailChangeService: ecs}
+}
+
+// RequestChange starts an email change for the current user
+func (h *EmailChangeHandler) RequestChange(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	change, err := h.emailChangeService.RequestEmailChange(ctx, utils.AuthID(ctx), req.Email, c.GetString("locale"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmailTaken):
+			response.Error(c, http.StatusConflict, i18n.T(c, "EmailTaken"))
+		case errors.Is(err, service.ErrEmailUnchanged):
+			response.Error(c, http.StatusBadRequest, i18n.T(c, "EmailUnchanged"))
+		default:
+			response.Error(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, i18n.T(c, "EmailChangeRequested"), change)
+}
+
+// Pending shows the current user's unconfirmed email change, if any
+func (h *EmailChangeHandler) Pending(c *gin.Context) {
+	ctx := c.Request.Context()
+	change, err := h.emailChangeService.GetPendingEmailChange(ctx, utils.AuthID(ctx))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(c, http.StatusNotFound, i18n.T(c, "NoPendingEmailChange"))
+		return
+	} else if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "PendingEmailChange"), change)
+}
+
+// Cancel drops the current user's unconfirmed email change
+func (h *EmailChangeHandler) Cancel(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := h.emailChangeService.CancelEmailChange(ctx, utils.AuthID(ctx)); err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "EmailChangeCancelled"), nil)
+}
+
+// CheckConfirm is opened from the link sent to the new address. It only checks the
+// token, so mail scanners following the link change nothing; the client shows the
+// address and POSTs the token to Confirm.
+func (h *EmailChangeHandler) CheckConfirm(c *gin.Context) {
+	ctx := c.Request.Context()
+	token, ok := bindEmailChangeToken(c)
+	if !ok {
+		return
+	}
+
+	change, err := h.emailChangeService.CheckConfirmToken(ctx, token)
+	if err != nil {
+		h.tokenError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "EmailChangeConfirmPrompt"), gin.H{
+		"email":      change.NewEmail,
+		"expires_at": change.ExpiresAt,
+	})
+}
+
+// Confirm switches the account to the new address
+func (h *EmailChangeHandler) Confirm(c *gin.Context) {
+	ctx := c.Request.Context()
+	token, ok := bindEmailChangeToken(c)
+	if !ok {
+		return
+	}
+
+	change, err := h.emailChangeService.ConfirmEmailChange(ctx, token)
+	if err != nil {
+		h.tokenError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "EmailChangeConfirmed"), gin.H{"email": change.NewEmail})
+}
+
+// CheckRevert is opened from the link sent to the old address; like CheckConfirm it
+// only checks the token, and the client POSTs it to Revert
+func (h *EmailChangeHandler) CheckRevert(c *gin.Context) {
+	ctx := c.Request.Context()
+	token, ok := bindEmailChangeToken(c)
+	if !ok {
+		return
+	}
+
+	change, err := h.emailChangeService.CheckRevertToken(ctx, token)
+	if err != nil {
+		h.tokenError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "EmailChangeRevertPrompt"), gin.H{
+		"email":      change.OldEmail,
+		"new_email":  change.NewEmail,
+		"expires_at": change.RevertExpiresAt,
+	})
+}
+
+// Revert restores the old address
+func (h *EmailChangeHandler) Revert(c *gin.Context) {
+	ctx := c.Request.Context()
+	token, ok := bindEmailChangeToken(c)
+	if !ok {
+		return
+	}
+
+	change, err := h.emailChangeService.RevertEmailChange(ctx, token)
+	if err != nil {
+		h.tokenError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "EmailChangeReverted"), gin.H{"email": change.OldEmail})
+}
+
+func (h *EmailChangeHandler) tokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidEmailChangeToken):
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidOrExpiredToken"))
+	case errors.Is(err, service.ErrEmailTaken):
+		response.Error(c, http.StatusConflict, i18n.T(c, "EmailTaken"))
+	default:
+		response.Error(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// bindEmailChangeToken reads the token from the query string (GET) or a JSON body (POST)
+func bindEmailChangeToken(c *gin.Context) (string, bool) {
+	var req struct {
+		Token string `form:"token" json:"token" binding:"required"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return req.Token, true
+}
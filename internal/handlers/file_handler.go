@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/storage"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FileHandler struct {
+	fileService service.FileService
+	cfg         *config.StorageConfig
+}
+
+func NewFileHandler(fs service.FileService, cfg *config.StorageConfig) *FileHandler {
+	return &FileHandler{fileService: fs, cfg: cfg}
+}
+
+// fileResponse is a file with a fresh signed download link
+type fileResponse struct {
+	models.File
+	DownloadURL       string    `json:"download_url"`
+	DownloadExpiresAt time.Time `json:"download_expires_at"`
+}
+
+func (h *FileHandler) withDownloadURL(file models.File) fileResponse {
+	link, expires := h.fileService.DownloadURL(&file)
+	return fileResponse{File: file, DownloadURL: link, DownloadExpiresAt: expires}
+}
+
+// Upload stores the files sent as multipart form parts named "file"
+func (h *FileHandler) Upload(c *gin.Context) {
+	ctx := c.Request.Context()
+	// Leave room for the multipart framing around the files
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.UploadMaxBytes+1<<20)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.Error(c, http.StatusRequestEntityTooLarge, i18n.T(c, "FileTooLarge"))
+			return
+		}
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "FileMissing"))
+		return
+	}
+	headers := form.File["file"]
+	if len(headers) == 0 {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "FileMissing"))
+		return
+	}
+
+	uploaded := make([]fileResponse, 0, len(headers))
+	for _, header := range headers {
+		file, err := h.fileService.Upload(ctx, utils.AuthID(ctx), header)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrFileTooLarge):
+				response.Error(c, http.StatusRequestEntityTooLarge, i18n.T(c, "FileTooLarge"))
+			case errors.Is(err, service.ErrFileTypeNotAllowed):
+				response.Error(c, http.StatusUnsupportedMediaType, i18n.T(c, "FileTypeNotAllowed"))
+			default:
+				response.Error(c, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+		uploaded = append(uploaded, h.withDownloadURL(*file))
+	}
+
+	response.Success(c, http.StatusCreated, i18n.T(c, "FilesUploaded"), uploaded)
+}
+
+func (h *FileHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	pagination := utils.ParsePagination(c)
+	files, page, err := h.fileService.ListFiles(ctx, utils.AuthID(ctx), pagination)
+	if err != nil {
+		listError(c, err)
+		return
+	}
+
+	items := make([]fileResponse, 0, len(files))
+	for _, file := range files {
+		items = append(items, h.withDownloadURL(file))
+	}
+	utils.PaginatedResponse(c, http.StatusOK, i18n.T(c, "ListOfFiles"), items, pagination, page)
+}
+
+func (h *FileHandler) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidInput"))
+		return
+	}
+
+	file, err := h.fileService.GetFile(ctx, utils.AuthID(ctx), uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrFileNotFound) {
+			response.Error(c, http.StatusNotFound, i18n.T(c, "FileNotFound"))
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "FileFound"), h.withDownloadURL(*file))
+}
+
+func (h *FileHandler) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidInput"))
+		return
+	}
+
+	if err := h.fileService.DeleteFile(ctx, utils.AuthID(ctx), uint(id)); err != nil {
+		if errors.Is(err, service.ErrFileNotFound) {
+			response.Error(c, http.StatusNotFound, i18n.T(c, "FileNotFound"))
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "FileDeleted"), nil)
+}
+
+// Download serves a file through a signed link; no access token is needed
+func (h *FileHandler) Download(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidInput"))
+		return
+	}
+
+	file, content, err := h.fileService.OpenSigned(ctx, uint(id), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		h.signedError(c, err)
+		return
+	}
+	defer content.Close()
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, file.Size, file.ContentType, content, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, file.Name, url.PathEscape(file.Name)),
+	})
+}
+
+// ServeSigned serves the links created by the local backend's Presign
+func (h *FileHandler) ServeSigned(c *gin.Context) {
+	ctx := c.Request.Context()
+	key := c.Param("key")
+	if len(key) > 0 && key[0] == '/' {
+		key = key[1:]
+	}
+
+	content, info, err := h.fileService.OpenSignedKey(ctx, key, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		h.signedError(c, err)
+		return
+	}
+	defer content.Close()
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, content, nil)
+}
+
+func (h *FileHandler) signedError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrInvalidSignature), errors.Is(err, storage.ErrInvalidKey):
+		response.Error(c, http.StatusForbidden, i18n.T(c, "InvalidDownloadLink"))
+	case errors.Is(err, storage.ErrURLExpired):
+		response.Error(c, http.StatusForbidden, i18n.T(c, "DownloadLinkExpired"))
+	case errors.Is(err, service.ErrFileNotFound):
+		response.Error(c, http.StatusNotFound, i18n.T(c, "FileNotFound"))
+	default:
+		response.Error(c, http.StatusInternalServerError, err.Error())
+	}
+}
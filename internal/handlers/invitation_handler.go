@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type InvitationHandler struct {
+	invitationService service.InvitationService
+}
+
+func NewInvitationHandler(is service.InvitationService) *InvitationHandler {
+	return &InvitationHandler{invitationService: is}
+}
+
+// Create invites someone by email with pre-assigned roles
+func (h *InvitationHandler) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req struct {
+		Email        string `json:"email" binding:"required,email"`
+		RoleIDs      []uint `json:"role_ids"`
+		Organization string `json:"organization" binding:"max=100"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	invitation, err := h.invitationService.Invite(ctx, utils.AuthID(ctx), req.Email, req.RoleIDs, req.Organization, c.GetString("locale"))
+	if err != nil {
+		h.invitationError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, i18n.T(c, "InvitationSent"), invitation)
+}
+
+// List returns the invitations that can still be accepted
+func (h *InvitationHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	pagination := utils.ParsePagination(c)
+	invitations, page, err := h.invitationService.ListPending(ctx, pagination)
+	if err != nil {
+		listError(c, err)
+		return
+	}
+
+	utils.PaginatedResponse(c, http.StatusOK, i18n.T(c, "ListOfInvitations"), invitations, pagination, page)
+}
+
+// Resend emails a new link; the previous link stops working
+func (h *InvitationHandler) Resend(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidInvitationID"))
+		return
+	}
+
+	invitation, err := h.invitationService.Resend(ctx, uint(id), c.GetString("locale"))
+	if err != nil {
+		h.invitationError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "InvitationSent"), invitation)
+}
+
+// Revoke cancels a pending invitation
+func (h *InvitationHandler) Revoke(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidInvitationID"))
+		return
+	}
+
+	if err := h.invitationService.Revoke(ctx, uint(id)); err != nil {
+		h.invitationError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "InvitationRevoked"), nil)
+}
+
+// Show describes the invitation behind a link, for the accept page
+func (h *InvitationHandler) Show(c *gin.Context) {
+	ctx := c.Request.Context()
+	invitation, err := h.invitationService.GetByToken(ctx, c.Query("token"))
+	if err != nil {
+		h.invitationError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "InvitationDetails"), gin.H{
+		"email":        invitation.Email,
+		"organization": invitation.Organization,
+		"roles":        invitation.Roles,
+		"expires_at":   invitation.ExpiresAt,
+	})
+}
+
+// Accept creates the invited account
+func (h *InvitationHandler) Accept(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Name     string `json:"name" binding:"required,max=100"`
+		Password string `json:"password" binding:"required,min=8"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.invitationService.Accept(ctx, req.Token, req.Name, req.Password)
+	if err != nil {
+		h.invitationError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, i18n.T(c, "InvitationAccepted"), user)
+}
+
+func (h *InvitationHandler) invitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(c, http.StatusNotFound, i18n.T(c, "InvitationNotFound"))
+	case errors.Is(err, service.ErrInvalidInvitation):
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidOrExpiredToken"))
+	case errors.Is(err, service.ErrEmailTaken):
+		response.Error(c, http.StatusConflict, i18n.T(c, "EmailTaken"))
+	case errors.Is(err, service.ErrInvitationAlreadySent):
+		response.Error(c, http.StatusConflict, i18n.T(c, "InvitationAlreadySent"))
+	case errors.Is(err, service.ErrInvitationNotPending):
+		response.Error(c, http.StatusConflict, i18n.T(c, "InvitationNotPending"))
+	case errors.Is(err, service.ErrInvitationRoleNotFound):
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "RoleNotFound"))
+	case errors.Is(err, service.ErrRoleNotAssignable):
+		response.Error(c, http.StatusForbidden, i18n.T(c, "RoleNotAssignable"))
+	default:
+		response.Error(c, http.StatusInternalServerError, err.Error())
+	}
+}
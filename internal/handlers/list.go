@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/repository"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listError answers a failed list query: bad filters, sorts or cursors are the client's fault
+func listError(c *gin.Context, err error) {
+	var queryErr *repository.QueryError
+	if errors.As(err, &queryErr) {
+		response.Error(c, http.StatusBadRequest, fmt.Sprintf("%s: %s", i18n.T(c, "InvalidListQuery"), queryErr))
+		return
+	}
+	response.Error(c, http.StatusInternalServerError, err.Error())
+}
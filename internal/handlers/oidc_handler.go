@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type OIDCHandler struct {
+	oidcService service.OIDCService
+}
+
+func NewOIDCHandler(os service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidcService: os}
+}
+
+// Discovery serves /.well-known/openid-configuration
+func (h *OIDCHandler) Discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, h.oidcService.Discovery())
+}
+
+func (h *OIDCHandler) JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, h.oidcService.JWKS())
+}
+
+// Authorize handles GET (start the flow) and POST (answer the consent prompt).
+// The user is authenticated with their normal access token; the SPA follows
+// `redirect_to` or renders a consent screen when `consent_required` is true.
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req struct {
+		service.AuthorizeRequest
+		Approve *bool `form:"approve" json:"approve"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if c.Request.Method == http.MethodGet {
+		req.Approve = nil // consent can only be given with POST
+	}
+
+	userID := utils.AuthID(ctx)
+	var authTime int64
+	if claims, ok := c.Get("AuthClaims"); ok {
+		// auth_time survives token refreshes; iat is the fallback for older tokens
+		mapClaims := claims.(jwt.MapClaims)
+		if at, ok := mapClaims["auth_time"].(float64); ok {
+			authTime = int64(at)
+		} else if iat, ok := mapClaims["iat"].(float64); ok {
+			authTime = int64(iat)
+		}
+	}
+
+	result, err := h.oidcService.Authorize(ctx, userID, authTime, req.AuthorizeRequest, req.Approve)
+	if err != nil {
+		var oauthErr *service.OAuthError
+		if errors.As(err, &oauthErr) {
+			response.Error(c, oauthErr.Status, oauthErr.Description)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "AuthorizationProcessed"), result)
+}
+
+// Token is the RFC 6749 token endpoint, so it answers in the OAuth format rather than our envelope
+func (h *OIDCHandler) Token(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+
+	tokens, err := h.oidcService.Exchange(ctx, req)
+	if err != nil {
+		var oauthErr *service.OAuthError
+		if errors.As(err, &oauthErr) {
+			c.JSON(oauthErr.Status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error", "error_description": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *OIDCHandler) UserInfo(c *gin.Context) {
+	ctx := c.Request.Context()
+	var scope string
+	if claims, ok := c.Get("AuthClaims"); ok {
+		scope, _ = claims.(jwt.MapClaims)["scope"].(string)
+	}
+
+	info, err := h.oidcService.UserInfo(ctx, utils.AuthID(ctx), scope)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token", "error_description": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+func (h *OIDCHandler) CreateClient(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req struct {
+		Name         string   `json:"name" binding:"required"`
+		RedirectURIs []string `json:"redirect_uris" binding:"required"`
+		Scopes       []string `json:"scopes"`
+		Public       bool     `json:"public"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	client, secret, err := h.oidcService.RegisterClient(ctx, utils.AuthID(ctx), req.Name, req.RedirectURIs, req.Scopes, req.Public)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusCreated, i18n.T(c, "OAuthClientCreated"), gin.H{
+		"client":        client,
+		"client_secret": secret, // only shown once
+	})
+}
+
+func (h *OIDCHandler) ListClients(c *gin.Context) {
+	ctx := c.Request.Context()
+	clients, err := h.oidcService.GetClients(ctx, utils.AuthID(ctx))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "ListOfOAuthClients"), clients)
+}
+
+func (h *OIDCHandler) DeleteClient(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidOAuthClientID"))
+		return
+	}
+	if err := h.oidcService.DeleteClient(ctx, utils.AuthID(ctx), uint(id)); err != nil {
+		if errors.Is(err, service.ErrOAuthClientNotFound) {
+			response.Error(c, http.StatusNotFound, i18n.T(c, "OAuthClientNotFound"))
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "OAuthClientDeleted"), nil)
+}
+
+func (h *OIDCHandler) ListConsents(c *gin.Context) {
+	ctx := c.Request.Context()
+	consents, err := h.oidcService.GetConsents(ctx, utils.AuthID(ctx))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "ListOfConsents"), consents)
+}
+
+func (h *OIDCHandler) RevokeConsent(c *gin.Context) {
+	ctx := c.Request.Context()
+	clientID, err := strconv.Atoi(c.Param("clientId"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidOAuthClientID"))
+		return
+	}
+	if err := h.oidcService.RevokeConsent(ctx, utils.AuthID(ctx), uint(clientID)); err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "ConsentRevoked"), nil)
+}
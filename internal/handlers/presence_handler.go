@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPresenceBatch caps how many ids a single presence lookup may ask for
+const maxPresenceBatch = 100
+
+type PresenceHandler struct {
+	authService service.AuthService
+	userService service.UserService
+}
+
+func NewPresenceHandler(as service.AuthService, us service.UserService) *PresenceHandler {
+	return &PresenceHandler{
+		authService: as,
+		userService: us,
+	}
+}
+
+// Heartbeat lets idle clients (e.g. an open tab) keep the user online
+func (h *PresenceHandler) Heartbeat(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := h.authService.Heartbeat(ctx, utils.AuthID(ctx)); err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "HeartbeatReceived"), nil)
+}
+
+// OnlineUsers lists the users currently online
+func (h *PresenceHandler) OnlineUsers(c *gin.Context) {
+	ctx := c.Request.Context()
+	ids, err := h.authService.GetOnlineUserIDs(ctx)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	users, err := h.userService.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "ListOfOnlineUsers"), users)
+}
+
+// Presence returns the online status for `?ids=1,2,3`
+func (h *PresenceHandler) Presence(c *gin.Context) {
+	ctx := c.Request.Context()
+	var ids []uint
+	for _, raw := range strings.Split(c.Query("ids"), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidUserID"))
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+	if len(ids) > maxPresenceBatch {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "TooManyIDs"))
+		return
+	}
+
+	statuses, err := h.authService.AreUsersOnline(ctx, ids)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "PresenceRetrieved"), statuses)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/storage"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PrivacyHandler struct {
+	privacyService service.PrivacyService
+}
+
+func NewPrivacyHandler(ps service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{privacyService: ps}
+}
+
+// RequestExport starts building an archive of the current user's data (`?format=json|csv`)
+func (h *PrivacyHandler) RequestExport(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req struct {
+		Format string `json:"format" form:"format"`
+	}
+	_ = c.ShouldBind(&req) // the format is optional
+
+	export, err := h.privacyService.RequestExport(ctx, utils.AuthID(ctx), req.Format, c.GetString("locale"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidExportFormat):
+			response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidExportFormat"))
+		case errors.Is(err, service.ErrExportInProgress):
+			response.Error(c, http.StatusConflict, i18n.T(c, "DataExportInProgress"))
+		default:
+			response.Error(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, i18n.T(c, "DataExportQueued"), export)
+}
+
+// ListExports shows the current user's exports and their status
+func (h *PrivacyHandler) ListExports(c *gin.Context) {
+	ctx := c.Request.Context()
+	exports, err := h.privacyService.ListExports(ctx, utils.AuthID(ctx))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "ListOfDataExports"), exports)
+}
+
+// DownloadExport sends the current user on to a signed link to the archive of a ready export
+func (h *PrivacyHandler) DownloadExport(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidInput"))
+		return
+	}
+
+	export, err := h.privacyService.GetReadyExport(ctx, utils.AuthID(ctx), uint(id))
+	if err != nil {
+		h.exportError(c, err)
+		return
+	}
+
+	link, _ := h.privacyService.DownloadURL(export)
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, link)
+}
+
+// DownloadSigned serves an export's archive through a signed link; no access token is needed
+func (h *PrivacyHandler) DownloadSigned(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidInput"))
+		return
+	}
+
+	export, content, err := h.privacyService.OpenSignedExport(ctx, uint(id), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		h.exportError(c, err)
+		return
+	}
+	defer content.Close()
+
+	c.Header("Cache-Control", "private, no-store")
+	c.DataFromReader(http.StatusOK, export.Size, "application/zip", content, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="data-export-%s.zip"`, export.CreatedAt.Format("2006-01-02")),
+	})
+}
+
+func (h *PrivacyHandler) exportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(c, http.StatusNotFound, i18n.T(c, "DataExportNotFound"))
+	case errors.Is(err, service.ErrExportNotReady):
+		response.Error(c, http.StatusConflict, i18n.T(c, "DataExportNotReady"))
+	case errors.Is(err, storage.ErrInvalidSignature):
+		response.Error(c, http.StatusForbidden, i18n.T(c, "InvalidDownloadLink"))
+	case errors.Is(err, storage.ErrURLExpired):
+		response.Error(c, http.StatusForbidden, i18n.T(c, "DownloadLinkExpired"))
+	default:
+		response.Error(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// Erase anonymizes a user's personal data on their behalf (e.g. a request received by email)
+func (h *PrivacyHandler) Erase(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidUserID"))
+		return
+	}
+	var req struct {
+		Reason string `json:"reason" binding:"required,max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.privacyService.EraseUser(ctx, utils.AuthID(ctx), uint(userID), req.Reason); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, i18n.T(c, "UserNotFound"))
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "UserErased"), nil)
+}
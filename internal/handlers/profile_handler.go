@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProfileHandler struct {
+	profileService service.ProfileService
+	cfg            *config.Config
+}
+
+func NewProfileHandler(ps service.ProfileService, cfg *config.Config) *ProfileHandler {
+	return &ProfileHandler{profileService: ps, cfg: cfg}
+}
+
+func (h *ProfileHandler) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	profile, err := h.profileService.GetProfile(ctx, utils.AuthID(ctx))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "ProfileFound"), profile)
+}
+
+// Update changes the fields present in the body; see service.ProfileInput
+func (h *ProfileHandler) Update(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.ProfileInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	profile, err := h.profileService.UpdateProfile(ctx, utils.AuthID(ctx), req)
+	if err != nil {
+		h.profileError(c, err)
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "ProfileUpdated"), profile)
+}
+
+// UploadAvatar takes a multipart form with the image in the "avatar" field
+func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
+	ctx := c.Request.Context()
+	// Leave room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.AvatarMaxBytes+64<<10)
+
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.Error(c, http.StatusRequestEntityTooLarge, i18n.T(c, "AvatarTooLarge"))
+			return
+		}
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "AvatarMissing"))
+		return
+	}
+	if file.Size > h.cfg.AvatarMaxBytes {
+		response.Error(c, http.StatusRequestEntityTooLarge, i18n.T(c, "AvatarTooLarge"))
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, h.cfg.AvatarMaxBytes+1))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	profile, err := h.profileService.UploadAvatar(ctx, utils.AuthID(ctx), data)
+	if err != nil {
+		h.profileError(c, err)
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "AvatarUpdated"), profile)
+}
+
+func (h *ProfileHandler) DeleteAvatar(c *gin.Context) {
+	ctx := c.Request.Context()
+	profile, err := h.profileService.DeleteAvatar(ctx, utils.AuthID(ctx))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "AvatarDeleted"), profile)
+}
+
+// ListFields returns the custom field schema so clients can render the profile form
+func (h *ProfileHandler) ListFields(c *gin.Context) {
+	ctx := c.Request.Context()
+	fields, err := h.profileService.GetProfileFields(ctx)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "ListOfProfileFields"), fields)
+}
+
+func (h *ProfileHandler) CreateField(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req service.ProfileFieldInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	field, err := h.profileService.CreateProfileField(ctx, req)
+	if err != nil {
+		h.profileError(c, err)
+		return
+	}
+	response.Success(c, http.StatusCreated, i18n.T(c, "ProfileFieldCreated"), field)
+}
+
+func (h *ProfileHandler) UpdateField(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidInput"))
+		return
+	}
+	var req service.ProfileFieldInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	field, err := h.profileService.UpdateProfileField(ctx, uint(id), req)
+	if err != nil {
+		h.profileError(c, err)
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "ProfileFieldUpdated"), field)
+}
+
+func (h *ProfileHandler) DeleteField(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidInput"))
+		return
+	}
+	if err := h.profileService.DeleteProfileField(ctx, uint(id)); err != nil {
+		h.profileError(c, err)
+		return
+	}
+	response.Success(c, http.StatusOK, i18n.T(c, "ProfileFieldDeleted"), nil)
+}
+
+func (h *ProfileHandler) profileError(c *gin.Context, err error) {
+	var fieldErr *service.CustomFieldError
+	switch {
+	case errors.As(err, &fieldErr):
+		response.Error(c, http.StatusUnprocessableEntity, fmt.Sprintf("%s: %s", i18n.T(c, "InvalidCustomField"), fieldErr))
+	case errors.Is(err, service.ErrInvalidPhone):
+		response.Error(c, http.StatusUnprocessableEntity, i18n.T(c, "InvalidPhone"))
+	case errors.Is(err, service.ErrInvalidTimezone):
+		response.Error(c, http.StatusUnprocessableEntity, i18n.T(c, "InvalidTimezone"))
+	case errors.Is(err, service.ErrUnsupportedLocale):
+		response.Error(c, http.StatusUnprocessableEntity, i18n.T(c, "UnsupportedLocale"))
+	case errors.Is(err, service.ErrAvatarTooLarge):
+		response.Error(c, http.StatusRequestEntityTooLarge, i18n.T(c, "AvatarTooLarge"))
+	case errors.Is(err, service.ErrUnsupportedImage):
+		response.Error(c, http.StatusUnsupportedMediaType, i18n.T(c, "UnsupportedImage"))
+	case errors.Is(err, service.ErrImageTooLarge):
+		response.Error(c, http.StatusUnprocessableEntity, i18n.T(c, "ImageDimensionsTooLarge"))
+	case errors.Is(err, service.ErrInvalidProfileField):
+		response.Error(c, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, service.ErrProfileFieldKeyTaken):
+		response.Error(c, http.StatusConflict, i18n.T(c, "ProfileFieldKeyTaken"))
+	case errors.Is(err, service.ErrProfileFieldNotFound):
+		response.Error(c, http.StatusNotFound, i18n.T(c, "ProfileFieldNotFound"))
+	default:
+		response.Error(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/middlewares"
+	"golang-api-template/internal/realtime"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = (wsPongWait * 9) / 10
+	wsMaxMessageSize = 4096
+	sseKeepAlive     = 25 * time.Second
+)
+
+type RealtimeHandler struct {
+	hub       *realtime.Hub
+	tickets   service.StreamTicketService
+	accounts  service.AccountService
+	upgrader  websocket.Upgrader
+	ticketTTL time.Duration
+	recheck   time.Duration
+}
+
+// NewRealtimeHandler creates the WebSocket / SSE handler.
+// cfg.AllowedOrigins limits browser WebSocket connections; empty means same-origin only.
+func NewRealtimeHandler(hub *realtime.Hub, tickets service.StreamTicketService, accounts service.AccountService, cfg *config.RealtimeConfig) *RealtimeHandler {
+	h := &RealtimeHandler{
+		hub:       hub,
+		tickets:   tickets,
+		accounts:  accounts,
+		ticketTTL: cfg.TicketTTL,
+		recheck:   cfg.RecheckInterval,
+	}
+	// Open streams must notice a suspended account or an expired token, so this can't be turned off
+	if h.recheck <= 0 {
+		h.recheck = time.Minute
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+	if len(cfg.AllowedOrigins) > 0 {
+		h.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			for _, allowed := range cfg.AllowedOrigins {
+				if origin == allowed {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return h
+}
+
+// Ticket issues a single-use ticket for opening a stream with `?ticket=`.
+// Browsers use it because WebSocket and EventSource cannot send an Authorization header.
+func (h *RealtimeHandler) Ticket(c *gin.Context) {
+	ctx := c.Request.Context()
+	claims, _ := c.Get("AuthClaims")
+	mapClaims, _ := claims.(jwt.MapClaims)
+
+	ticket, err := h.tickets.Issue(ctx, mapClaims)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.Success(c, http.StatusCreated, i18n.T(c, "StreamTicketIssued"), gin.H{
+		"ticket":     ticket,
+		"expires_in": int(h.ticketTTL.Seconds()),
+	})
+}
+
+// clientCommand is what WebSocket clients send to manage their subscriptions
+type clientCommand struct {
+	Action string   `json:"action"` // "subscribe" or "unsubscribe"
+	Topics []string `json:"topics"`
+}
+
+// WebSocket streams events; clients manage topics by sending clientCommand messages
+func (h *RealtimeHandler) WebSocket(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := utils.AuthID(ctx)
+
+	// Check the initial topics while we can still answer with a normal HTTP error
+	initial := parseTopics(c.Query("topics"))
+	for _, topic := range initial {
+		if !realtime.CanSubscribe(userID, topic) {
+			response.Error(c, http.StatusForbidden, i18n.T(c, "TopicNotAllowed"))
+			return
+		}
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade already wrote the HTTP error
+		return
+	}
+
+	client := h.hub.Register(userID)
+	_ = client.Subscribe(initial...)
+
+	expiresAt, _ := middlewares.TokenExpiry(c)
+	go h.wsReadPump(conn, client)
+	h.wsWritePump(ctx, conn, client, expiresAt)
+}
+
+// wsReadPump handles subscription commands and pongs until the connection closes
+func (h *RealtimeHandler) wsReadPump(conn *websocket.Conn, client *realtime.Client) {
+	defer h.hub.Unregister(client)
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var cmd clientCommand
+		if err := conn.ReadJSON(&cmd); err != nil {
+			return
+		}
+		switch cmd.Action {
+		case "subscribe":
+			if err := client.Subscribe(cmd.Topics...); err != nil {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()), time.Now().Add(wsWriteWait))
+				return
+			}
+		case "unsubscribe":
+			client.Unsubscribe(cmd.Topics...)
+		}
+	}
+}
+
+// wsWritePump is the only goroutine writing data frames to the connection
+func (h *RealtimeHandler) wsWritePump(ctx context.Context, conn *websocket.Conn, client *realtime.Client, expiresAt time.Time) {
+	ticker := time.NewTicker(wsPingPeriod)
+	recheck := time.NewTicker(h.recheck)
+	defer func() {
+		ticker.Stop()
+		recheck.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case payload := <-client.Messages():
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				h.hub.Unregister(client)
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				h.hub.Unregister(client)
+				return
+			}
+		case <-recheck.C:
+			if !h.sessionValid(ctx, client.UserID, expiresAt) {
+				h.hub.Unregister(client)
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session ended"), time.Now().Add(wsWriteWait))
+				return
+			}
+		case <-client.Done():
+			// Dropped by the hub (slow consumer, shutdown) or the read pump ended
+			_ = conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "disconnected"), time.Now().Add(wsWriteWait))
+			return
+		}
+	}
+}
+
+// SSE is the fallback for clients that cannot use WebSockets; topics come from `?topics=a,b`
+func (h *RealtimeHandler) SSE(c *gin.Context) {
+	ctx := c.Request.Context()
+	topics := parseTopics(c.Query("topics"))
+	if len(topics) == 0 {
+		topics = []string{realtime.TopicPresence, realtime.UserTopic(utils.AuthID(ctx))}
+	}
+
+	client := h.hub.Register(utils.AuthID(ctx))
+	defer h.hub.Unregister(client)
+	if err := client.Subscribe(topics...); err != nil {
+		response.Error(c, http.StatusForbidden, i18n.T(c, "TopicNotAllowed"))
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	recheck := time.NewTicker(h.recheck)
+	defer recheck.Stop()
+	expiresAt, _ := middlewares.TokenExpiry(c)
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-client.Done():
+			return
+		case payload := <-client.Messages():
+			if _, err := fmt.Fprintf(c.Writer, "data: %s\n\n", payload); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-recheck.C:
+			if !h.sessionValid(ctx, client.UserID, expiresAt) {
+				return
+			}
+		}
+	}
+}
+
+// sessionValid reports whether a stream may stay open: the access token it was opened
+// with has not expired and the account is still active
+func (h *RealtimeHandler) sessionValid(ctx context.Context, userID uint, expiresAt time.Time) bool {
+	if !expiresAt.IsZero() && time.Now().After(expiresAt) {
+		return false
+	}
+	return h.accounts.CheckActive(ctx, userID) == nil
+}
+
+func parseTopics(raw string) []string {
+	var topics []string
+	for _, topic := range strings.Split(raw, ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"golang-api-template/internal/models"
 	"golang-api-template/internal/service"
 	"net/http"
 	"strconv"
@@ -9,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// RoleHandler serves the role routes beyond the generic CRUD ones (see RegisterCrudRoutes)
 type RoleHandler struct {
 	service service.RoleService
 }
@@ -17,91 +17,15 @@ func NewRoleHandler(service service.RoleService) *RoleHandler {
 	return &RoleHandler{service}
 }
 
-func (h *RoleHandler) CreateRole(c *gin.Context) {
-	var role models.Role
-	if err := c.ShouldBindJSON(&role); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	if err := h.service.CreateRole(&role); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, role)
-}
-
-func (h *RoleHandler) GetAllRoles(c *gin.Context) {
-	roles, err := h.service.GetAllRoles()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, roles)
-}
-
-func (h *RoleHandler) GetRoleByID(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role ID"})
-		return
-	}
-
-	role, err := h.service.GetRoleByID(uint(id))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, role)
-}
-
-func (h *RoleHandler) UpdateRole(c *gin.Context) {
-	var role models.Role
-	if err := c.ShouldBindJSON(&role); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role ID"})
-		return
-	}
-	role.ID = uint(id)
-
-	if err := h.service.UpdateRole(&role); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, role)
-}
-
-func (h *RoleHandler) DeleteRole(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role ID"})
-		return
-	}
-
-	if err := h.service.DeleteRole(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"success": "Role deleted"})
-}
 func (h *RoleHandler) GetPermissionsByRoleID(c *gin.Context) {
+	ctx := c.Request.Context()
 	roleID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
 		return
 	}
 
-	permissions, err := h.service.GetPermissionsByRoleID(uint(roleID))
+	permissions, err := h.service.GetPermissionsByRoleID(ctx, uint(roleID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -1,31 +1,43 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"golang-api-template/internal/config"
 	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/middlewares"
+	"golang-api-template/internal/repository"
 	"golang-api-template/internal/service"
 	"golang-api-template/internal/utils"
 	"golang-api-template/pkg/response"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type UserHandler struct {
-	userService  service.UserService
-	emailService *service.EmailService
+	userService        service.UserService
+	emailService       *service.EmailService
+	emailChangeService service.EmailChangeService
+	stepUpMaxAge       time.Duration
 }
 
-func NewUserHandler(us service.UserService, es *service.EmailService) *UserHandler {
+func NewUserHandler(us service.UserService, es *service.EmailService, ecs service.EmailChangeService, cfg *config.Config) *UserHandler {
 	return &UserHandler{
-		userService:  us,
-		emailService: es, // Initialize the EmailService here
+		userService:        us,
+		emailService:       es, // Initialize the EmailService here
+		emailChangeService: ecs,
+		stepUpMaxAge:       time.Duration(cfg.StepUpMaxAgeSec) * time.Second,
 	}
 }
 
 // CREATE
 func (h *UserHandler) Create(c *gin.Context) {
+	ctx := c.Request.Context()
 	var req struct {
 		Name     string `json:"name"`
 		Email    string `json:"email"`
@@ -36,16 +48,12 @@ func (h *UserHandler) Create(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.CreateUser(req.Name, req.Email, req.Password)
+	user, err := h.userService.CreateUser(ctx, req.Name, req.Email, req.Password)
 	if err != nil {
-		// e.g. userService might return "email is already taken"
-		// we can map that if we want, or just show error directly
-		// For demonstration, let's do a direct approach:
-		if err.Error() == "email is already taken" {
+		if errors.Is(err, service.ErrEmailTaken) {
 			response.Error(c, http.StatusConflict, i18n.T(c, "EmailTaken"))
 			return
 		}
-		// fallback
 		response.Error(c, http.StatusInternalServerError, i18n.T(c, "CreateUserError"))
 		return
 	}
@@ -55,6 +63,7 @@ func (h *UserHandler) Create(c *gin.Context) {
 
 // READ (Get user by ID)
 func (h *UserHandler) GetByID(c *gin.Context) {
+	ctx := c.Request.Context()
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -62,7 +71,7 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(uint(id))
+	user, err := h.userService.Get(ctx, uint(id))
 	if err != nil {
 		response.Error(c, http.StatusNotFound, i18n.T(c, "UserNotFound"))
 		return
@@ -72,27 +81,33 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 }
 
 // READ (List users)
+// Supports `filter[email][like]=`, `filter[status]=active`, `sort=-created_at` and `q=`.
+// `?cursor=` (empty for the first page) switches to keyset pagination; `?total=exact|estimate|none`.
 func (h *UserHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	trashed := c.Query("trashed")
+	if !repository.ValidTrashed(trashed) {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidTrashedFilter"))
+		return
+	}
+	// Deleted accounts are only visible to user managers
+	if trashed != repository.TrashedWithout && !h.authorize(c, 0, false) {
+		return
+	}
+
 	pagination := utils.ParsePagination(c)
-	users, total, err := h.userService.GetAllUsers(pagination)
+	users, page, err := h.userService.List(ctx, pagination, trashed)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		listError(c, err)
 		return
 	}
 
-	data := gin.H{
-		"pagination": gin.H{
-			"page":  pagination.Page,
-			"limit": pagination.Limit,
-			"total": total,
-		},
-		"items": users,
-	}
-	response.Success(c, http.StatusOK, i18n.T(c, "ListOfUsers"), data)
+	utils.PaginatedResponse(c, http.StatusOK, i18n.T(c, "ListOfUsers"), users, pagination, page)
 }
 
 // UPDATE
 func (h *UserHandler) Update(c *gin.Context) {
+	ctx := c.Request.Context()
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -110,22 +125,65 @@ func (h *UserHandler) Update(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.UpdateUser(uint(id), req.Name, req.Email, req.Password)
-	if err != nil {
-		// Check for "email is already taken"
-		if err.Error() == "email is already taken by another user" {
-			response.Error(c, http.StatusConflict, i18n.T(c, "EmailTaken"))
+	// Only the account owner or a user manager may change an account
+	if !h.authorize(c, uint(id), true) {
+		return
+	}
+	// Someone else's password can only be changed through a password reset
+	if uint(id) != utils.AuthID(ctx) && req.Password != "" {
+		response.Error(c, http.StatusForbidden, i18n.T(c, "CannotSetOthersPassword"))
+		return
+	}
+
+	// Credentials must never be changed by support staff acting as the user
+	if middlewares.IsImpersonating(c) && req.Password != "" {
+		response.Error(c, http.StatusForbidden, i18n.T(c, "NotAllowedWhileImpersonating"))
+		return
+	}
+
+	// Changing credentials needs a recent login
+	var emailChanged bool
+	if req.Password != "" || req.Email != "" {
+		existing, err := h.userService.Get(ctx, uint(id))
+		if err != nil {
+			response.Error(c, http.StatusNotFound, i18n.T(c, "UserNotFound"))
+			return
+		}
+		emailChanged = req.Email != "" && !strings.EqualFold(req.Email, existing.Email)
+		if (req.Password != "" || emailChanged) && !middlewares.HasRecentAuth(c, h.stepUpMaxAge) {
+			middlewares.AbortReauthenticationRequired(c, h.stepUpMaxAge)
+			return
+		}
+	}
+
+	// A new email only takes effect once the new address confirms it
+	if emailChanged {
+		if _, err := h.emailChangeService.RequestEmailChange(ctx, uint(id), req.Email, c.GetString("locale")); err != nil {
+			if errors.Is(err, service.ErrEmailTaken) {
+				response.Error(c, http.StatusConflict, i18n.T(c, "EmailTaken"))
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, i18n.T(c, "UpdateUserError"))
 			return
 		}
+	}
+
+	user, err := h.userService.UpdateUser(ctx, uint(id), req.Name, req.Password)
+	if err != nil {
 		response.Error(c, http.StatusInternalServerError, i18n.T(c, "UpdateUserError"))
 		return
 	}
 
+	if emailChanged {
+		response.Success(c, http.StatusOK, i18n.T(c, "EmailChangeRequested"), user)
+		return
+	}
 	response.Success(c, http.StatusOK, i18n.T(c, "UserUpdated"), user)
 }
 
 // DELETE
 func (h *UserHandler) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -133,23 +191,86 @@ func (h *UserHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	err = h.userService.DeleteUser(uint(id))
-	if err != nil {
+	// Users may move their own account to the trash; anything else takes users.manage
+	force := c.Query("force") == "true"
+	if !h.authorize(c, uint(id), !force) {
+		return
+	}
+
+	// `?force=true` removes the user for good instead of moving them to the trash
+	if err := h.userService.Delete(ctx, uint(id), force); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, i18n.T(c, "UserNotFound"))
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, i18n.T(c, "DeleteUserError"))
 		return
 	}
 
+	if force {
+		response.Success(c, http.StatusOK, i18n.T(c, "UserPermanentlyDeleted"), nil)
+		return
+	}
 	response.Success(c, http.StatusOK, i18n.T(c, "UserDeleted"), nil)
 }
 
+// Restore brings a soft-deleted user back
+func (h *UserHandler) Restore(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, i18n.T(c, "InvalidUserID"))
+		return
+	}
+
+	user, err := h.userService.Restore(ctx, uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.Error(c, http.StatusNotFound, i18n.T(c, "DeletedUserNotFound"))
+		case errors.Is(err, service.ErrEmailTaken):
+			response.Error(c, http.StatusConflict, i18n.T(c, "EmailTaken"))
+		case errors.Is(err, service.ErrUserErased):
+			response.Error(c, http.StatusConflict, i18n.T(c, "ErasedUserNotRestorable"))
+		default:
+			response.Error(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, i18n.T(c, "UserRestored"), user)
+}
+
+// authorize lets the request through when the caller holds users.manage or, with
+// allowSelf, when targetID is their own account. Otherwise it answers with 403.
+func (h *UserHandler) authorize(c *gin.Context, targetID uint, allowSelf bool) bool {
+	ctx := c.Request.Context()
+	actorID := utils.AuthID(ctx)
+	if allowSelf && targetID == actorID {
+		return true
+	}
+
+	allowed, err := h.userService.HasPermission(ctx, actorID, service.PermissionManageUsers)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if !allowed {
+		response.Error(c, http.StatusForbidden, i18n.T(c, "PermissionDenied"))
+		return false
+	}
+	return true
+}
+
 func (h *UserHandler) GetPermissionsByUserID(c *gin.Context) {
+	ctx := c.Request.Context()
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	permissions, err := h.userService.GetPermissionsByUserID(uint(userID))
+	permissions, err := h.userService.GetPermissionsByUserID(ctx, uint(userID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -159,6 +280,7 @@ func (h *UserHandler) GetPermissionsByUserID(c *gin.Context) {
 }
 
 func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	ctx := c.Request.Context()
 	var request struct {
 		Email string `json:"email"`
 	}
@@ -167,13 +289,13 @@ func (h *UserHandler) ForgotPassword(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.FindByEmail(request.Email)
+	user, err := h.userService.FindByEmail(ctx, request.Email)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"message": i18n.T(c, "PasswordResetRequestSuccess")})
 		return
 	}
 
-	resetToken, err := h.userService.GeneratePasswordResetToken(user)
+	resetToken, err := h.userService.GeneratePasswordResetToken(ctx, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.T(c, "PasswordResetRequestError")})
 		return
@@ -187,6 +309,7 @@ func (h *UserHandler) ForgotPassword(c *gin.Context) {
 }
 
 func (h *UserHandler) ResetPassword(c *gin.Context) {
+	ctx := c.Request.Context()
 	var request struct {
 		Token    string `json:"token"`
 		Password string `json:"password"`
@@ -196,7 +319,7 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	err := h.userService.ResetPassword(request.Token, request.Password)
+	err := h.userService.ResetPassword(ctx, request.Token, request.Password)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.T(c, "InvalidOrExpiredToken")})
 		return
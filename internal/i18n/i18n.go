@@ -64,3 +64,15 @@ func TT(lang, key string) string {
 	}
 	return key // Fallback to the key itself if not found
 }
+
+// IsSupported reports whether translations exist for lang
+func IsSupported(lang string) bool {
+	_, ok := translations[lang]
+	return ok
+}
+
+// Has reports whether key has an English translation, which every key must have
+func Has(key string) bool {
+	_, ok := translations["en"][key]
+	return ok
+}
@@ -1,28 +1,37 @@
 package middlewares
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"golang-api-template/internal/config"
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
 	"golang-api-template/pkg/response"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// AuthMiddleware validates the access token and stores the user in the context.
+// Tokens of accounts that are no longer active are rejected, and requests made
+// with an impersonation ("act as") token are audit-logged.
+func AuthMiddleware(cfg *config.Config, accounts service.AccountService, audit service.AuditService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		tokenStr := bearerOrCookieToken(c, cfg.Cookie)
+		if tokenStr == "" {
 			response.Error(c, http.StatusUnauthorized, "missing Authorization header")
 			c.Abort()
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-
 		// Validate the Access token
 		claims, err := validateAccessToken(tokenStr, cfg.JWTAccessSecret)
 		if err != nil {
@@ -30,20 +39,207 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		// Tokens granted to relying parties only open /oauth/userinfo (see UserInfoAuth).
+		// They are signed with the provider key now; this also stops older ones.
+		if _, ok := claims["client_id"]; ok {
+			response.Error(c, http.StatusUnauthorized, "access token was issued to a client application")
+			c.Abort()
+			return
+		}
 
-		// Optionally store user ID in context
+		// Store the user ID in the request context, where handlers and services read it
 		userID, ok := claims["user_id"].(float64)
 		if ok {
-			fmt.Println("User ID:", userID)
-			c.Set("AuthID", uint(userID))
+			c.Request = c.Request.WithContext(utils.WithAuthID(c.Request.Context(), uint(userID)))
+
+			// Suspending or deactivating an account must end its sessions right away
+			if err := accounts.CheckActive(c.Request.Context(), uint(userID)); err != nil {
+				if key, inactive := AccountErrorKey(err); inactive {
+					response.Error(c, http.StatusForbidden, i18n.T(c, key))
+				} else {
+					response.Error(c, http.StatusInternalServerError, err.Error())
+				}
+				c.Abort()
+				return
+			}
+		}
+		c.Set("AuthClaims", claims)
+
+		// Impersonation tokens carry the real user in the `act` claim
+		actorID, impersonating := actorFromClaims(claims)
+		if !impersonating {
+			c.Next()
+			return
+		}
+		c.Set("ActorID", actorID)
+
+		c.Next()
+
+		entry := &models.AuditLog{
+			ActorID:   actorID,
+			UserID:    utils.AuthID(c.Request.Context()),
+			Action:    "impersonation.request",
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Status:    c.Writer.Status(),
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}
+		// The audit entry is written after the response, even if the client has gone
+		if err := audit.Record(context.WithoutCancel(c.Request.Context()), entry); err != nil {
+			log.Printf("failed to audit impersonated request: %v", err)
+		}
+	}
+}
+
+// UserInfoAuth accepts the access tokens issued to relying parties as well as
+// first-party ones, which it hands to userAuth (AuthMiddleware). Only the userinfo
+// endpoint may use it.
+func UserInfoAuth(oidc service.OIDCService, accounts service.AccountService, userAuth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, claims, err := oidc.ValidateAccessToken(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		if err != nil {
+			userAuth(c)
+			return
+		}
+
+		c.Request = c.Request.WithContext(utils.WithAuthID(c.Request.Context(), userID))
+		if err := accounts.CheckActive(c.Request.Context(), userID); err != nil {
+			if key, inactive := AccountErrorKey(err); inactive {
+				response.Error(c, http.StatusForbidden, i18n.T(c, key))
+			} else {
+				response.Error(c, http.StatusInternalServerError, err.Error())
+			}
+			c.Abort()
+			return
+		}
+		c.Set("AuthClaims", claims)
+		c.Next()
+	}
+}
+
+// AccountErrorKey returns the i18n key describing why an account may not authenticate,
+// or false if err is not an account status error
+func AccountErrorKey(err error) (string, bool) {
+	switch {
+	case errors.Is(err, service.ErrAccountPending):
+		return "AccountPending", true
+	case errors.Is(err, service.ErrAccountSuspended):
+		return "AccountSuspended", true
+	case errors.Is(err, service.ErrAccountDeactivated):
+		return "AccountDeactivated", true
+	case errors.Is(err, service.ErrAccountPendingDeletion):
+		return "AccountPendingDeletion", true
+	case errors.Is(err, service.ErrAccountNotFound):
+		return "AccountNotFound", true
+	default:
+		return "", false
+	}
+}
+
+// bearerOrCookieToken prefers the Authorization header and falls back to the
+// access token cookie when cookie transport is enabled
+func bearerOrCookieToken(c *gin.Context, cookieCfg *config.CookieConfig) string {
+	if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+		return strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	if cookieCfg.Enabled {
+		if token, err := c.Cookie(cookieCfg.AccessName); err == nil {
+			return token
+		}
+	}
+	return ""
+}
+
+// StreamAuth authenticates streaming routes. Browser WebSockets and EventSource cannot
+// set headers, so they pass a single-use `?ticket=` from POST /realtime/ticket instead
+// of the access token; other clients go through userAuth (AuthMiddleware) as usual.
+func StreamAuth(tickets service.StreamTicketService, accounts service.AccountService, userAuth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			userAuth(c)
+			return
 		}
 
+		claims, err := tickets.Redeem(c.Request.Context(), ticket)
+		if err != nil {
+			response.Error(c, http.StatusUnauthorized, "invalid or expired stream ticket")
+			c.Abort()
+			return
+		}
+		userID, ok := claims["user_id"].(float64)
+		if !ok {
+			response.Error(c, http.StatusUnauthorized, "invalid or expired stream ticket")
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(utils.WithAuthID(c.Request.Context(), uint(userID)))
+		if err := accounts.CheckActive(c.Request.Context(), uint(userID)); err != nil {
+			if key, inactive := AccountErrorKey(err); inactive {
+				response.Error(c, http.StatusForbidden, i18n.T(c, key))
+			} else {
+				response.Error(c, http.StatusInternalServerError, err.Error())
+			}
+			c.Abort()
+			return
+		}
+		c.Set("AuthClaims", claims)
+		if actorID, impersonating := actorFromClaims(claims); impersonating {
+			c.Set("ActorID", actorID)
+		}
 		c.Next()
 	}
 }
 
+// TokenExpiry returns when the access token of the request expires
+func TokenExpiry(c *gin.Context) (time.Time, bool) {
+	claims, ok := c.Get("AuthClaims")
+	if !ok {
+		return time.Time{}, false
+	}
+	exp, ok := claims.(jwt.MapClaims)["exp"].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(exp), 0), true
+}
+
+// DenyImpersonation blocks sensitive routes for impersonation tokens
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsImpersonating(c) {
+			response.Error(c, http.StatusForbidden, i18n.T(c, "NotAllowedWhileImpersonating"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsImpersonating reports whether the request was made with an "act as" token
+func IsImpersonating(c *gin.Context) bool {
+	return c.GetUint("ActorID") != 0
+}
+
+func actorFromClaims(claims jwt.MapClaims) (uint, bool) {
+	act, ok := claims["act"].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	sub, ok := act["sub"].(float64)
+	if !ok || sub <= 0 {
+		return 0, false
+	}
+	return uint(sub), true
+}
+
 func validateAccessToken(tokenStr, secret string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
 		return []byte(secret), nil
 	})
 	if err != nil {
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission only lets the request through when the authenticated user
+// has the given permission through one of their roles. Must run after AuthMiddleware.
+func RequirePermission(userService service.UserService, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.AuthID(c.Request.Context())
+		if userID == 0 {
+			response.Error(c, http.StatusUnauthorized, "missing authenticated user")
+			c.Abort()
+			return
+		}
+
+		permissions, err := userService.GetPermissionsByUserID(c.Request.Context(), userID)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, err.Error())
+			c.Abort()
+			return
+		}
+		for _, perm := range permissions {
+			if perm.Name == permission {
+				c.Next()
+				return
+			}
+		}
+
+		response.Error(c, http.StatusForbidden, i18n.T(c, "PermissionDenied"))
+		c.Abort()
+	}
+}
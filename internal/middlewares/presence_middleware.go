@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"log"
+
+	"golang-api-template/internal/service"
+	"golang-api-template/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrackPresence extends the authenticated user's online presence on every request.
+// Must run after AuthMiddleware. Impersonated requests don't count as the user being online.
+func TrackPresence(authService service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := utils.AuthID(c.Request.Context())
+		if userID != 0 && !IsImpersonating(c) {
+			if err := authService.Heartbeat(c.Request.Context(), userID); err != nil {
+				log.Printf("failed to update presence for user %d: %v", userID, err)
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang-api-template/internal/i18n"
+	"golang-api-template/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// RequireRecentAuth only lets the request through when the user authenticated
+// (logged in or called /auth/reauthenticate) within maxAge.
+// Must be used after AuthMiddleware.
+func RequireRecentAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasRecentAuth(c, maxAge) {
+			AbortReauthenticationRequired(c, maxAge)
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasRecentAuth reports whether the access token's auth_time is within maxAge.
+// Tokens without auth_time (issued before step-up existed) never count as recent.
+func HasRecentAuth(c *gin.Context, maxAge time.Duration) bool {
+	claims, ok := c.Get("AuthClaims")
+	if !ok {
+		return false
+	}
+	authTime, ok := claims.(jwt.MapClaims)["auth_time"].(float64)
+	if !ok {
+		return false
+	}
+	return time.Since(time.Unix(int64(authTime), 0)) <= maxAge
+}
+
+// AbortReauthenticationRequired answers with 401 and the RFC 9470 challenge so
+// clients know to call /auth/reauthenticate and retry
+func AbortReauthenticationRequired(c *gin.Context, maxAge time.Duration) {
+	c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_user_authentication", max_age=%d`, int(maxAge.Seconds())))
+	response.Error(c, http.StatusUnauthorized, i18n.T(c, "ReauthenticationRequired"))
+	c.Abort()
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"golang-api-template/internal/migrations/baseline"
+
+	"gorm.io/gorm"
+)
+
+// The schema as GORM's AutoMigrate left it before versioned migrations. On databases
+// created back then the tables already exist and AutoMigrate only adds what is missing.
+func init() {
+	register(upBaseline, downBaseline)
+}
+
+func upBaseline(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(baseline.Models()...); err != nil {
+		return err
+	}
+	return dropLegacyUniqueIndexes(tx)
+}
+
+func downBaseline(tx *gorm.DB) error {
+	return dropTables(tx, baseline.Tables()...)
+}
+
+// dropLegacyUniqueIndexes removes the single-column unique indexes that were replaced by
+// (column, deleted_id) ones; they would keep soft-deleted rows blocking their email / name
+func dropLegacyUniqueIndexes(tx *gorm.DB) error {
+	legacy := []struct {
+		model interface{}
+		index string
+	}{
+		{&baseline.User{}, "idx_users_email"},
+		{&baseline.Role{}, "idx_roles_name"},
+	}
+	for _, l := range legacy {
+		if tx.Migrator().HasIndex(l.model, l.index) {
+			if err := tx.Migrator().DropIndex(l.model, l.index); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
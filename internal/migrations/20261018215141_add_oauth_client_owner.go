@@ -0,0 +1,32 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// OAuth clients remember who registered them, so the registry can be scoped to owners
+func init() {
+	register(upAddOAuthClientOwner, downAddOAuthClientOwner)
+}
+
+type oauthClientOwner struct {
+	OwnerID uint `gorm:"index"`
+}
+
+func (oauthClientOwner) TableName() string { return "o_auth_clients" }
+
+func upAddOAuthClientOwner(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.AddColumn(&oauthClientOwner{}, "OwnerID"); err != nil {
+		return err
+	}
+	return m.CreateIndex(&oauthClientOwner{}, "OwnerID")
+}
+
+func downAddOAuthClientOwner(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if m.HasIndex(&oauthClientOwner{}, "OwnerID") {
+		if err := m.DropIndex(&oauthClientOwner{}, "OwnerID"); err != nil {
+			return err
+		}
+	}
+	return m.DropColumn(&oauthClientOwner{}, "OwnerID")
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Users remember who scheduled their deletion, so logging in only cancels the ones they asked for
+func init() {
+	register(upAddUserDeletionRequestedBy, downAddUserDeletionRequestedBy)
+}
+
+type userDeletionRequestedBy struct {
+	ID                  uint
+	Status              string
+	StatusReason        string
+	DeletionRequestedBy uint
+}
+
+func (userDeletionRequestedBy) TableName() string { return "users" }
+
+func upAddUserDeletionRequestedBy(tx *gorm.DB) error {
+	if err := tx.Migrator().AddColumn(&userDeletionRequestedBy{}, "DeletionRequestedBy"); err != nil {
+		return err
+	}
+
+	// Until now a deletion the user asked for was only told apart by its reason
+	return tx.Model(&userDeletionRequestedBy{}).
+		Where("status = ? AND status_reason = ?", "pending_deletion", "requested by user").
+		Update("deletion_requested_by", gorm.Expr("id")).Error
+}
+
+func downAddUserDeletionRequestedBy(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&userDeletionRequestedBy{}, "DeletionRequestedBy")
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Data export archives move from DATA_EXPORT_DIR to the storage backend, so exports
+// keep a storage key instead of a file path. Archives already built cannot be served
+// any more; their exports are dropped and the user can request a new one.
+func init() {
+	register(upStoreDataExportsInStorage, downStoreDataExportsInStorage)
+}
+
+type dataExportArchive struct {
+	ID         uint
+	FilePath   string
+	StorageKey string
+}
+
+func (dataExportArchive) TableName() string { return "data_exports" }
+
+func upStoreDataExportsInStorage(tx *gorm.DB) error {
+	if err := tx.Where("file_path <> ''").Delete(&dataExportArchive{}).Error; err != nil {
+		return err
+	}
+	return tx.Migrator().RenameColumn(&dataExportArchive{}, "file_path", "storage_key")
+}
+
+func downStoreDataExportsInStorage(tx *gorm.DB) error {
+	if err := tx.Where("storage_key <> ''").Delete(&dataExportArchive{}).Error; err != nil {
+		return err
+	}
+	return tx.Migrator().RenameColumn(&dataExportArchive{}, "storage_key", "file_path")
+}
@@ -0,0 +1,187 @@
+// Package baseline freezes the models as they were when the schema moved to versioned
+// migrations, so the baseline migration always creates the same tables. Never change
+// these types; schema changes go into a new migration.
+package baseline
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type User struct {
+	gorm.Model
+	Name                string `gorm:"size:100"`
+	Email               string `gorm:"size:255;uniqueIndex:idx_users_email_live"`
+	Password            string `gorm:"size:225"`
+	Roles               []Role `gorm:"many2many:user_roles"`
+	ResetToken          string `gorm:"index"`
+	AuthSource          string `gorm:"size:20;default:local"`
+	Status              string `gorm:"size:20;default:active;index"`
+	StatusReason        string `gorm:"size:255"`
+	StatusChangedAt     *time.Time
+	DeletionScheduledAt *time.Time   `gorm:"index"`
+	Organization        string       `gorm:"size:100"`
+	Profile             *UserProfile `gorm:"foreignKey:UserID"`
+	LastSeenAt          *time.Time
+	DeletedID           uint `gorm:"uniqueIndex:idx_users_email_live;not null;default:0"`
+}
+
+type Role struct {
+	gorm.Model
+	Name        string       `gorm:"type:varchar(255);uniqueIndex:idx_roles_name_live;not null"`
+	Permissions []Permission `gorm:"many2many:role_permissions"`
+	DeletedID   uint         `gorm:"uniqueIndex:idx_roles_name_live;not null;default:0"`
+}
+
+type Permission struct {
+	gorm.Model
+	Name  string `gorm:"type:varchar(255);uniqueIndex;not null"`
+	Roles []Role `gorm:"many2many:role_permissions"`
+}
+
+type OAuthClient struct {
+	gorm.Model
+	ClientID     string `gorm:"type:varchar(64);uniqueIndex;not null"`
+	ClientSecret string `gorm:"size:255"`
+	Name         string `gorm:"size:100;not null"`
+	RedirectURIs string `gorm:"type:text"`
+	Scopes       string `gorm:"size:255"`
+	Public       bool
+}
+
+type OAuthConsent struct {
+	gorm.Model
+	UserID   uint   `gorm:"uniqueIndex:idx_consent_user_client;not null"`
+	ClientID uint   `gorm:"uniqueIndex:idx_consent_user_client;not null"`
+	Scopes   string `gorm:"size:255"`
+}
+
+type AuditLog struct {
+	gorm.Model
+	ActorID   uint   `gorm:"index"`
+	UserID    uint   `gorm:"index"`
+	Action    string `gorm:"size:100;index;not null"`
+	Method    string `gorm:"size:10"`
+	Path      string `gorm:"size:255"`
+	Status    int
+	IP        string `gorm:"size:45"`
+	UserAgent string `gorm:"size:255"`
+	Details   string `gorm:"type:text"`
+}
+
+type LoginEvent struct {
+	gorm.Model
+	UserID        uint   `gorm:"index"`
+	Email         string `gorm:"size:255;index"`
+	Success       bool
+	Method        string `gorm:"size:20"`
+	FailureReason string `gorm:"size:100"`
+	IP            string `gorm:"size:45"`
+	IPPrefix      string `gorm:"size:45;index"`
+	UserAgent     string `gorm:"size:255"`
+	DeviceID      string `gorm:"size:64"`
+	Country       string `gorm:"size:2"`
+	City          string `gorm:"size:100"`
+	NewDevice     bool
+}
+
+type KnownDevice struct {
+	gorm.Model
+	UserID      uint   `gorm:"uniqueIndex:idx_device_user_fingerprint;not null"`
+	Fingerprint string `gorm:"size:64;uniqueIndex:idx_device_user_fingerprint;not null"`
+	UserAgent   string `gorm:"size:255"`
+	LastIP      string `gorm:"size:45"`
+	LastSeenAt  time.Time
+}
+
+type EmailChange struct {
+	gorm.Model
+	UserID           uint   `gorm:"index;not null"`
+	OldEmail         string `gorm:"size:255;not null"`
+	NewEmail         string `gorm:"size:255;index;not null"`
+	ConfirmTokenHash string `gorm:"size:64;uniqueIndex"`
+	RevertTokenHash  string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt        time.Time
+	RevertExpiresAt  time.Time
+	ConfirmedAt      *time.Time
+	RevertedAt       *time.Time
+	CancelledAt      *time.Time
+}
+
+type Invitation struct {
+	gorm.Model
+	Email        string `gorm:"size:255;index;not null"`
+	Organization string `gorm:"size:100"`
+	Roles        []Role `gorm:"many2many:invitation_roles"`
+	InvitedByID  uint   `gorm:"index"`
+	TokenHash    string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt    time.Time
+	SentCount    int
+	LastSentAt   *time.Time
+	AcceptedAt   *time.Time
+	AcceptedByID *uint
+	RevokedAt    *time.Time
+}
+
+type DataExport struct {
+	gorm.Model
+	UserID      uint   `gorm:"index;not null"`
+	Format      string `gorm:"size:10;not null"`
+	Status      string `gorm:"size:20;index;not null"`
+	FilePath    string `gorm:"size:255"`
+	Size        int64
+	Error       string `gorm:"size:255"`
+	CompletedAt *time.Time
+	ExpiresAt   *time.Time `gorm:"index"`
+}
+
+type UserProfile struct {
+	gorm.Model
+	UserID       uint   `gorm:"uniqueIndex;not null"`
+	DisplayName  string `gorm:"size:100"`
+	Phone        string `gorm:"size:32"`
+	Timezone     string `gorm:"size:64"`
+	Locale       string `gorm:"size:10"`
+	AvatarKey    string `gorm:"size:255"`
+	CustomFields string `gorm:"type:text"`
+}
+
+type ProfileField struct {
+	gorm.Model
+	Key       string `gorm:"type:varchar(64);uniqueIndex;not null"`
+	Label     string `gorm:"size:100;not null"`
+	Type      string `gorm:"size:20;not null"`
+	Required  bool
+	Options   string `gorm:"type:text"`
+	MaxLength int
+}
+
+type File struct {
+	gorm.Model
+	OwnerID     uint   `gorm:"index;not null"`
+	Key         string `gorm:"type:varchar(255);uniqueIndex;not null"`
+	Name        string `gorm:"size:255;not null"`
+	ContentType string `gorm:"size:100;not null"`
+	Size        int64  `gorm:"not null"`
+	Checksum    string `gorm:"size:64"`
+}
+
+// Models lists every table of the baseline, in the order AutoMigrate received them
+func Models() []interface{} {
+	return []interface{}{
+		&User{}, &Role{}, &Permission{}, &OAuthClient{}, &OAuthConsent{}, &AuditLog{},
+		&LoginEvent{}, &KnownDevice{}, &EmailChange{}, &Invitation{}, &DataExport{},
+		&UserProfile{}, &ProfileField{}, &File{},
+	}
+}
+
+// Tables lists the tables to drop when the baseline is rolled back, join tables first
+func Tables() []string {
+	return []string{
+		"user_roles", "role_permissions", "invitation_roles",
+		"user_profiles", "o_auth_consents", "files", "profile_fields", "data_exports",
+		"invitations", "email_changes", "known_devices", "login_events", "audit_logs",
+		"o_auth_clients", "permissions", "roles", "users",
+	}
+}
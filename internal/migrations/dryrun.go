@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+// Statements that only read, which a dry run still sends to the database so
+// migrations can inspect the current schema (e.g. AutoMigrate's HasTable)
+var readPattern = regexp.MustCompile(`(?i)^\s*(SELECT|SHOW|WITH|PRAGMA|EXPLAIN|DESCRIBE)\b`)
+
+var errDryRunQuery = errors.New("migrations: dry run cannot return rows for a write")
+
+// dryRunPool is a gorm.ConnPool that prints writes instead of executing them
+type dryRunPool struct {
+	gorm.ConnPool
+	dialector gorm.Dialector
+	out       io.Writer
+}
+
+func (p *dryRunPool) print(query string, args []interface{}) {
+	fmt.Fprintf(p.out, "%s;\n", p.dialector.Explain(query, args...))
+}
+
+func (p *dryRunPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("migrations: dry run does not support prepared statements")
+}
+
+func (p *dryRunPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	p.print(query, args)
+	return driver.RowsAffected(0), nil
+}
+
+func (p *dryRunPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if readPattern.MatchString(query) {
+		return p.ConnPool.QueryContext(ctx, query, args...)
+	}
+	p.print(query, args)
+	return nil, errDryRunQuery
+}
+
+func (p *dryRunPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if readPattern.MatchString(query) {
+		return p.ConnPool.QueryRowContext(ctx, query, args...)
+	}
+	// A write returning a row, e.g. INSERT ... RETURNING: print it and scan nothing
+	p.print(query, args)
+	return p.ConnPool.QueryRowContext(ctx, "SELECT 1 WHERE 1 = 0")
+}
+
+// BeginTx lets migration steps run in their usual transaction, which does nothing here
+func (p *dryRunPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return &dryRunTx{p}, nil
+}
+
+type dryRunTx struct {
+	*dryRunPool
+}
+
+func (*dryRunTx) Commit() error   { return nil }
+func (*dryRunTx) Rollback() error { return nil }
@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const lockName = "schema_migrations"
+
+var ErrLockTimeout = errors.New("migrations: timed out waiting for another instance to finish migrating")
+
+// withLock runs fn while holding a database advisory lock. The lock belongs to a
+// connection set aside for it, and is released when fn returns or the connection drops.
+// SQLite has no advisory locks; it only allows one writer anyway.
+func withLock(db *gorm.DB, timeout time.Duration, fn func() error) error {
+	driverName := db.Dialector.Name()
+	if driverName != "mysql" && driverName != "postgres" {
+		return fn()
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// 1. Acquire
+	if driverName == "mysql" {
+		err = lockMySQL(ctx, conn, timeout)
+	} else {
+		err = lockPostgres(ctx, conn, timeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 2. Release once done, whatever fn returned
+	defer func() {
+		if driverName == "mysql" {
+			conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", lockName)
+		} else {
+			conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", postgresLockKey())
+		}
+	}()
+	return fn()
+}
+
+func lockMySQL(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	// GET_LOCK returns 1 once acquired, 0 on timeout and NULL on error
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, int(timeout.Seconds())).Scan(&acquired); err != nil {
+		return fmt.Errorf("migrations: acquiring the lock: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		return ErrLockTimeout
+	}
+	return nil
+}
+
+// lockPostgres polls, because pg_advisory_lock itself waits forever
+func lockPostgres(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", postgresLockKey()).Scan(&acquired); err != nil {
+			return fmt.Errorf("migrations: acquiring the lock: %w", err)
+		}
+		if acquired {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// postgresLockKey is the bigint key of the lock; Postgres locks are numbered, not named
+func postgresLockKey() int64 {
+	return int64(crc32.ChecksumIEEE([]byte(lockName)))
+}
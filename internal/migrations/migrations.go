@@ -1,16 +1,80 @@
+// Package migrations holds the versioned schema migrations. Each migration lives in
+// its own file named <version>_<name>.go, where version is a UTC timestamp such as
+// 20261018000000, and registers its up and down steps from init:
+//
+//	func init() {
+//		register(upAddUserNickname, downAddUserNickname)
+//	}
+//
+// Create new ones with `go run ./cmd/migrate create add_user_nickname`. Migrations
+// must not use the models package: models change over time while a migration has to
+// keep creating the same schema, so declare the columns it needs locally.
 package migrations
 
 import (
-	"golang-api-template/internal/models"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// AutoMigrate runs GORM's automigration for all models
-func AutoMigrateDatabase(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&models.User{},
-		&models.Role{},
-		&models.Permission{},
-	)
+// Migration is one reversible schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error // nil when the migration cannot be reverted
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+var (
+	registry    []Migration
+	filePattern = regexp.MustCompile(`^(\d+)_(\w+)\.go$`)
+)
+
+// register adds the migration defined in the calling file, named <version>_<name>.go
+func register(up, down func(tx *gorm.DB) error) {
+	_, file, _, ok := runtime.Caller(1)
+	if !ok {
+		panic("migrations: cannot find the file registering a migration")
+	}
+	match := filePattern.FindStringSubmatch(filepath.Base(file))
+	if match == nil {
+		panic(fmt.Sprintf("migrations: %s is not named <version>_<name>.go", filepath.Base(file)))
+	}
+	version, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		panic(fmt.Sprintf("migrations: %s: %v", filepath.Base(file), err))
+	}
+	for _, m := range registry {
+		if m.Version == version {
+			panic(fmt.Sprintf("migrations: %s and %s share version %d", m, match[0], version))
+		}
+	}
+
+	registry = append(registry, Migration{Version: version, Name: match[2], Up: up, Down: down})
+	sort.Slice(registry, func(i, j int) bool { return registry[i].Version < registry[j].Version })
+}
+
+// All returns the registered migrations, oldest first
+func All() []Migration {
+	return append([]Migration(nil), registry...)
+}
+
+// dropTables drops tables in the given order, for down steps
+func dropTables(tx *gorm.DB, tables ...string) error {
+	for _, table := range tables {
+		if err := tx.Exec("DROP TABLE IF EXISTS ?", clause.Table{Name: table}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
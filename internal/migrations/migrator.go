@@ -0,0 +1,325 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"golang-api-template/internal/config"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	ErrDirty        = errors.New("a migration failed halfway and the schema needs fixing by hand")
+	ErrIrreversible = errors.New("migration cannot be reverted")
+	ErrUnknown      = errors.New("migration is not part of this build")
+)
+
+// schemaMigration is a row of schema_migrations, one per applied migration. Dirty is
+// set while a step runs and stays set if it fails on a database without transactional
+// DDL (MySQL), where a failed step can leave part of its changes behind.
+type schemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255;not null"`
+	Dirty     bool   `gorm:"not null;default:false"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus is a registered or recorded migration, see Migrator.Status
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+	Missing   bool // recorded as applied but not part of this build
+}
+
+// Migrator applies and reverts the registered migrations, recording them in
+// schema_migrations. Runs that change the schema hold a database advisory lock, so
+// several instances starting at once apply each migration only once.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+
+	// DryRun, when set, receives the SQL the up / down steps would execute instead of
+	// running it. Reads still hit the database, and nothing is recorded.
+	DryRun io.Writer
+
+	// LockTimeout is how long to wait for another instance that is migrating
+	LockTimeout time.Duration
+}
+
+// NewMigrator returns a migrator for db. Its statements run without DB_QUERY_TIMEOUT,
+// since schema changes on large tables take a while.
+func NewMigrator(db *gorm.DB) *Migrator {
+	db = db.WithContext(config.WithoutQueryTimeout(context.Background()))
+	return &Migrator{db: db, migrations: All(), LockTimeout: time.Minute}
+}
+
+// Pending returns the migrations that were not applied yet, oldest first
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Status lists every registered migration and every recorded one, by version
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			s.Applied, s.Dirty, s.AppliedAt = true, row.Dirty, &row.AppliedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version: row.Version, Name: row.Name, Applied: true, Dirty: row.Dirty, AppliedAt: &appliedAt, Missing: true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Up applies up to limit pending migrations, all of them when limit is 0, and
+// returns how many were applied
+func (m *Migrator) Up(limit int) (int, error) {
+	count := 0
+	err := m.locked(func() error {
+		if err := m.checkClean(); err != nil {
+			return err
+		}
+		pending, err := m.Pending()
+		if err != nil {
+			return err
+		}
+		if limit > 0 && len(pending) > limit {
+			pending = pending[:limit]
+		}
+		for _, mig := range pending {
+			if err := m.run(mig, true); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down reverts the last n applied migrations, newest first, and returns how many were reverted
+func (m *Migrator) Down(n int) (int, error) {
+	if n < 1 {
+		return 0, fmt.Errorf("migrations: down needs a positive number of steps, got %d", n)
+	}
+
+	count := 0
+	err := m.locked(func() error {
+		if err := m.checkClean(); err != nil {
+			return err
+		}
+		applied, err := m.applied()
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if len(versions) > n {
+			versions = versions[:n]
+		}
+
+		// 1. Check the whole range first, so an irreversible one stops nothing halfway
+		steps := make([]Migration, 0, len(versions))
+		for _, version := range versions {
+			mig, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("%w: %d_%s", ErrUnknown, version, applied[version].Name)
+			}
+			if mig.Down == nil {
+				return fmt.Errorf("%w: %s", ErrIrreversible, mig)
+			}
+			steps = append(steps, mig)
+		}
+
+		// 2. Revert them
+		for _, mig := range steps {
+			if err := m.run(mig, false); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Force records version as cleanly applied, or removes its record when applied is
+// false, without running anything. It is the way out of a dirty state once the
+// schema was fixed by hand.
+func (m *Migrator) Force(version int64, applied bool) error {
+	if m.DryRun != nil {
+		return errors.New("migrations: force does not support dry runs")
+	}
+	return m.locked(func() error {
+		if !applied {
+			return m.db.Delete(&schemaMigration{}, version).Error
+		}
+		mig, ok := m.find(version)
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrUnknown, version)
+		}
+		row := schemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}
+		return m.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error
+	})
+}
+
+// run executes one step and records the outcome
+func (m *Migrator) run(mig Migration, up bool) error {
+	direction := "up"
+	if !up {
+		direction = "down"
+	}
+	step := mig.Up
+	if !up {
+		step = mig.Down
+	}
+
+	if m.DryRun != nil {
+		fmt.Fprintf(m.DryRun, "-- %s (%s)\n", mig, direction)
+		return m.dryRunSession().Transaction(step)
+	}
+
+	// 1. Mark the version dirty while its step runs
+	row := schemaMigration{Version: mig.Version, Name: mig.Name, Dirty: true, AppliedAt: time.Now()}
+	if up {
+		if err := m.db.Create(&row).Error; err != nil {
+			return err
+		}
+	} else if err := m.db.Model(&row).Update("dirty", true).Error; err != nil {
+		return err
+	}
+
+	// 2. Run the step
+	started := time.Now()
+	if err := m.db.Transaction(step); err != nil {
+		if m.db.Dialector.Name() == "mysql" {
+			return fmt.Errorf("migrations: %s (%s) failed and %s is left dirty; MySQL cannot roll back schema changes, "+
+				"so fix the schema by hand, then run `migrate force %d` if the migration is now applied or "+
+				"`migrate force -unapplied %d` if it is not: %w", mig, direction, mig, mig.Version, mig.Version, err)
+		}
+		// The step was rolled back with its transaction, so is the bookkeeping
+		if up {
+			m.db.Delete(&row)
+		} else {
+			m.db.Model(&row).Update("dirty", false)
+		}
+		return fmt.Errorf("migrations: %s (%s): %w", mig, direction, err)
+	}
+
+	// 3. Record the outcome
+	var err error
+	if up {
+		err = m.db.Model(&row).Update("dirty", false).Error
+	} else {
+		err = m.db.Delete(&row).Error
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("migrations: %s (%s) done in %s", mig, direction, time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// applied loads schema_migrations by version; it is empty until the first migration ran
+func (m *Migrator) applied() (map[int64]schemaMigration, error) {
+	applied := map[int64]schemaMigration{}
+	if !m.db.Migrator().HasTable(&schemaMigration{}) {
+		return applied, nil
+	}
+
+	var rows []schemaMigration
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// locked runs fn under the migration lock. Dry runs change nothing, so they neither
+// take the lock nor create schema_migrations.
+func (m *Migrator) locked(fn func() error) error {
+	if m.DryRun != nil {
+		return fn()
+	}
+
+	return withLock(m.db, m.LockTimeout, func() error {
+		if err := m.db.AutoMigrate(&schemaMigration{}); err != nil {
+			return err
+		}
+		return fn()
+	})
+}
+
+// checkClean refuses to go on while a migration is left dirty
+func (m *Migrator) checkClean() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+	for _, row := range applied {
+		if row.Dirty {
+			return fmt.Errorf("%w: %d_%s; fix it, then run `migrate force %d` or `migrate force -unapplied %d`",
+				ErrDirty, row.Version, row.Name, row.Version, row.Version)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+// dryRunSession is a session whose writes are printed to DryRun instead of executed
+func (m *Migrator) dryRunSession() *gorm.DB {
+	tx := m.db.Session(&gorm.Session{NewDB: true, Logger: logger.Discard})
+	tx.Statement.ConnPool = &dryRunPool{ConnPool: m.db.ConnPool, dialector: m.db.Dialector, out: m.DryRun}
+	return tx
+}
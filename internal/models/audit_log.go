@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+// AuditLog records security relevant actions, e.g. requests made while impersonating a user
+type AuditLog struct {
+	gorm.Model
+	ActorID   uint   `gorm:"index" json:"actor_id"` // who really performed the action
+	UserID    uint   `gorm:"index" json:"user_id"`  // the account the action was performed on / as
+	Action    string `gorm:"size:100;index;not null" json:"action"`
+	Method    string `gorm:"size:10" json:"method,omitempty"`
+	Path      string `gorm:"size:255" json:"path,omitempty"`
+	Status    int    `json:"status,omitempty"`
+	IP        string `gorm:"size:45" json:"ip,omitempty"`
+	UserAgent string `gorm:"size:255" json:"user_agent,omitempty"`
+	Details   string `gorm:"type:text" json:"details,omitempty"`
+}
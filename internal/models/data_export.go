@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Data export states
+const (
+	DataExportPending    = "pending"
+	DataExportProcessing = "processing"
+	DataExportReady      = "ready"
+	DataExportFailed     = "failed"
+)
+
+// DataExport is a subject-access request: an archive of everything stored about a user
+type DataExport struct {
+	gorm.Model
+	UserID      uint       `gorm:"index;not null" json:"user_id"`
+	Format      string     `gorm:"size:10;not null" json:"format"` // "json" or "csv"
+	Status      string     `gorm:"size:20;index;not null" json:"status"`
+	StorageKey  string     `gorm:"size:255" json:"-"` // archive in the storage backend
+	Size        int64      `json:"size"`
+	Error       string     `gorm:"size:255" json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at"`
+}
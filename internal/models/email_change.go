@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailChange is a requested email address change. The new address only becomes
+// active once confirmed; until RevertExpiresAt the old address can undo the change.
+type EmailChange struct {
+	gorm.Model
+	UserID           uint       `gorm:"index;not null" json:"user_id"`
+	OldEmail         string     `gorm:"size:255;not null" json:"old_email"`
+	NewEmail         string     `gorm:"size:255;index;not null" json:"new_email"`
+	ConfirmTokenHash string     `gorm:"size:64;uniqueIndex" json:"-"`
+	RevertTokenHash  string     `gorm:"size:64;uniqueIndex" json:"-"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RevertExpiresAt  time.Time  `json:"revert_expires_at"`
+	ConfirmedAt      *time.Time `json:"confirmed_at"`
+	RevertedAt       *time.Time `json:"reverted_at"`
+	CancelledAt      *time.Time `json:"cancelled_at"`
+}
+
+// Pending reports whether the change still waits for the new address to confirm
+func (e *EmailChange) Pending() bool {
+	return e.ConfirmedAt == nil && e.RevertedAt == nil && e.CancelledAt == nil && time.Now().Before(e.ExpiresAt)
+}
@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// File is the metadata of an uploaded file; the content lives in the storage backend
+type File struct {
+	gorm.Model
+	OwnerID     uint   `gorm:"index;not null" json:"owner_id"`
+	Key         string `gorm:"type:varchar(255);uniqueIndex;not null" json:"-"` // storage key
+	Name        string `gorm:"size:255;not null" json:"name"`                   // original file name
+	ContentType string `gorm:"size:100;not null" json:"content_type"`           // sniffed from the content
+	Size        int64  `gorm:"not null" json:"size"`
+	Checksum    string `gorm:"size:64" json:"checksum"` // hex SHA-256
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invitation lets an admin pre-assign roles to someone who does not have an account yet
+type Invitation struct {
+	gorm.Model
+	Email        string     `gorm:"size:255;index;not null" json:"email"`
+	Organization string     `gorm:"size:100" json:"organization,omitempty"`
+	Roles        []Role     `gorm:"many2many:invitation_roles" json:"roles"`
+	InvitedByID  uint       `gorm:"index" json:"invited_by_id"`
+	TokenHash    string     `gorm:"size:64;uniqueIndex" json:"-"` // hash of the jti of the latest invite link
+	ExpiresAt    time.Time  `json:"expires_at"`
+	SentCount    int        `json:"sent_count"`
+	LastSentAt   *time.Time `json:"last_sent_at"`
+	AcceptedAt   *time.Time `json:"accepted_at"`
+	AcceptedByID *uint      `json:"accepted_by_id"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+}
+
+// Pending reports whether the invitation can still be accepted
+func (i *Invitation) Pending() bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && time.Now().Before(i.ExpiresAt)
+}
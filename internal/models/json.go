@@ -0,0 +1,60 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONMap is a free-form object stored as JSON text
+type JSONMap map[string]interface{}
+
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	raw, err := json.Marshal(m)
+	return string(raw), err
+}
+
+func (m *JSONMap) Scan(value interface{}) error {
+	raw, err := jsonBytes(value)
+	if err != nil || len(raw) == 0 {
+		*m = JSONMap{}
+		return err
+	}
+	return json.Unmarshal(raw, m)
+}
+
+// StringList is a list of strings stored as JSON text
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal(l)
+	return string(raw), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	raw, err := jsonBytes(value)
+	if err != nil || len(raw) == 0 {
+		*l = nil
+		return err
+	}
+	return json.Unmarshal(raw, l)
+}
+
+func jsonBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("cannot scan %T into a JSON column", value)
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginEvent is one login attempt, successful or not
+type LoginEvent struct {
+	gorm.Model
+	UserID        uint   `gorm:"index" json:"user_id"` // 0 when the email is unknown
+	Email         string `gorm:"size:255;index" json:"email"`
+	Success       bool   `json:"success"`
+	Method        string `gorm:"size:20" json:"method"` // e.g. "local", "ldap"
+	FailureReason string `gorm:"size:100" json:"failure_reason,omitempty"`
+	IP            string `gorm:"size:45" json:"ip"`
+	IPPrefix      string `gorm:"size:45;index" json:"-"` // network the IP belongs to (/24 or /48)
+	UserAgent     string `gorm:"size:255" json:"user_agent"`
+	DeviceID      string `gorm:"size:64" json:"device_id"`
+	Country       string `gorm:"size:2" json:"country,omitempty"`
+	City          string `gorm:"size:100" json:"city,omitempty"`
+	NewDevice     bool   `json:"new_device"`
+}
+
+// KnownDevice is a device fingerprint a user has logged in from before
+type KnownDevice struct {
+	gorm.Model
+	UserID      uint      `gorm:"uniqueIndex:idx_device_user_fingerprint;not null" json:"user_id"`
+	Fingerprint string    `gorm:"size:64;uniqueIndex:idx_device_user_fingerprint;not null" json:"fingerprint"`
+	UserAgent   string    `gorm:"size:255" json:"user_agent"`
+	LastIP      string    `gorm:"size:45" json:"last_ip"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// OAuthClient is a relying party registered to sign users in through our OpenID Connect provider
+type OAuthClient struct {
+	gorm.Model
+	ClientID     string `gorm:"type:varchar(64);uniqueIndex;not null" json:"client_id"`
+	ClientSecret string `gorm:"size:255" json:"-"` // bcrypt hash, empty for public (PKCE-only) clients
+	Name         string `gorm:"size:100;not null" json:"name"`
+	RedirectURIs string `gorm:"type:text" json:"redirect_uris"` // space separated
+	Scopes       string `gorm:"size:255" json:"scopes"`         // space separated scopes the client may request
+	Public       bool   `json:"public"`
+	OwnerID      uint   `gorm:"index" json:"owner_id"` // the user who registered it
+}
+
+// RedirectURIList returns the registered redirect URIs as a slice
+func (c *OAuthClient) RedirectURIList() []string {
+	return strings.Fields(c.RedirectURIs)
+}
+
+// OAuthConsent records the scopes a user has granted to a client. Client is not a GORM
+// association: OAuthClient has a ClientID field of its own, so GORM would take the
+// relation for "client has one consent" and turn o_auth_clients.client_id into a
+// foreign key to consents. The repository loads it instead.
+type OAuthConsent struct {
+	gorm.Model
+	UserID   uint        `gorm:"uniqueIndex:idx_consent_user_client;not null" json:"user_id"`
+	ClientID uint        `gorm:"uniqueIndex:idx_consent_user_client;not null" json:"client_id"`
+	Client   OAuthClient `gorm:"-" json:"client"`
+	Scopes   string      `gorm:"size:255" json:"scopes"` // space separated
+}
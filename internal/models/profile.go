@@ -0,0 +1,38 @@
+package models
+
+import "gorm.io/gorm"
+
+// Custom profile field types
+const (
+	ProfileFieldString  = "string"
+	ProfileFieldNumber  = "number"
+	ProfileFieldBoolean = "boolean"
+	ProfileFieldDate    = "date" // "2006-01-02"
+	ProfileFieldEnum    = "enum" // one of Options
+)
+
+// UserProfile holds the optional, user editable details of an account
+type UserProfile struct {
+	gorm.Model
+	UserID      uint   `gorm:"uniqueIndex;not null" json:"user_id"`
+	DisplayName string `gorm:"size:100" json:"display_name"`
+	Phone       string `gorm:"size:32" json:"phone"`    // E.164, e.g. +14155552671
+	Timezone    string `gorm:"size:64" json:"timezone"` // IANA name, e.g. Europe/Madrid
+	Locale      string `gorm:"size:10" json:"locale"`   // one of the supported languages
+	AvatarKey   string `gorm:"size:255" json:"-"`       // storage key of the resized avatar
+	AvatarURL   string `gorm:"-" json:"avatar_url"`
+
+	// CustomFields holds the values of the admin defined ProfileFields, by key
+	CustomFields JSONMap `gorm:"type:text" json:"custom_fields"`
+}
+
+// ProfileField is an admin defined attribute users can fill in on their profile
+type ProfileField struct {
+	gorm.Model
+	Key       string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"key"`
+	Label     string     `gorm:"size:100;not null" json:"label"`
+	Type      string     `gorm:"size:20;not null" json:"type"` // see the ProfileField* constants
+	Required  bool       `json:"required"`
+	Options   StringList `gorm:"type:text" json:"options,omitempty"` // allowed values of an enum
+	MaxLength int        `json:"max_length,omitempty"`               // for strings, 0 means no limit
+}
@@ -4,6 +4,9 @@ import "gorm.io/gorm"
 
 type Role struct {
 	gorm.Model
-	Name        string       `gorm:"type:varchar(255);uniqueIndex;not null" json:"name"` // Use varchar instead of text
+	Name        string       `gorm:"type:varchar(255);uniqueIndex:idx_roles_name_live;not null" json:"name"` // Use varchar instead of text
 	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+
+	// DeletedID is 0 for live rows and the row's own ID once soft-deleted (see models.User)
+	DeletedID uint `gorm:"uniqueIndex:idx_roles_name_live;not null;default:0" json:"-"`
 }
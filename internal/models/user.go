@@ -6,12 +6,48 @@ import (
 	"gorm.io/gorm"
 )
 
+// Account lifecycle states
+const (
+	UserStatusPending         = "pending"          // created but not allowed to log in yet
+	UserStatusActive          = "active"           // normal account
+	UserStatusSuspended       = "suspended"        // blocked by an admin, e.g. for abuse
+	UserStatusDeactivated     = "deactivated"      // closed, can be reactivated by an admin
+	UserStatusPendingDeletion = "pending_deletion" // deleted after a grace period unless the user logs in
+)
+
+// UserErasedReason is the status reason of users whose personal data was erased; only
+// their anonymized row is left, which cannot be restored
+const UserErasedReason = "erased"
+
 type User struct {
 	gorm.Model
-	Name        string    `gorm:"size:100" json:"name"`              // Limit Name to 100 characters
-	Email       string    `gorm:"size:255;uniqueIndex" json:"email"` // Limit Email to 255 characters
+	Name        string    `gorm:"size:100" json:"name"`                                   // Limit Name to 100 characters
+	Email       string    `gorm:"size:255;uniqueIndex:idx_users_email_live" json:"email"` // Limit Email to 255 characters
 	Password    string    `gorm:"size:225" json:"-"`
 	Roles       []Role    `gorm:"many2many:user_roles" json:"roles"` // Hashed password, omit from JSON
 	ResetToken  string    `gorm:"index"`                             // Index this field for faster lookup
 	TokenExpiry time.Time `gorm:"-"`
+
+	// AuthSource is "local" for password users or the directory that provisioned the account, e.g. "ldap"
+	AuthSource string `gorm:"size:20;default:local" json:"auth_source"`
+
+	// Status is the account lifecycle state, see the UserStatus* constants
+	Status              string     `gorm:"size:20;default:active;index" json:"status"`
+	StatusReason        string     `gorm:"size:255" json:"status_reason,omitempty"`
+	StatusChangedAt     *time.Time `json:"status_changed_at,omitempty"`
+	DeletionScheduledAt *time.Time `gorm:"index" json:"deletion_scheduled_at,omitempty"`
+	// DeletionRequestedBy is who scheduled the deletion; only the user themselves can cancel it by logging in
+	DeletionRequestedBy uint `json:"deletion_requested_by,omitempty"`
+
+	// Organization is set when the user joined through an invitation for one
+	Organization string `gorm:"size:100" json:"organization,omitempty"`
+
+	Profile *UserProfile `gorm:"foreignKey:UserID" json:"profile,omitempty"`
+
+	// LastSeenAt is refreshed (throttled) by authenticated requests and heartbeats
+	LastSeenAt *time.Time `json:"last_seen_at"`
+
+	// DeletedID is 0 for live rows and the row's own ID once soft-deleted, so the
+	// (email, deleted_id) unique index lets a deleted email be registered again
+	DeletedID uint `gorm:"uniqueIndex:idx_users_email_live;not null;default:0" json:"-"`
 }
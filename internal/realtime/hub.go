@@ -0,0 +1,239 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Topics every authenticated client may subscribe to.
+// Private topics have the form "user:<id>" and are only readable by that user.
+const (
+	TopicPresence  = "presence"
+	TopicBroadcast = "broadcast"
+)
+
+// redisChannel is shared by all API instances, so an event published on one
+// instance reaches clients connected to any other
+const redisChannel = "realtime:events"
+
+// Event is what clients receive, over WebSocket or SSE
+type Event struct {
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Time  time.Time       `json:"time"`
+}
+
+// UserTopic is the private topic of a user
+func UserTopic(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// CanSubscribe reports whether a user may listen on a topic
+func CanSubscribe(userID uint, topic string) bool {
+	switch {
+	case topic == TopicPresence, topic == TopicBroadcast:
+		return true
+	case strings.HasPrefix(topic, "user:"):
+		return topic == UserTopic(userID)
+	default:
+		return false
+	}
+}
+
+// Hub fans events out to the clients connected to this instance
+type Hub struct {
+	rdb        *redis.Client
+	bufferSize int
+
+	mu      sync.RWMutex
+	clients map[*Client]struct{}
+}
+
+// NewHub creates a hub; bufferSize is how many events a client may lag behind
+// before it is treated as a slow consumer and disconnected.
+func NewHub(rdb *redis.Client, bufferSize int) *Hub {
+	if bufferSize < 1 {
+		bufferSize = 64
+	}
+	return &Hub{
+		rdb:        rdb,
+		bufferSize: bufferSize,
+		clients:    map[*Client]struct{}{},
+	}
+}
+
+// Publish sends an event to every subscriber of the topic on every instance
+func (h *Hub) Publish(ctx context.Context, topic, eventType string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(Event{Topic: topic, Type: eventType, Data: raw, Time: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	return h.rdb.Publish(ctx, redisChannel, payload).Err()
+}
+
+// Run relays events from Redis to local clients until ctx is cancelled.
+// The subscription is re-established if Redis goes away.
+func (h *Hub) Run(ctx context.Context) {
+	for {
+		if err := h.listen(ctx); err != nil {
+			log.Printf("realtime: redis subscription lost: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			h.closeAll()
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (h *Hub) listen(ctx context.Context) error {
+	sub := h.rdb.Subscribe(ctx, redisChannel)
+	defer sub.Close()
+
+	// Wait for the subscription to be confirmed so errors surface here
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("subscription channel closed")
+			}
+			h.dispatch([]byte(msg.Payload))
+		}
+	}
+}
+
+func (h *Hub) dispatch(payload []byte) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		log.Printf("realtime: dropping malformed event: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	var slow []*Client
+	for client := range h.clients {
+		if !client.subscribed(event.Topic) {
+			continue
+		}
+		// Never block the fan-out on one client
+		select {
+		case client.send <- payload:
+		default:
+			slow = append(slow, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range slow {
+		log.Printf("realtime: disconnecting slow consumer (user %d)", client.UserID)
+		h.Unregister(client)
+	}
+}
+
+// Register adds a client for the user; it starts without subscriptions
+func (h *Hub) Register(userID uint) *Client {
+	client := &Client{
+		UserID: userID,
+		send:   make(chan []byte, h.bufferSize),
+		done:   make(chan struct{}),
+		topics: map[string]bool{},
+	}
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+	return client
+}
+
+// Unregister removes the client and signals its connection to close
+func (h *Hub) Unregister(client *Client) {
+	h.mu.Lock()
+	delete(h.clients, client)
+	h.mu.Unlock()
+	client.close()
+}
+
+func (h *Hub) closeAll() {
+	h.mu.Lock()
+	clients := h.clients
+	h.clients = map[*Client]struct{}{}
+	h.mu.Unlock()
+	for client := range clients {
+		client.close()
+	}
+}
+
+// Client is one WebSocket or SSE connection
+type Client struct {
+	UserID uint
+
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu     sync.RWMutex
+	topics map[string]bool
+}
+
+// Subscribe adds topics, refusing any the user may not read
+func (c *Client) Subscribe(topics ...string) error {
+	for _, topic := range topics {
+		if !CanSubscribe(c.UserID, topic) {
+			return fmt.Errorf("not allowed to subscribe to %q", topic)
+		}
+	}
+	c.mu.Lock()
+	for _, topic := range topics {
+		c.topics[topic] = true
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Client) Unsubscribe(topics ...string) {
+	c.mu.Lock()
+	for _, topic := range topics {
+		delete(c.topics, topic)
+	}
+	c.mu.Unlock()
+}
+
+// Messages yields encoded events for this client
+func (c *Client) Messages() <-chan []byte {
+	return c.send
+}
+
+// Done is closed when the hub dropped the client (slow consumer or shutdown)
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) subscribed(topic string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.topics[topic]
+}
+
+func (c *Client) close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"golang-api-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	CreateAuditLog(ctx context.Context, entry *models.AuditLog) error
+	GetAuditLogsByUserID(ctx context.Context, userID uint) ([]models.AuditLog, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) CreateAuditLog(ctx context.Context, entry *models.AuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// GetAuditLogsByUserID returns entries where the user was either the actor or the subject
+func (r *auditRepository) GetAuditLogsByUserID(ctx context.Context, userID uint) ([]models.AuditLog, error) {
+	var logs []models.AuditLog
+	err := replica(r.db.WithContext(ctx)).Where("user_id = ? OR actor_id = ?", userID, userID).Order("id DESC").Find(&logs).Error
+	return logs, err
+}
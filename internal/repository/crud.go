@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var ErrNotSoftDeletable = errors.New("model does not support soft deletes")
+
+// Crud is the data access every resource needs: create, read, update, delete, list
+// and, for models with a gorm.DeletedAt field, trash handling. Resource repositories
+// embed it and only add their own queries.
+type Crud[T any] interface {
+	// Create inserts entity as a new row. A primary key or timestamps set by the
+	// caller are ignored, and associations are not written.
+	Create(ctx context.Context, entity *T) error
+	// GetByID returns gorm.ErrRecordNotFound for missing and soft-deleted rows
+	GetByID(ctx context.Context, id uint) (*T, error)
+	// Update writes every column of entity to row id, except the primary key,
+	// created_at and the soft-delete columns. Associations are not written.
+	Update(ctx context.Context, id uint, entity *T) error
+	// Delete soft-deletes the row, or removes it for models without soft deletes
+	Delete(ctx context.Context, id uint) error
+	// List fetches a page of rows; trashed is one of the Trashed* constants
+	List(ctx context.Context, p utils.PaginationParams, trashed string) ([]T, utils.PageInfo, error)
+
+	GetDeletedByID(ctx context.Context, id uint) (*T, error)
+	Restore(ctx context.Context, id uint) error
+	// ForceDelete permanently removes the row with its has-one, has-many and many2many data
+	ForceDelete(ctx context.Context, id uint) error
+}
+
+type crudRepository[T any] struct {
+	db     *gorm.DB
+	spec   ListSpec
+	schema *schema.Schema
+
+	// Optional columns, see models.User for DeletedID
+	softDelete bool
+	deletedID  bool
+}
+
+// NewCrud returns the generic repository for model T, listed according to spec.
+// It panics if T is not a GORM model, like regexp.MustCompile does for bad patterns,
+// since that is a programming error found on startup.
+func NewCrud[T any](db *gorm.DB, spec ListSpec) Crud[T] {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		panic(fmt.Sprintf("repository: %T is not a valid model: %v", *new(T), err))
+	}
+	if primary := stmt.Schema.PrioritizedPrimaryField; primary == nil || primary.IndirectFieldType.Kind() != reflect.Uint {
+		panic(fmt.Sprintf("repository: %T needs a uint primary key", *new(T)))
+	}
+
+	r := &crudRepository[T]{db: db, spec: spec, schema: stmt.Schema}
+	if field := stmt.Schema.LookUpField("DeletedAt"); field != nil {
+		r.softDelete = field.FieldType == reflect.TypeOf(gorm.DeletedAt{})
+	}
+	r.deletedID = stmt.Schema.LookUpField("DeletedID") != nil
+	return r
+}
+
+func (r *crudRepository[T]) Create(ctx context.Context, entity *T) error {
+	r.clearManaged(entity)
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(entity).Error
+}
+
+func (r *crudRepository[T]) GetByID(ctx context.Context, id uint) (*T, error) {
+	entity := new(T)
+	query := r.db.WithContext(ctx)
+	for _, association := range r.spec.Preload {
+		query = query.Preload(association)
+	}
+	if err := query.First(entity, id).Error; err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+func (r *crudRepository[T]) Update(ctx context.Context, id uint, entity *T) error {
+	value := reflect.ValueOf(entity).Elem()
+	primary := r.schema.PrioritizedPrimaryField
+	primary.ReflectValueOf(ctx, value).SetUint(uint64(id))
+
+	omit := []string{primary.DBName, "created_at", "deleted_at", "deleted_id", clause.Associations}
+	return r.db.WithContext(ctx).Model(entity).Select("*").Omit(omit...).Updates(entity).Error
+}
+
+// Delete soft-deletes the row; models with a DeletedID column also get it set to the
+// row's own ID, which frees their unique values for new rows
+func (r *crudRepository[T]) Delete(ctx context.Context, id uint) error {
+	if !r.deletedID {
+		return r.db.WithContext(ctx).Delete(new(T), id).Error
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(new(T)).Where("id = ?", id).Update("deleted_id", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(new(T), id).Error
+	})
+}
+
+func (r *crudRepository[T]) List(ctx context.Context, p utils.PaginationParams, trashed string) ([]T, utils.PageInfo, error) {
+	if trashed != TrashedWithout && !r.softDelete {
+		return nil, utils.PageInfo{}, &QueryError{Param: "trashed", Reason: "is not supported here"}
+	}
+	if err := r.spec.Validate(p); err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	if trashed == TrashedWithout {
+		// No scope, so the live list of a large table can still be estimated
+		return paginate[T](r.db.WithContext(ctx), r.spec, p, r.schema.Table)
+	}
+	return paginate[T](r.db.WithContext(ctx), r.spec, p, r.schema.Table, scopeTrashed(trashed))
+}
+
+func (r *crudRepository[T]) GetDeletedByID(ctx context.Context, id uint) (*T, error) {
+	if !r.softDelete {
+		return nil, gorm.ErrRecordNotFound
+	}
+	entity := new(T)
+	if err := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(entity, id).Error; err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+func (r *crudRepository[T]) Restore(ctx context.Context, id uint) error {
+	if !r.softDelete {
+		return ErrNotSoftDeletable
+	}
+	values := map[string]interface{}{"deleted_at": nil}
+	if r.deletedID {
+		values["deleted_id"] = 0
+	}
+	return r.db.WithContext(ctx).Unscoped().Model(new(T)).Where("id = ?", id).Updates(values).Error
+}
+
+func (r *crudRepository[T]) ForceDelete(ctx context.Context, id uint) error {
+	entity := new(T)
+	value := reflect.ValueOf(entity).Elem()
+	r.schema.PrioritizedPrimaryField.ReflectValueOf(ctx, value).SetUint(uint64(id))
+
+	var owned []string
+	for name, rel := range r.schema.Relationships.Relations {
+		switch rel.Type {
+		case schema.HasOne, schema.HasMany, schema.Many2Many:
+			owned = append(owned, name)
+		}
+	}
+	if len(owned) == 0 {
+		return r.db.WithContext(ctx).Unscoped().Delete(entity).Error
+	}
+	return r.db.WithContext(ctx).Unscoped().Select(owned).Delete(entity).Error
+}
+
+// clearManaged resets the columns the database and GORM maintain themselves, so a
+// bound request body cannot choose them
+func (r *crudRepository[T]) clearManaged(entity *T) {
+	value := reflect.ValueOf(entity).Elem()
+	for _, field := range r.schema.Fields {
+		if field.PrimaryKey || field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 ||
+			field.DBName == "deleted_at" || field.DBName == "deleted_id" {
+			fieldValue := field.ReflectValueOf(context.Background(), value)
+			fieldValue.Set(reflect.Zero(fieldValue.Type()))
+		}
+	}
+}
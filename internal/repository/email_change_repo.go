@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"golang-api-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type EmailChangeRepository interface {
+	CreateEmailChange(ctx context.Context, change *models.EmailChange) error
+	GetEmailChangeByConfirmHash(ctx context.Context, hash string) (*models.EmailChange, error)
+	GetEmailChangeByRevertHash(ctx context.Context, hash string) (*models.EmailChange, error)
+	GetPendingEmailChange(ctx context.Context, userID uint) (*models.EmailChange, error)
+	CancelPendingEmailChanges(ctx context.Context, userID uint) error
+
+	// ApplyEmailChange saves the change and sets the user's email in one transaction
+	ApplyEmailChange(ctx context.Context, change *models.EmailChange, email string) error
+}
+
+type emailChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailChangeRepository(db *gorm.DB) EmailChangeRepository {
+	return &emailChangeRepository{db: db}
+}
+
+func (r *emailChangeRepository) CreateEmailChange(ctx context.Context, change *models.EmailChange) error {
+	return r.db.WithContext(ctx).Create(change).Error
+}
+
+func (r *emailChangeRepository) GetEmailChangeByConfirmHash(ctx context.Context, hash string) (*models.EmailChange, error) {
+	var change models.EmailChange
+	if err := r.db.WithContext(ctx).Where("confirm_token_hash = ?", hash).First(&change).Error; err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+func (r *emailChangeRepository) GetEmailChangeByRevertHash(ctx context.Context, hash string) (*models.EmailChange, error) {
+	var change models.EmailChange
+	if err := r.db.WithContext(ctx).Where("revert_token_hash = ?", hash).First(&change).Error; err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// GetPendingEmailChange returns the user's latest unconfirmed, unexpired change
+func (r *emailChangeRepository) GetPendingEmailChange(ctx context.Context, userID uint) (*models.EmailChange, error) {
+	var change models.EmailChange
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND confirmed_at IS NULL AND reverted_at IS NULL AND cancelled_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("id DESC").
+		First(&change).Error
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+func (r *emailChangeRepository) CancelPendingEmailChanges(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&models.EmailChange{}).
+		Where("user_id = ? AND confirmed_at IS NULL AND reverted_at IS NULL AND cancelled_at IS NULL", userID).
+		Update("cancelled_at", time.Now()).Error
+}
+
+func (r *emailChangeRepository) ApplyEmailChange(ctx context.Context, change *models.EmailChange, email string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", change.UserID).Update("email", email).Error; err != nil {
+			return err
+		}
+		return tx.Save(change).Error
+	})
+}
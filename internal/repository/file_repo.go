@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+type FileRepository interface {
+	CreateFile(ctx context.Context, file *models.File) error
+	GetFileByID(ctx context.Context, id uint) (*models.File, error)
+	GetFilesByOwnerID(ctx context.Context, ownerID uint, p utils.PaginationParams) ([]models.File, utils.PageInfo, error)
+	GetAllFilesByOwnerID(ctx context.Context, ownerID uint) ([]models.File, error)
+	DeleteFile(ctx context.Context, id uint) error
+}
+
+type fileRepository struct {
+	db *gorm.DB
+}
+
+func NewFileRepository(db *gorm.DB) FileRepository {
+	return &fileRepository{db: db}
+}
+
+func (r *fileRepository) CreateFile(ctx context.Context, file *models.File) error {
+	return r.db.WithContext(ctx).Create(file).Error
+}
+
+func (r *fileRepository) GetFileByID(ctx context.Context, id uint) (*models.File, error) {
+	var file models.File
+	if err := r.db.WithContext(ctx).First(&file, id).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// fileListSpec is what `GET /files` can filter, sort and search on
+var fileListSpec = ListSpec{
+	Filterable: map[string]string{
+		"name":         "files.name",
+		"content_type": "files.content_type",
+		"size":         "files.size",
+		"created_at":   "files.created_at",
+	},
+	Sortable: map[string]string{
+		"id":         "files.id",
+		"name":       "files.name",
+		"size":       "files.size",
+		"created_at": "files.created_at",
+	},
+	Searchable:  []string{"files.name"},
+	DefaultSort: []utils.SortField{{Field: "id", Desc: true}},
+}
+
+// GetFilesByOwnerID returns the owner's files, newest first
+func (r *fileRepository) GetFilesByOwnerID(ctx context.Context, ownerID uint, p utils.PaginationParams) ([]models.File, utils.PageInfo, error) {
+	if err := fileListSpec.Validate(p); err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	byOwner := func(db *gorm.DB) *gorm.DB { return db.Where("files.owner_id = ?", ownerID) }
+	return paginate[models.File](r.db.WithContext(ctx), fileListSpec, p, "files", byOwner)
+}
+
+func (r *fileRepository) GetAllFilesByOwnerID(ctx context.Context, ownerID uint) ([]models.File, error) {
+	var files []models.File
+	err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Order("id").Find(&files).Error
+	return files, err
+}
+
+// DeleteFile deletes permanently; the stored content is gone at that point anyway
+func (r *fileRepository) DeleteFile(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&models.File{}, id).Error
+}
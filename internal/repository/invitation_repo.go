@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+type InvitationRepository interface {
+	CreateInvitation(ctx context.Context, invitation *models.Invitation) error
+	GetInvitationByID(ctx context.Context, id uint) (*models.Invitation, error)
+	UpdateInvitation(ctx context.Context, invitation *models.Invitation) error
+	GetPendingInvitations(ctx context.Context, p utils.PaginationParams) ([]models.Invitation, utils.PageInfo, error)
+	GetPendingInvitationByEmail(ctx context.Context, email string) (*models.Invitation, error)
+
+	// AcceptInvitation creates the user with the invitation's roles and marks it accepted, atomically
+	AcceptInvitation(ctx context.Context, invitation *models.Invitation, user *models.User) error
+}
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+func (r *invitationRepository) CreateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	return r.db.WithContext(ctx).Create(invitation).Error
+}
+
+func (r *invitationRepository) GetInvitationByID(ctx context.Context, id uint) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := r.db.WithContext(ctx).Preload("Roles").First(&invitation, id).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) UpdateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	return r.db.WithContext(ctx).Omit("Roles").Save(invitation).Error
+}
+
+// invitationListSpec is what `GET /invitations` can filter, sort and search on
+var invitationListSpec = ListSpec{
+	Filterable: map[string]string{
+		"email":         "invitations.email",
+		"organization":  "invitations.organization",
+		"invited_by_id": "invitations.invited_by_id",
+		"created_at":    "invitations.created_at",
+		"expires_at":    "invitations.expires_at",
+	},
+	Sortable: map[string]string{
+		"id":         "invitations.id",
+		"email":      "invitations.email",
+		"created_at": "invitations.created_at",
+		"expires_at": "invitations.expires_at",
+	},
+	Searchable:  []string{"invitations.email", "invitations.organization"},
+	DefaultSort: []utils.SortField{{Field: "id", Desc: true}},
+	Preload:     []string{"Roles"},
+}
+
+// GetPendingInvitations lists invitations that are neither accepted, revoked nor expired, newest first
+func (r *invitationRepository) GetPendingInvitations(ctx context.Context, p utils.PaginationParams) ([]models.Invitation, utils.PageInfo, error) {
+	if err := invitationListSpec.Validate(p); err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	return paginate[models.Invitation](r.db.WithContext(ctx), invitationListSpec, p, "invitations", r.pending)
+}
+
+func (r *invitationRepository) GetPendingInvitationByEmail(ctx context.Context, email string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := r.pending(r.db.WithContext(ctx).Where("email = ?", email)).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) AcceptInvitation(ctx context.Context, invitation *models.Invitation, user *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		user.Roles = invitation.Roles
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		invitation.AcceptedByID = &user.ID
+		return tx.Omit("Roles").Save(invitation).Error
+	})
+}
+
+func (r *invitationRepository) pending(query *gorm.DB) *gorm.DB {
+	return query.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", time.Now())
+}
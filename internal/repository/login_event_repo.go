@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+type LoginEventRepository interface {
+	CreateLoginEvent(ctx context.Context, event *models.LoginEvent) error
+	GetLoginEventsByUserID(ctx context.Context, userID uint, p utils.PaginationParams) ([]models.LoginEvent, utils.PageInfo, error)
+	CountSuccessfulLogins(ctx context.Context, userID uint) (int64, error)
+	HasLoggedInFromNetwork(ctx context.Context, userID uint, ipPrefix string) (bool, error)
+
+	GetKnownDevice(ctx context.Context, userID uint, fingerprint string) (*models.KnownDevice, error)
+	SaveKnownDevice(ctx context.Context, device *models.KnownDevice) error
+}
+
+type loginEventRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginEventRepository(db *gorm.DB) LoginEventRepository {
+	return &loginEventRepository{db: db}
+}
+
+func (r *loginEventRepository) CreateLoginEvent(ctx context.Context, event *models.LoginEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// loginEventListSpec is what `GET /me/logins` can filter and sort on
+var loginEventListSpec = ListSpec{
+	Filterable: map[string]string{
+		"success":    "login_events.success",
+		"country":    "login_events.country",
+		"created_at": "login_events.created_at",
+	},
+	Sortable: map[string]string{
+		"id":         "login_events.id",
+		"created_at": "login_events.created_at",
+	},
+	DefaultSort: []utils.SortField{{Field: "id", Desc: true}},
+}
+
+// GetLoginEventsByUserID returns the user's login history, newest first
+func (r *loginEventRepository) GetLoginEventsByUserID(ctx context.Context, userID uint, p utils.PaginationParams) ([]models.LoginEvent, utils.PageInfo, error) {
+	if err := loginEventListSpec.Validate(p); err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	byUser := func(db *gorm.DB) *gorm.DB { return db.Where("login_events.user_id = ?", userID) }
+	return paginate[models.LoginEvent](r.db.WithContext(ctx), loginEventListSpec, p, "login_events", byUser)
+}
+
+func (r *loginEventRepository) CountSuccessfulLogins(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.LoginEvent{}).Where("user_id = ? AND success = ?", userID, true).Count(&count).Error
+	return count, err
+}
+
+func (r *loginEventRepository) HasLoggedInFromNetwork(ctx context.Context, userID uint, ipPrefix string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.LoginEvent{}).
+		Where("user_id = ? AND success = ? AND ip_prefix = ?", userID, true, ipPrefix).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *loginEventRepository) GetKnownDevice(ctx context.Context, userID uint, fingerprint string) (*models.KnownDevice, error) {
+	var device models.KnownDevice
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND fingerprint = ?", userID, fingerprint).First(&device).Error; err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *loginEventRepository) SaveKnownDevice(ctx context.Context, device *models.KnownDevice) error {
+	return r.db.WithContext(ctx).Save(device).Error
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+
+	"golang-api-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client *models.OAuthClient) error
+	GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	GetClientByID(ctx context.Context, id uint) (*models.OAuthClient, error)
+	GetAllClients(ctx context.Context) ([]models.OAuthClient, error)
+	GetClientsByOwnerID(ctx context.Context, ownerID uint) ([]models.OAuthClient, error)
+	DeleteClient(ctx context.Context, id uint) error
+
+	GetConsent(ctx context.Context, userID, clientID uint) (*models.OAuthConsent, error)
+	SaveConsent(ctx context.Context, consent *models.OAuthConsent) error
+	GetConsentsByUserID(ctx context.Context, userID uint) ([]models.OAuthConsent, error)
+	DeleteConsent(ctx context.Context, userID, clientID uint) error
+}
+
+type oauthRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthRepository(db *gorm.DB) OAuthRepository {
+	return &oauthRepository{db: db}
+}
+
+func (r *oauthRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+func (r *oauthRepository) GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	if err := r.db.WithContext(ctx).Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *oauthRepository) GetClientByID(ctx context.Context, id uint) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	if err := r.db.WithContext(ctx).First(&client, id).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *oauthRepository) GetAllClients(ctx context.Context) ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	err := r.db.WithContext(ctx).Find(&clients).Error
+	return clients, err
+}
+
+func (r *oauthRepository) GetClientsByOwnerID(ctx context.Context, ownerID uint) ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Find(&clients).Error
+	return clients, err
+}
+
+func (r *oauthRepository) DeleteClient(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("client_id = ?", id).Delete(&models.OAuthConsent{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.OAuthClient{}, id).Error
+	})
+}
+
+func (r *oauthRepository) GetConsent(ctx context.Context, userID, clientID uint) (*models.OAuthConsent, error) {
+	var consent models.OAuthConsent
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error; err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+func (r *oauthRepository) SaveConsent(ctx context.Context, consent *models.OAuthConsent) error {
+	return r.db.WithContext(ctx).Save(consent).Error
+}
+
+func (r *oauthRepository) GetConsentsByUserID(ctx context.Context, userID uint) ([]models.OAuthConsent, error) {
+	var consents []models.OAuthConsent
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&consents).Error; err != nil {
+		return nil, err
+	}
+	if len(consents) == 0 {
+		return consents, nil
+	}
+
+	// Attach the clients, see models.OAuthConsent
+	ids := make([]uint, len(consents))
+	for i, consent := range consents {
+		ids[i] = consent.ClientID
+	}
+	var clients []models.OAuthClient
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.OAuthClient, len(clients))
+	for _, client := range clients {
+		byID[client.ID] = client
+	}
+	for i := range consents {
+		consents[i].Client = byID[consents[i].ClientID]
+	}
+	return consents, nil
+}
+
+func (r *oauthRepository) DeleteConsent(ctx context.Context, userID, clientID uint) error {
+	return r.db.WithContext(ctx).Unscoped().Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&models.OAuthConsent{}).Error
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// sortKey is one resolved ORDER BY entry of a list query
+type sortKey struct {
+	name   string // API name, e.g. "created_at"
+	column string // e.g. "users.created_at"
+	desc   bool
+}
+
+// paginate runs a list query on table with the filters, search, sort and paging of p.
+// Page-number requests use OFFSET/LIMIT; requests with a `cursor` use keyset
+// pagination, which stays fast on large tables. scopes are extra conditions such as
+// the owner or the trashed filter; a list with scopes is never estimated. The caller
+// validates p against spec first. Lists are read from a replica when there is one.
+func paginate[T any](db *gorm.DB, spec ListSpec, p utils.PaginationParams, table string, scopes ...func(*gorm.DB) *gorm.DB) ([]T, utils.PageInfo, error) {
+	var info utils.PageInfo
+	db = replica(db)
+	wholeTable := len(scopes) == 0
+	scopes = append(scopes, scopeFilters(spec, p))
+
+	// 1. Total, only when asked for (without the preloads, which only apply to rows)
+	if p.Total != utils.TotalNone {
+		total, estimated, err := countRows(db, new(T), table, p, wholeTable, scopes)
+		if err != nil {
+			return nil, info, err
+		}
+		info.Total, info.TotalEstimated = &total, estimated
+	}
+
+	// 2. Page-number mode
+	var rows []T
+	find := db.Scopes(scopes...)
+	for _, association := range spec.Preload {
+		find = find.Preload(association)
+	}
+	if !p.UseCursor {
+		err := find.Scopes(scopeSort(spec, p, table)).Offset(p.Offset()).Limit(p.Limit).Find(&rows).Error
+		return rows, info, err
+	}
+
+	// 3. Keyset mode: rows after (or before) the cursor, one extra to know if there is more
+	keys := resolveSort(spec, p, table)
+	signature := sortSignature(keys)
+
+	var cursor *utils.Cursor
+	if p.Cursor != "" {
+		var err error
+		cursor, err = utils.DecodeCursor(p.Cursor)
+		if err != nil {
+			return nil, info, &QueryError{Param: "cursor", Reason: "is invalid"}
+		}
+		if cursor.Sort != signature || len(cursor.Values) != len(keys) {
+			return nil, info, &QueryError{Param: "cursor", Reason: "does not match the sort order"}
+		}
+	}
+	backwards := cursor != nil && cursor.Prev
+
+	query := find
+	if cursor != nil {
+		condition, args := keysetCondition(keys, cursor.Values, backwards)
+		query = query.Where(condition, args...)
+	}
+	for _, key := range keys {
+		// Walking backwards reverses the order; the rows are flipped back below
+		desc := key.desc != backwards
+		if desc {
+			query = query.Order(key.column + " DESC")
+		} else {
+			query = query.Order(key.column)
+		}
+	}
+	if err := query.Limit(p.Limit + 1).Find(&rows).Error; err != nil {
+		return nil, info, err
+	}
+
+	hasMore := len(rows) > p.Limit
+	if hasMore {
+		rows = rows[:p.Limit]
+	}
+	if backwards {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, info, nil
+	}
+
+	// 4. Cursors pointing past the last row and before the first one
+	hasNext := hasMore || backwards
+	hasPrev := cursor != nil && (!backwards || hasMore)
+	var err error
+	if hasNext {
+		if info.NextCursor, err = rowCursor(db, &rows[len(rows)-1], keys, signature, false); err != nil {
+			return nil, info, err
+		}
+	}
+	if hasPrev {
+		if info.PrevCursor, err = rowCursor(db, &rows[0], keys, signature, true); err != nil {
+			return nil, info, err
+		}
+	}
+	return rows, info, nil
+}
+
+// resolveSort returns the requested (or default) sort with the primary key as tiebreaker
+func resolveSort(spec ListSpec, p utils.PaginationParams, table string) []sortKey {
+	fields := p.Sort
+	if len(fields) == 0 {
+		fields = spec.DefaultSort
+	}
+	var keys []sortKey
+	for _, sf := range fields {
+		if sf.Field == "id" {
+			continue
+		}
+		keys = append(keys, sortKey{name: sf.Field, column: spec.Sortable[sf.Field], desc: sf.Desc})
+	}
+	idDesc := false
+	for _, sf := range fields {
+		if sf.Field == "id" {
+			idDesc = sf.Desc
+		}
+	}
+	return append(keys, sortKey{name: "id", column: table + ".id", desc: idDesc})
+}
+
+// sortSignature ties a cursor to the sort it was created for, e.g. "-created_at,id"
+func sortSignature(keys []sortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.name
+		if key.desc {
+			parts[i] = "-" + key.name
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// keysetCondition selects the rows after values in the sort order (before them when
+// backwards), expanded so it works with mixed directions on every database:
+// (a > ?) OR (a = ? AND b > ?) OR (a = ? AND b = ? AND id > ?)
+func keysetCondition(keys []sortKey, values []interface{}, backwards bool) (string, []interface{}) {
+	var (
+		clauses []string
+		args    []interface{}
+	)
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, keys[j].column+" = ?")
+			args = append(args, values[j])
+		}
+		op := ">"
+		if key.desc != backwards {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s ?", key.column, op))
+		args = append(args, values[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+// rowCursor reads the sort key values of row through its GORM schema
+func rowCursor[T any](db *gorm.DB, row *T, keys []sortKey, signature string, prev bool) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(row); err != nil {
+		return "", err
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		name := key.column[strings.LastIndex(key.column, ".")+1:]
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return "", fmt.Errorf("sort field %s is not a column of %s", name, stmt.Schema.Table)
+		}
+		values[i], _ = field.ValueOf(context.Background(), reflect.ValueOf(row).Elem())
+	}
+	return utils.EncodeCursor(utils.Cursor{Values: values, Prev: prev, Sort: signature})
+}
+
+// countRows counts the matching rows. Estimates come from the table statistics, so
+// they only apply to lists of the whole table; anything narrower, such as one owner's
+// files or a filtered list, is counted exactly. The statistics of a soft-deletable
+// table include its trashed rows.
+func countRows(db *gorm.DB, model interface{}, table string, p utils.PaginationParams, wholeTable bool, scopes []func(*gorm.DB) *gorm.DB) (int64, bool, error) {
+	if p.Total == utils.TotalEstimate && wholeTable && len(p.Filters) == 0 && p.Query == "" {
+		if estimate, ok := estimateRows(db, table); ok {
+			return estimate, true, nil
+		}
+	}
+	var total int64
+	err := db.Model(model).Scopes(scopes...).Count(&total).Error
+	return total, false, err
+}
+
+func estimateRows(db *gorm.DB, table string) (int64, bool) {
+	var estimate int64
+	var err error
+	switch db.Dialector.Name() {
+	case "mysql":
+		err = db.Raw("SELECT table_rows FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", table).Scan(&estimate).Error
+	case "postgres":
+		err = db.Raw("SELECT reltuples::bigint FROM pg_class WHERE relname = ?", table).Scan(&estimate).Error
+	default:
+		return 0, false
+	}
+	return estimate, err == nil && estimate >= 0
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang-api-template/internal/dbtest"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/utils"
+)
+
+// TestKeysetPagination walks a list forwards and backwards by cursor, with duplicate
+// sort values so the primary key tiebreaker matters
+func TestKeysetPagination(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	for i, name := range []string{"carol", "alice", "bob", "alice", "dave", "bob", "alice"} {
+		user := newUser(name, fmt.Sprintf("keyset-%d@example.com", i))
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another user's soft-deleted row must not show up in the pages
+	deleted := newUser("alice", "keyset-deleted@example.com")
+	if err := repo.Create(ctx, deleted); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		sort []utils.SortField
+		less func(a, b models.User) bool
+	}{
+		{
+			sort: []utils.SortField{{Field: "name"}},
+			less: func(a, b models.User) bool { return a.Name < b.Name || a.Name == b.Name && a.ID < b.ID },
+		},
+		{
+			sort: []utils.SortField{{Field: "name", Desc: true}, {Field: "id", Desc: true}},
+			less: func(a, b models.User) bool { return a.Name > b.Name || a.Name == b.Name && a.ID > b.ID },
+		},
+		{
+			// Rows created within the same instant tie on created_at
+			sort: []utils.SortField{{Field: "created_at", Desc: true}},
+			less: func(a, b models.User) bool {
+				return a.CreatedAt.After(b.CreatedAt) || a.CreatedAt.Equal(b.CreatedAt) && a.ID < b.ID
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(sortName(c.sort), func(t *testing.T) {
+			stored, _, err := repo.List(ctx, utils.PaginationParams{
+				Page: 1, Limit: 100, Total: utils.TotalNone, Filters: keysetFilter,
+			}, TrashedWithout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(stored, func(i, j int) bool { return c.less(stored[i], stored[j]) })
+			want := userIDs(stored)
+
+			p := utils.PaginationParams{Limit: 3, UseCursor: true, Total: utils.TotalNone, Sort: c.sort, Filters: keysetFilter}
+
+			// 1. Forwards from the first page
+			var pages [][]uint
+			var last utils.PageInfo
+			for {
+				if len(pages) > len(want) {
+					t.Fatal("paging forwards does not end")
+				}
+				users, info, err := repo.List(ctx, p, TrashedWithout)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(pages) == 0 && info.PrevCursor != "" {
+					t.Error("the first page has a previous cursor")
+				}
+				pages = append(pages, userIDs(users))
+				last = info
+				if info.NextCursor == "" {
+					break
+				}
+				p.Cursor = info.NextCursor
+			}
+			var forward []uint
+			for _, page := range pages {
+				forward = append(forward, page...)
+			}
+			if fmt.Sprint(forward) != fmt.Sprint(want) {
+				t.Fatalf("forwards: got %v, want %v", forward, want)
+			}
+
+			// 2. Backwards from the last page, through the same pages
+			p.Cursor = last.PrevCursor
+			for i := len(pages) - 2; i >= 0; i-- {
+				users, info, err := repo.List(ctx, p, TrashedWithout)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := userIDs(users); fmt.Sprint(got) != fmt.Sprint(pages[i]) {
+					t.Fatalf("backwards, page %d: got %v, want %v", i+1, got, pages[i])
+				}
+				if info.NextCursor == "" {
+					t.Errorf("backwards, page %d has no next cursor", i+1)
+				}
+				p.Cursor = info.PrevCursor
+			}
+			if p.Cursor != "" {
+				t.Fatal("the first page, reached backwards, has a previous cursor")
+			}
+		})
+	}
+
+	// A cursor only works with the sort it was created for
+	p := utils.PaginationParams{Limit: 3, UseCursor: true, Total: utils.TotalNone, Sort: []utils.SortField{{Field: "name"}}, Filters: keysetFilter}
+	_, info, err := repo.List(ctx, p, TrashedWithout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Sort, p.Cursor = []utils.SortField{{Field: "email"}}, info.NextCursor
+	if _, _, err := repo.List(ctx, p, TrashedWithout); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("cursor with another sort: got %v, want ErrInvalidQuery", err)
+	}
+	p.Cursor = "not-a-cursor"
+	if _, _, err := repo.List(ctx, p, TrashedWithout); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("malformed cursor: got %v, want ErrInvalidQuery", err)
+	}
+}
+
+var keysetFilter = []utils.Filter{{Field: "email", Operator: utils.FilterLike, Value: "keyset-"}}
+
+func userIDs(users []models.User) []uint {
+	ids := make([]uint, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids
+}
+
+// sortName is the `sort` parameter for fields, e.g. "-name,-id"
+func sortName(fields []utils.SortField) string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Field
+		if f.Desc {
+			names[i] = "-" + f.Field
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// TestEstimatedTotalOfScopedList asks for an estimated total of one owner's files. The
+// table statistics cover every owner, so the total must be counted, not estimated.
+func TestEstimatedTotalOfScopedList(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewFileRepository(db)
+	ctx := context.Background()
+
+	users := NewUserRepository(db)
+	owners := []*models.User{newUser("Owner", "estimate-owner@example.com"), newUser("Other", "estimate-other@example.com")}
+	for i, owner := range owners {
+		if err := users.Create(ctx, owner); err != nil {
+			t.Fatal(err)
+		}
+		// The second owner has more files than the first
+		for j := 0; j < 2+3*i; j++ {
+			file := &models.File{OwnerID: owner.ID, Key: fmt.Sprintf("estimate/%d/%d", i, j), Name: "f.txt", ContentType: "text/plain"}
+			if err := repo.CreateFile(ctx, file); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for i, want := range []int64{2, 5} {
+		files, info, err := repo.GetFilesByOwnerID(ctx, owners[i].ID, utils.PaginationParams{Page: 1, Limit: 10, Total: utils.TotalEstimate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Total == nil || *info.Total != want || info.TotalEstimated {
+			t.Errorf("owner %d: got total %v (estimated %v), want exactly %d", i+1, info.Total, info.TotalEstimated, want)
+		}
+		for _, f := range files {
+			if f.OwnerID != owners[i].ID {
+				t.Errorf("owner %d: got a file of owner %d", i+1, f.OwnerID)
+			}
+		}
+	}
+}
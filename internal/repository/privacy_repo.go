@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang-api-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// UserData is everything stored about one user, as included in a data export
+type UserData struct {
+	User          models.User           `json:"profile"`
+	LoginEvents   []models.LoginEvent   `json:"login_history"`
+	KnownDevices  []models.KnownDevice  `json:"devices"`
+	AuditLogs     []models.AuditLog     `json:"audit_log"`
+	OAuthConsents []models.OAuthConsent `json:"oauth_consents"`
+	EmailChanges  []models.EmailChange  `json:"email_changes"`
+	Invitations   []models.Invitation   `json:"invitations_sent"`
+	Files         []models.File         `json:"files"`
+}
+
+// PrivacyRepository knows every table holding personal data, for exports and erasure.
+// New tables with user data must be added to both CollectUserData and EraseUser.
+type PrivacyRepository interface {
+	CreateDataExport(ctx context.Context, export *models.DataExport) error
+	UpdateDataExport(ctx context.Context, export *models.DataExport) error
+	GetDataExport(ctx context.Context, id, userID uint) (*models.DataExport, error)
+	GetDataExportByID(ctx context.Context, id uint) (*models.DataExport, error)
+	GetDataExportsByUserID(ctx context.Context, userID uint) ([]models.DataExport, error)
+	GetUnfinishedDataExport(ctx context.Context, userID uint, since time.Time) (*models.DataExport, error)
+	GetExpiredDataExports(ctx context.Context, now time.Time) ([]models.DataExport, error)
+	DeleteDataExport(ctx context.Context, id uint) error
+
+	CollectUserData(ctx context.Context, userID uint) (*UserData, error)
+
+	// EraseUser anonymizes the user row (kept so IDs referenced elsewhere stay valid),
+	// anonymizes or deletes their data in other tables and soft-deletes the account
+	EraseUser(ctx context.Context, userID uint) error
+}
+
+type privacyRepository struct {
+	db *gorm.DB
+}
+
+func NewPrivacyRepository(db *gorm.DB) PrivacyRepository {
+	return &privacyRepository{db: db}
+}
+
+func (r *privacyRepository) CreateDataExport(ctx context.Context, export *models.DataExport) error {
+	return r.db.WithContext(ctx).Create(export).Error
+}
+
+func (r *privacyRepository) UpdateDataExport(ctx context.Context, export *models.DataExport) error {
+	return r.db.WithContext(ctx).Save(export).Error
+}
+
+func (r *privacyRepository) GetDataExport(ctx context.Context, id, userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&export).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *privacyRepository) GetDataExportByID(ctx context.Context, id uint) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := r.db.WithContext(ctx).First(&export, id).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *privacyRepository) GetDataExportsByUserID(ctx context.Context, userID uint) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&exports).Error
+	return exports, err
+}
+
+// GetUnfinishedDataExport returns an export still being built; ones started before
+// since are ignored so an export lost in a crash does not block the user forever
+func (r *privacyRepository) GetUnfinishedDataExport(ctx context.Context, userID uint, since time.Time) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status IN ? AND created_at > ?", userID, []string{models.DataExportPending, models.DataExportProcessing}, since).
+		First(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *privacyRepository) GetExpiredDataExports(ctx context.Context, now time.Time) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := r.db.WithContext(ctx).Where("expires_at <= ?", now).Find(&exports).Error
+	return exports, err
+}
+
+func (r *privacyRepository) DeleteDataExport(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&models.DataExport{}, id).Error
+}
+
+func (r *privacyRepository) CollectUserData(ctx context.Context, userID uint) (*UserData, error) {
+	data := &UserData{}
+	if err := r.db.WithContext(ctx).Preload("Roles").Preload("Profile").First(&data.User, userID).Error; err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		dest  interface{}
+		query string
+		args  []interface{}
+	}{
+		{&data.LoginEvents, "user_id = ?", []interface{}{userID}},
+		{&data.KnownDevices, "user_id = ?", []interface{}{userID}},
+		{&data.AuditLogs, "user_id = ? OR actor_id = ?", []interface{}{userID, userID}},
+		{&data.OAuthConsents, "user_id = ?", []interface{}{userID}},
+		{&data.EmailChanges, "user_id = ?", []interface{}{userID}},
+		{&data.Invitations, "invited_by_id = ?", []interface{}{userID}},
+		{&data.Files, "owner_id = ?", []interface{}{userID}},
+	}
+	for _, q := range queries {
+		if err := r.db.WithContext(ctx).Where(q.query, q.args...).Order("id").Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func (r *privacyRepository) EraseUser(ctx context.Context, userID uint) error {
+	placeholder := fmt.Sprintf("erased-%d@erased.invalid", userID)
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Unscoped().First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		steps := []func() *gorm.DB{
+			// Rows that only describe the user are removed
+			func() *gorm.DB { return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.KnownDevice{}) },
+			func() *gorm.DB { return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.EmailChange{}) },
+			func() *gorm.DB { return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.OAuthConsent{}) },
+			func() *gorm.DB { return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.UserProfile{}) },
+			func() *gorm.DB { return tx.Exec("DELETE FROM user_roles WHERE user_id = ?", userID) },
+
+			// Security records are kept, without the personal details
+			func() *gorm.DB {
+				return tx.Model(&models.LoginEvent{}).Where("user_id = ? OR email = ?", userID, user.Email).Updates(map[string]interface{}{
+					"email": "", "ip": "", "ip_prefix": "", "user_agent": "", "device_id": "", "country": "", "city": "",
+				})
+			},
+			func() *gorm.DB {
+				return tx.Model(&models.AuditLog{}).Where("user_id = ? OR actor_id = ?", userID, userID).Updates(map[string]interface{}{
+					"ip": "", "user_agent": "",
+				})
+			},
+			func() *gorm.DB {
+				return tx.Model(&models.Invitation{}).Where("email = ? OR accepted_by_id = ?", user.Email, userID).Update("email", placeholder)
+			},
+
+			// The user row stays (other tables reference its ID) but holds nothing personal
+			func() *gorm.DB {
+				return tx.Unscoped().Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+					"name":                  "Erased user",
+					"email":                 placeholder,
+					"password":              "",
+					"reset_token":           "",
+					"organization":          "",
+					"last_seen_at":          nil,
+					"status":                models.UserStatusDeactivated,
+					"status_reason":         models.UserErasedReason,
+					"status_changed_at":     time.Now(),
+					"deletion_scheduled_at": nil,
+					"deletion_requested_by": 0,
+					"deleted_id":            userID,
+				})
+			},
+			func() *gorm.DB { return tx.Where("id = ?", userID).Delete(&models.User{}) },
+		}
+		for _, step := range steps {
+			if err := step().Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"golang-api-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ProfileRepository interface {
+	// GetProfileByUserID returns gorm.ErrRecordNotFound if the user never saved a profile
+	GetProfileByUserID(ctx context.Context, userID uint) (*models.UserProfile, error)
+	SaveProfile(ctx context.Context, profile *models.UserProfile) error
+
+	CreateProfileField(ctx context.Context, field *models.ProfileField) error
+	UpdateProfileField(ctx context.Context, field *models.ProfileField) error
+	GetProfileFieldByID(ctx context.Context, id uint) (*models.ProfileField, error)
+	GetProfileFields(ctx context.Context) ([]models.ProfileField, error)
+	// DeleteProfileField removes the definition; stored values are dropped on the next profile update
+	DeleteProfileField(ctx context.Context, id uint) error
+}
+
+type profileRepository struct {
+	db *gorm.DB
+}
+
+func NewProfileRepository(db *gorm.DB) ProfileRepository {
+	return &profileRepository{db: db}
+}
+
+func (r *profileRepository) GetProfileByUserID(ctx context.Context, userID uint) (*models.UserProfile, error) {
+	var profile models.UserProfile
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *profileRepository) SaveProfile(ctx context.Context, profile *models.UserProfile) error {
+	return r.db.WithContext(ctx).Save(profile).Error
+}
+
+func (r *profileRepository) CreateProfileField(ctx context.Context, field *models.ProfileField) error {
+	return r.db.WithContext(ctx).Create(field).Error
+}
+
+func (r *profileRepository) UpdateProfileField(ctx context.Context, field *models.ProfileField) error {
+	return r.db.WithContext(ctx).Save(field).Error
+}
+
+func (r *profileRepository) GetProfileFieldByID(ctx context.Context, id uint) (*models.ProfileField, error) {
+	var field models.ProfileField
+	if err := r.db.WithContext(ctx).First(&field, id).Error; err != nil {
+		return nil, err
+	}
+	return &field, nil
+}
+
+func (r *profileRepository) GetProfileFields(ctx context.Context) ([]models.ProfileField, error) {
+	var fields []models.ProfileField
+	err := r.db.WithContext(ctx).Order("id").Find(&fields).Error
+	return fields, err
+}
+
+// DeleteProfileField deletes permanently so the key can be defined again
+func (r *profileRepository) DeleteProfileField(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&models.ProfileField{}, id).Error
+}
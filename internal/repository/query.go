@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidQuery = errors.New("invalid list query")
+
+// QueryError tells which query parameter of a list request was rejected and why
+type QueryError struct {
+	Param  string
+	Reason string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s %s", e.Param, e.Reason)
+}
+
+func (e *QueryError) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+// ListSpec is the whitelist of what clients may filter, sort and search on for one
+// resource. Keys are the names used in the API, values the SQL columns they map to,
+// so nothing from the request is ever interpolated into SQL. Sortable columns must
+// be NOT NULL, because keyset (cursor) pagination compares their values.
+type ListSpec struct {
+	Filterable  map[string]string
+	Sortable    map[string]string
+	Searchable  []string // columns matched by `q`
+	DefaultSort []utils.SortField
+	Preload     []string // associations loaded with the rows
+}
+
+// Validate checks the filters and sort fields of p against the whitelist
+func (s ListSpec) Validate(p utils.PaginationParams) error {
+	for _, f := range p.Filters {
+		param := fmt.Sprintf("filter[%s][%s]", f.Field, f.Operator)
+		if _, ok := s.Filterable[f.Field]; !ok {
+			return &QueryError{Param: param, Reason: "is not a filterable field"}
+		}
+		switch f.Operator {
+		case utils.FilterEq, utils.FilterNe, utils.FilterLike, utils.FilterGt, utils.FilterGte,
+			utils.FilterLt, utils.FilterLte, utils.FilterIn:
+		case utils.FilterNull:
+			if f.Value != "true" && f.Value != "false" {
+				return &QueryError{Param: param, Reason: `must be "true" or "false"`}
+			}
+		default:
+			return &QueryError{Param: param, Reason: "uses an unknown operator"}
+		}
+	}
+	for _, sf := range p.Sort {
+		if _, ok := s.Sortable[sf.Field]; !ok {
+			return &QueryError{Param: "sort", Reason: fmt.Sprintf("cannot sort by %q", sf.Field)}
+		}
+	}
+	if p.Query != "" && len(s.Searchable) == 0 {
+		return &QueryError{Param: "q", Reason: "is not supported here"}
+	}
+	return nil
+}
+
+// scopeFilters applies the validated filters and the `q` search
+func scopeFilters(spec ListSpec, p utils.PaginationParams) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, f := range p.Filters {
+			column := spec.Filterable[f.Field]
+			switch f.Operator {
+			case utils.FilterEq:
+				db = db.Where(column+" = ?", f.Value)
+			case utils.FilterNe:
+				db = db.Where(column+" <> ?", f.Value)
+			case utils.FilterLike:
+				db = db.Where(column+" "+likeOperator(db)+" ? ESCAPE '!'", likePattern(f.Value))
+			case utils.FilterGt:
+				db = db.Where(column+" > ?", f.Value)
+			case utils.FilterGte:
+				db = db.Where(column+" >= ?", f.Value)
+			case utils.FilterLt:
+				db = db.Where(column+" < ?", f.Value)
+			case utils.FilterLte:
+				db = db.Where(column+" <= ?", f.Value)
+			case utils.FilterIn:
+				db = db.Where(column+" IN ?", strings.Split(f.Value, ","))
+			case utils.FilterNull:
+				if f.Value == "true" {
+					db = db.Where(column + " IS NULL")
+				} else {
+					db = db.Where(column + " IS NOT NULL")
+				}
+			}
+		}
+
+		if p.Query != "" && len(spec.Searchable) > 0 {
+			conditions := make([]string, len(spec.Searchable))
+			args := make([]interface{}, len(spec.Searchable))
+			for i, column := range spec.Searchable {
+				conditions[i] = column + " " + likeOperator(db) + " ? ESCAPE '!'"
+				args[i] = likePattern(p.Query)
+			}
+			db = db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+		}
+		return db
+	}
+}
+
+// scopeSort orders by the requested fields (or the default), with the primary key
+// last so rows with equal sort values always come back in the same order
+func scopeSort(spec ListSpec, p utils.PaginationParams, table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, key := range resolveSort(spec, p, table) {
+			if key.desc {
+				db = db.Order(key.column + " DESC")
+			} else {
+				db = db.Order(key.column)
+			}
+		}
+		return db
+	}
+}
+
+// likeOperator matches case-insensitively on every driver: MySQL's default collation
+// and SQLite's LIKE already ignore case, PostgreSQL needs ILIKE
+func likeOperator(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// likePattern builds a "contains" pattern, escaping the LIKE wildcards in value.
+// '!' is used as escape character because backslash handling differs between databases.
+func likePattern(value string) string {
+	escaped := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+	return "%" + escaped + "%"
+}
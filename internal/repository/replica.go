@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"golang-api-template/internal/config"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replica sends the reads of db to a read replica when DB_REPLICA_HOSTS is set.
+// Replicas may lag a little behind the primary, so this is for listings, not for
+// reads that must see a write the caller just made. Inside a transaction it has no
+// effect.
+func replica(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Use(config.ReplicaResolver))
+}
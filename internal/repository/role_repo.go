@@ -1,55 +1,85 @@
 package repository
 
 import (
+	"context"
+
 	"golang-api-template/internal/models"
 
 	"gorm.io/gorm"
 )
 
 type RoleRepository interface {
-	CreateRole(role *models.Role) error
-	GetAllRoles() ([]models.Role, error)
-	GetRoleByID(id uint) (*models.Role, error)
-	UpdateRole(role *models.Role) error
-	DeleteRole(id uint) error
-	GetPermissionsByRoleID(roleID uint) ([]models.Permission, error)
+	Crud[models.Role]
+	GetRoleByName(ctx context.Context, name string) (*models.Role, error)
+	GetPermissionsByRoleID(ctx context.Context, roleID uint) ([]models.Permission, error)
+	// ReplaceRolePermissions sets the role's permissions to the existing ones among ids
+	ReplaceRolePermissions(ctx context.Context, role *models.Role, ids []uint) error
 }
 
 type roleRepo struct {
+	Crud[models.Role]
 	db *gorm.DB
 }
 
 func NewRoleRepository(db *gorm.DB) RoleRepository {
-	return &roleRepo{db}
-}
-
-func (repo *roleRepo) CreateRole(role *models.Role) error {
-	return repo.db.Create(role).Error
+	return &roleRepo{Crud: NewCrud[models.Role](db, roleListSpec), db: db}
 }
 
-func (repo *roleRepo) GetAllRoles() ([]models.Role, error) {
-	var roles []models.Role
-	err := repo.db.Preload("Permissions").Find(&roles).Error
-	return roles, err
+// roleListSpec is what `GET /roles` can filter, sort and search on
+var roleListSpec = ListSpec{
+	Filterable: map[string]string{
+		"id":         "roles.id",
+		"name":       "roles.name",
+		"created_at": "roles.created_at",
+		"deleted_at": "roles.deleted_at",
+	},
+	Sortable: map[string]string{
+		"id":         "roles.id",
+		"name":       "roles.name",
+		"created_at": "roles.created_at",
+	},
+	Searchable: []string{"roles.name"},
+	Preload:    []string{"Permissions"},
 }
 
-func (repo *roleRepo) GetRoleByID(id uint) (*models.Role, error) {
+func (repo *roleRepo) GetRoleByName(ctx context.Context, name string) (*models.Role, error) {
 	var role models.Role
-	err := repo.db.Preload("Permissions").First(&role, id).Error
+	err := repo.db.WithContext(ctx).Where("name = ?", name).First(&role).Error
 	return &role, err
 }
 
-func (repo *roleRepo) UpdateRole(role *models.Role) error {
-	return repo.db.Save(role).Error
+// ForceDelete permanently removes the role, its permission links and its assignments
+func (repo *roleRepo) ForceDelete(ctx context.Context, id uint) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"user_roles", "invitation_roles"} {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE role_id = ?", id).Error; err != nil {
+				return err
+			}
+		}
+		role := &models.Role{}
+		role.ID = id
+		return tx.Unscoped().Select("Permissions").Delete(role).Error
+	})
 }
 
-func (repo *roleRepo) DeleteRole(id uint) error {
-	return repo.db.Delete(&models.Role{}, id).Error
-}
-func (repo *roleRepo) GetPermissionsByRoleID(roleID uint) ([]models.Permission, error) {
+func (repo *roleRepo) GetPermissionsByRoleID(ctx context.Context, roleID uint) ([]models.Permission, error) {
 	var role models.Role
-	if err := repo.db.Preload("Permissions").First(&role, roleID).Error; err != nil {
+	if err := repo.db.WithContext(ctx).Preload("Permissions").First(&role, roleID).Error; err != nil {
 		return nil, err
 	}
 	return role.Permissions, nil
 }
+
+func (repo *roleRepo) ReplaceRolePermissions(ctx context.Context, role *models.Role, ids []uint) error {
+	permissions := []models.Permission{}
+	if len(ids) > 0 {
+		if err := repo.db.WithContext(ctx).Where("id IN ?", ids).Find(&permissions).Error; err != nil {
+			return err
+		}
+	}
+	if err := repo.db.WithContext(ctx).Model(role).Association("Permissions").Replace(permissions); err != nil {
+		return err
+	}
+	role.Permissions = permissions
+	return nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang-api-template/internal/dbtest"
+	"golang-api-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func TestRoleRepository(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewRoleRepository(db)
+	users := NewUserRepository(db)
+	ctx := context.Background()
+
+	permissions := []models.Permission{{Name: "role-repo-test.view"}, {Name: "role-repo-test.export"}}
+	if err := db.Create(&permissions).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. Create, get and look up by name
+	role := &models.Role{Name: "analyst"}
+	if err := repo.Create(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, role.ID); err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if found, err := repo.GetRoleByName(ctx, "analyst"); err != nil || found.ID != role.ID {
+		t.Fatalf("GetRoleByName: got %v, %v", found, err)
+	}
+
+	// 2. Permissions are replaced by ID; unknown IDs are ignored
+	if err := repo.ReplaceRolePermissions(ctx, role, []uint{permissions[0].ID, permissions[1].ID, 999999}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.GetPermissionsByRoleID(ctx, role.ID); err != nil || len(got) != 2 {
+		t.Fatalf("GetPermissionsByRoleID: got %d permissions and %v, want 2", len(got), err)
+	}
+	if err := repo.ReplaceRolePermissions(ctx, role, []uint{permissions[1].ID}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.GetPermissionsByRoleID(ctx, role.ID)
+	if err != nil || len(got) != 1 || got[0].Name != "role-repo-test.export" {
+		t.Fatalf("GetPermissionsByRoleID after replacing: got %v and %v", got, err)
+	}
+
+	// 3. Users get the permissions of their roles
+	user := newUser("Analyst", "analyst@example.com")
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.ReplaceUserRoles(ctx, user, []models.Role{*role}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := users.GetPermissionsByUserID(ctx, user.ID); err != nil || len(got) != 1 {
+		t.Fatalf("GetPermissionsByUserID: got %v and %v", got, err)
+	}
+
+	// 4. Update
+	role.Name = "senior-analyst"
+	if err := repo.Update(ctx, role.ID, role); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetRoleByName(ctx, "analyst"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetRoleByName of the old name: got %v, want ErrRecordNotFound", err)
+	}
+
+	// 5. Names are unique among live roles only
+	// A failed statement aborts a PostgreSQL transaction, so try in a nested one
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return NewRoleRepository(tx).Create(ctx, &models.Role{Name: "senior-analyst"})
+	})
+	if err == nil {
+		t.Fatal("Create with the name of a live role succeeded")
+	}
+	if err := repo.Delete(ctx, role.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetDeletedByID(ctx, role.ID); err != nil {
+		t.Fatalf("GetDeletedByID: %v", err)
+	}
+	replacement := &models.Role{Name: "senior-analyst"}
+	if err := repo.Create(ctx, replacement); err != nil {
+		t.Fatalf("Create with the name of a deleted role: %v", err)
+	}
+	if err := repo.ForceDelete(ctx, replacement.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Restore(ctx, role.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 6. Force-delete removes the permission links and the assignments, not the permissions
+	if err := repo.ForceDelete(ctx, role.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetDeletedByID(ctx, role.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetDeletedByID after ForceDelete: got %v, want ErrRecordNotFound", err)
+	}
+	for _, table := range []string{"role_permissions", "user_roles"} {
+		var links int64
+		db.Table(table).Where("role_id = ?", role.ID).Count(&links)
+		if links != 0 {
+			t.Fatalf("ForceDelete left %d rows in %s", links, table)
+		}
+	}
+	var kept int64
+	db.Model(&models.Permission{}).Where("id IN ?", []uint{permissions[0].ID, permissions[1].ID}).Count(&kept)
+	if kept != 2 {
+		t.Fatalf("ForceDelete removed permissions, %d left", kept)
+	}
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Values of the `?trashed=` query parameter on list endpoints
+const (
+	TrashedWithout = ""     // only live rows (default)
+	TrashedWith    = "with" // live and soft-deleted rows
+	TrashedOnly    = "only" // only soft-deleted rows
+)
+
+var ErrInvalidTrashedFilter = errors.New(`trashed must be "with" or "only"`)
+
+// ValidTrashed reports whether the value is an accepted `?trashed=` filter
+func ValidTrashed(trashed string) bool {
+	return trashed == TrashedWithout || trashed == TrashedWith || trashed == TrashedOnly
+}
+
+// scopeTrashed applies the `?trashed=` filter to a query on a soft-deletable model
+func scopeTrashed(trashed string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch trashed {
+		case TrashedWith:
+			return db.Unscoped()
+		case TrashedOnly:
+			return db.Unscoped().Where("deleted_at IS NOT NULL")
+		default:
+			return db
+		}
+	}
+}
@@ -1,92 +1,80 @@
 package repository
 
 import (
-	"golang-api-template/internal/models"
-	"golang-api-template/internal/utils"
+	"context"
+	"strings"
 	"time"
 
+	"golang-api-template/internal/models"
+
 	"golang.org/x/crypto/bcrypt"
 
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	CreateUser(user *models.User) error
-	GetUserByID(id uint) (*models.User, error)
-	GetUserByEmail(email string) (*models.User, error)
-	UpdateUser(user *models.User) error
-	DeleteUser(id uint) error
-
-	// For listing with pagination
-	GetUsers(p utils.PaginationParams) ([]models.User, int64, error)
-	GetPermissionsByUserID(userID uint) ([]models.Permission, error)
-
-	FindByEmail(email string) (*models.User, error)
-	SaveResetToken(userID uint, token string, expiry time.Time) error // Corrected signature
-
-	FindByToken(token string) (*models.User, error)
-	UpdatePassword(userID uint, newPassword string) error
+	Crud[models.User]
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetPermissionsByUserID(ctx context.Context, userID uint) ([]models.Permission, error)
+	ReplaceUserRoles(ctx context.Context, user *models.User, roles []models.Role) error
+	GetUsersByIDs(ctx context.Context, ids []uint) ([]models.User, error)
+	UpdateLastSeen(ctx context.Context, userID uint, seenAt time.Time) error
+	UpdateStatus(ctx context.Context, userID uint, status, reason string, deletionAt *time.Time, deletionBy uint) error
+	GetUsersDueForDeletion(ctx context.Context, now time.Time, limit int) ([]models.User, error)
+
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	SaveResetToken(ctx context.Context, userID uint, token string, expiry time.Time) error // Corrected signature
+
+	FindByToken(ctx context.Context, token string) (*models.User, error)
+	UpdatePassword(ctx context.Context, userID uint, newPassword string) error
+	UpdateNameAndPassword(ctx context.Context, userID uint, name, hashedPassword string) error
 }
 
 type userRepository struct {
+	Crud[models.User]
 	db *gorm.DB
 }
 
 func NewUserRepository(db *gorm.DB) UserRepository {
-	return &userRepository{db: db}
-}
-
-func (r *userRepository) CreateUser(user *models.User) error {
-	return r.db.Create(user).Error
-}
-
-func (r *userRepository) GetUserByID(id uint) (*models.User, error) {
-	var user models.User
-	if err := r.db.First(&user, id).Error; err != nil {
-		return nil, err
-	}
-	return &user, nil
+	return &userRepository{Crud: NewCrud[models.User](db, userListSpec), db: db}
 }
 
-func (r *userRepository) GetUserByEmail(email string) (*models.User, error) {
+// GetUserByEmail ignores case like MySQL's collation does; emails are stored lowercase
+// so the lookup behaves the same on case-sensitive databases
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("email = ?", normalizeEmail(email)).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) UpdateUser(user *models.User) error {
-	return r.db.Save(user).Error
-}
-
-func (r *userRepository) DeleteUser(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
-}
-
-// GetUsers fetches a list of users with pagination
-func (r *userRepository) GetUsers(p utils.PaginationParams) ([]models.User, int64, error) {
-	var (
-		users []models.User
-		total int64
-	)
-
-	// Count total
-	if err := r.db.Model(&models.User{}).Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// Fetch with pagination
-	if err := r.db.Offset(p.Offset()).Limit(p.Limit).Find(&users).Error; err != nil {
-		return nil, 0, err
-	}
-
-	return users, total, nil
+// userListSpec is what `GET /users` can filter, sort and search on
+var userListSpec = ListSpec{
+	Filterable: map[string]string{
+		"id":           "users.id",
+		"name":         "users.name",
+		"email":        "users.email",
+		"status":       "users.status",
+		"auth_source":  "users.auth_source",
+		"organization": "users.organization",
+		"created_at":   "users.created_at",
+		"last_seen_at": "users.last_seen_at",
+		"deleted_at":   "users.deleted_at",
+	},
+	Sortable: map[string]string{
+		"id":         "users.id",
+		"name":       "users.name",
+		"email":      "users.email",
+		"status":     "users.status",
+		"created_at": "users.created_at",
+	},
+	Searchable: []string{"users.name", "users.email"},
 }
 
-func (repo *userRepository) GetPermissionsByUserID(userID uint) ([]models.Permission, error) {
+func (repo *userRepository) GetPermissionsByUserID(ctx context.Context, userID uint) ([]models.Permission, error) {
 	var user models.User
-	if err := repo.db.Preload("Roles.Permissions").First(&user, userID).Error; err != nil {
+	if err := repo.db.WithContext(ctx).Preload("Roles.Permissions").First(&user, userID).Error; err != nil {
 		return nil, err
 	}
 
@@ -102,6 +90,29 @@ func (repo *userRepository) GetPermissionsByUserID(userID uint) ([]models.Permis
 	return permissions, nil
 }
 
+// ReplaceUserRoles sets the user's roles to exactly the given list
+func (r *userRepository) ReplaceUserRoles(ctx context.Context, user *models.User, roles []models.Role) error {
+	return r.db.WithContext(ctx).Model(user).Association("Roles").Replace(roles)
+}
+
+func (r *userRepository) GetUsersByIDs(ctx context.Context, ids []uint) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+// UpdateLastSeen only touches last_seen_at, so it does not bump updated_at
+func (r *userRepository) UpdateLastSeen(ctx context.Context, userID uint, seenAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).UpdateColumn("last_seen_at", seenAt).Error
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 func containsPermission(permissions []models.Permission, permission models.Permission) bool {
 	for _, perm := range permissions {
 		if perm.ID == permission.ID {
@@ -111,31 +122,67 @@ func containsPermission(permissions []models.Permission, permission models.Permi
 	return false
 }
 
-func (r *userRepository) FindByEmail(email string) (*models.User, error) {
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("email = ?", normalizeEmail(email)).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
-func (r *userRepository) SaveResetToken(userID uint, token string, expiry time.Time) error {
-	return r.db.Model(&models.User{}).Where("id = ?", userID).Updates(models.User{ResetToken: token, TokenExpiry: expiry}).Error
+func (r *userRepository) SaveResetToken(ctx context.Context, userID uint, token string, expiry time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(models.User{ResetToken: token, TokenExpiry: expiry}).Error
 }
 
-func (r *userRepository) FindByToken(token string) (*models.User, error) {
+// FindByToken looks a user up by reset token; cleared tokens are empty strings, which
+// must never match
+func (r *userRepository) FindByToken(ctx context.Context, token string) (*models.User, error) {
+	if token == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
 	var user models.User
-	if err := r.db.Where("reset_token = ?", token).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("reset_token = ?", token).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) UpdatePassword(userID uint, newPassword string) error {
+func (r *userRepository) UpdatePassword(ctx context.Context, userID uint, newPassword string) error {
 	// Example hashing password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
-	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("password", hashedPassword).Error
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("password", hashedPassword).Error
+}
+
+// UpdateNameAndPassword writes only these two columns, so it cannot undo a status
+// change made meanwhile. An empty hashedPassword keeps the current password.
+func (r *userRepository) UpdateNameAndPassword(ctx context.Context, userID uint, name, hashedPassword string) error {
+	columns := map[string]interface{}{"name": name}
+	if hashedPassword != "" {
+		columns["password"] = hashedPassword
+	}
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(columns).Error
+}
+
+// UpdateStatus moves the user to another lifecycle state; deletionAt and deletionBy are only kept for pending_deletion
+func (r *userRepository) UpdateStatus(ctx context.Context, userID uint, status, reason string, deletionAt *time.Time, deletionBy uint) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"status":                status,
+		"status_reason":         reason,
+		"status_changed_at":     time.Now(),
+		"deletion_scheduled_at": deletionAt,
+		"deletion_requested_by": deletionBy,
+	}).Error
+}
+
+func (r *userRepository) GetUsersDueForDeletion(ctx context.Context, now time.Time, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND deletion_scheduled_at <= ?", models.UserStatusPendingDeletion, now).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
 }
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang-api-template/internal/dbtest"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+func newUser(name, email string) *models.User {
+	return &models.User{Name: name, Email: email, Password: "hash"}
+}
+
+// TestUserRepository runs on in-memory SQLite, or on the database set by
+// TEST_DB_DRIVER and TEST_DB_DSN (see package dbtest)
+func TestUserRepository(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	// 1. Create, get and list
+	user := newUser("Ann", "ann@example.com")
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 || user.Status != models.UserStatusActive || user.AuthSource != "local" {
+		t.Fatalf("Create: got %+v, want an ID and the column defaults", user)
+	}
+	if _, err := repo.GetByID(ctx, user.ID); err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	// Lookups ignore case and surrounding spaces on every driver
+	found, err := repo.GetUserByEmail(ctx, " Ann@Example.COM ")
+	if err != nil || found.ID != user.ID {
+		t.Fatalf("GetUserByEmail: got %v, %v", found, err)
+	}
+	byEmail := []utils.Filter{{Field: "email", Operator: utils.FilterEq, Value: "ann@example.com"}}
+	users, page, err := repo.List(ctx, utils.PaginationParams{Page: 1, Limit: 10, Total: utils.TotalExact, Filters: byEmail}, TrashedWithout)
+	if err != nil || len(users) != 1 || *page.Total != 1 {
+		t.Fatalf("List: got %d users and %v", len(users), err)
+	}
+
+	// 2. Update
+	user.Name = "Ann Smith"
+	if err := repo.Update(ctx, user.ID, user); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.GetByID(ctx, user.ID); got.Name != "Ann Smith" {
+		t.Fatalf("GetByID after Update: name %q", got.Name)
+	}
+
+	// 3. Soft-delete and restore
+	if err := repo.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetByID after Delete: got %v, want ErrRecordNotFound", err)
+	}
+	if _, err := repo.GetUserByEmail(ctx, "ann@example.com"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetUserByEmail after Delete: got %v, want ErrRecordNotFound", err)
+	}
+	trashed, _, err := repo.List(ctx, utils.PaginationParams{Page: 1, Limit: 10, Total: utils.TotalNone, Filters: byEmail}, TrashedOnly)
+	if err != nil || len(trashed) != 1 || trashed[0].ID != user.ID {
+		t.Fatalf("List trashed: got %d users and %v", len(trashed), err)
+	}
+	if err := repo.Restore(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, user.ID); err != nil {
+		t.Fatalf("GetByID after Restore: %v", err)
+	}
+
+	// 4. Force-delete takes the role assignments along
+	role := &models.Role{Name: "user-repo-test"}
+	if err := NewRoleRepository(db).Create(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ReplaceUserRoles(ctx, user, []models.Role{*role}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ForceDelete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetDeletedByID(ctx, user.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetDeletedByID after ForceDelete: got %v, want ErrRecordNotFound", err)
+	}
+	var links int64
+	db.Table("user_roles").Where("user_id = ?", user.ID).Count(&links)
+	if links != 0 {
+		t.Fatalf("ForceDelete left %d role assignments", links)
+	}
+}
+
+// TestUserEmailUniqueAmongLiveUsers checks the (email, deleted_id) index: an email is
+// unique among live users, but deleted users do not keep it from being registered again
+func TestUserEmailUniqueAmongLiveUsers(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	// A failed statement aborts a PostgreSQL transaction, so try in a nested one
+	createNested := func(user *models.User) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			return NewUserRepository(tx).Create(ctx, user)
+		})
+	}
+
+	first := newUser("First", "same@example.com")
+	if err := repo.Create(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := createNested(newUser("Copy", "same@example.com")); err == nil {
+		t.Fatal("Create with the email of a live user succeeded")
+	}
+
+	// 1. Deleting stamps deleted_id, which frees the email
+	if err := repo.Delete(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	var deleted models.User
+	if err := db.Unscoped().First(&deleted, first.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if deleted.DeletedID != first.ID {
+		t.Fatalf("deleted_id is %d, want the row's own ID %d", deleted.DeletedID, first.ID)
+	}
+	second := newUser("Second", "same@example.com")
+	if err := repo.Create(ctx, second); err != nil {
+		t.Fatalf("Create with the email of a deleted user: %v", err)
+	}
+
+	// 2. Any number of deleted users may share it
+	if err := repo.Delete(ctx, second.ID); err != nil {
+		t.Fatalf("Delete of a second user with the same email: %v", err)
+	}
+	third := newUser("Third", "same@example.com")
+	if err := repo.Create(ctx, third); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3. Restoring clears deleted_id, so it fails while the email is taken again
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return NewUserRepository(tx).Restore(ctx, first.ID)
+	})
+	if err == nil {
+		t.Fatal("Restore of a user whose email is taken succeeded")
+	}
+	if err := repo.ForceDelete(ctx, third.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Restore(ctx, first.ID); err != nil {
+		t.Fatalf("Restore once the email is free: %v", err)
+	}
+}
+
+// TestUserUpdateNameAndPassword checks that editing a user leaves the other columns,
+// here a suspension made after the user was read, as they are
+func TestUserUpdateNameAndPassword(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	user := newUser("Before", "partial@example.com")
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateStatus(ctx, user.ID, models.UserStatusSuspended, "abuse", nil, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.UpdateNameAndPassword(ctx, user.ID, "After", ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "After" || got.Password != "hash" {
+		t.Fatalf("got name %q and password %q, want the new name and the old password", got.Name, got.Password)
+	}
+	if got.Status != models.UserStatusSuspended || got.StatusReason != "abuse" {
+		t.Fatalf("got status %q (%q), want the suspension kept", got.Status, got.StatusReason)
+	}
+
+	if err := repo.UpdateNameAndPassword(ctx, user.ID, "After", "new-hash"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.GetByID(ctx, user.ID); got.Password != "new-hash" {
+		t.Fatalf("got password %q, want new-hash", got.Password)
+	}
+}
+
+func TestUserResetToken(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	// Users without a reset token have an empty one, which must never match
+	withoutToken := newUser("No Token", "no-token@example.com")
+	user := newUser("Reset", "reset@example.com")
+	for _, u := range []*models.User{withoutToken, user} {
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.FindByToken(ctx, ""); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindByToken(\"\"): got %v, want ErrRecordNotFound", err)
+	}
+
+	if err := repo.SaveResetToken(ctx, user.ID, "0123456789abcdef", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	found, err := repo.FindByToken(ctx, "0123456789abcdef")
+	if err != nil || found.ID != user.ID {
+		t.Fatalf("FindByToken: got %v, %v", found, err)
+	}
+	if _, err := repo.FindByToken(ctx, "fedcba9876543210"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindByToken with another token: got %v, want ErrRecordNotFound", err)
+	}
+
+	// The new password is stored hashed
+	if err := repo.UpdatePassword(ctx, user.ID, "new-password"); err != nil {
+		t.Fatal(err)
+	}
+	updated, _ := repo.GetByID(ctx, user.ID)
+	if !utils.CheckPasswordHash("new-password", updated.Password) {
+		t.Fatal("UpdatePassword did not store a hash of the new password")
+	}
+
+	// Deleted users cannot reset their password
+	if err := repo.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.FindByToken(ctx, "0123456789abcdef"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindByToken of a deleted user: got %v, want ErrRecordNotFound", err)
+	}
+}
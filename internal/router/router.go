@@ -1,12 +1,20 @@
 package router
 
 import (
+	"context"
+	"log"
+	"path/filepath"
+	"time"
+
 	"golang-api-template/internal/config"
 	"golang-api-template/internal/handlers"
 	"golang-api-template/internal/i18n"
 	"golang-api-template/internal/middlewares"
+	"golang-api-template/internal/realtime"
 	"golang-api-template/internal/repository"
 	"golang-api-template/internal/service"
+	"golang-api-template/internal/storage"
+	"golang-api-template/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
@@ -18,29 +26,105 @@ func SetupRouter(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine
 		panic("Failed to load translations: " + err.Error())
 	}
 
+	utils.ConfigurePagination(cfg.Pagination)
+	if err := utils.ConfigureTrustedProxies(cfg.TrustedProxies); err != nil {
+		panic("Invalid TRUSTED_PROXIES: " + err.Error())
+	}
+
 	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		panic("Invalid TRUSTED_PROXIES: " + err.Error())
+	}
 
 	// Middlewares
 	r.Use(middlewares.LocaleMiddleware())
+	r.Use(middlewares.CSRFProtection(cfg.Cookie))
 
 	emailConfig := config.GetEmailConfig()
 	emailService := service.NewEmailService(emailConfig)
 
 	// Repos
 	userRepo := repository.NewUserRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	loginEventRepo := repository.NewLoginEventRepository(db)
+	emailChangeRepo := repository.NewEmailChangeRepository(db)
+	invitationRepo := repository.NewInvitationRepository(db)
+	privacyRepo := repository.NewPrivacyRepository(db)
+	profileRepo := repository.NewProfileRepository(db)
+	fileRepo := repository.NewFileRepository(db)
+
+	geoLocator, err := service.NewGeoLocator(cfg.GeoIPDBPath)
+	if err != nil {
+		panic("Failed to open GeoIP database: " + err.Error())
+	}
+
+	// Uploaded files; public keys of the local driver are served from /files/public
+	files, err := storage.New(cfg.Storage)
+	if err != nil {
+		panic("Failed to set up file storage: " + err.Error())
+	}
+	if cfg.Storage.Driver == "local" {
+		r.Static("/files/public", filepath.Join(cfg.Storage.LocalDir, "public"))
+	}
+
+	// Login backends, tried in order
+	authenticators := []service.Authenticator{service.NewDBAuthenticator(userRepo)}
+	if cfg.LDAP.Enabled {
+		authenticators = append(authenticators, service.NewLDAPAuthenticator(cfg.LDAP, userRepo, roleRepo))
+	}
+
+	// Real-time push, fanned out across instances through Redis pub/sub
+	hub := realtime.NewHub(rdb, cfg.Realtime.ClientBuffer)
+	go hub.Run(context.Background())
 
 	// Services
 	userService := service.NewUserService(userRepo) // from previous examples
-	authService := service.NewAuthService(userRepo, rdb, cfg)
+	authService := service.NewAuthService(userRepo, rdb, cfg, hub, authenticators...)
+	go sweepPresence(context.Background(), authService, cfg.Realtime.SweepInterval)
+	auditService := service.NewAuditService(auditRepo)
+	profileService := service.NewProfileService(profileRepo, files, cfg)
+	fileService := service.NewFileService(fileRepo, files, cfg.Storage)
+	privacyService := service.NewPrivacyService(privacyRepo, auditService, profileService, fileService, files, emailService, hub, rdb, cfg)
+	go purgeExpiredExports(context.Background(), privacyService, cfg.AccountPurgeInterval)
+	accountService := service.NewAccountService(userRepo, auditService, privacyService, rdb, cfg)
+	go purgeDeletedAccounts(context.Background(), accountService, cfg.AccountPurgeInterval)
+	loginHistoryService := service.NewLoginHistoryService(loginEventRepo, userRepo, emailService, geoLocator)
+	emailChangeService := service.NewEmailChangeService(emailChangeRepo, userRepo, emailService, cfg)
+	invitationService := service.NewInvitationService(invitationRepo, userRepo, roleRepo, emailService, cfg)
 
 	// Handlers
-	userHandler := handlers.NewUserHandler(userService, emailService)
-	authHandler := handlers.NewAuthHandler(authService)
+	userHandler := handlers.NewUserHandler(userService, emailService, emailChangeService, cfg)
+	emailChangeHandler := handlers.NewEmailChangeHandler(emailChangeService)
+	invitationHandler := handlers.NewInvitationHandler(invitationService)
+	authHandler := handlers.NewAuthHandler(authService, loginHistoryService, profileService, cfg)
+	profileHandler := handlers.NewProfileHandler(profileService, cfg)
+	fileHandler := handlers.NewFileHandler(fileService, cfg.Storage)
+	adminHandler := handlers.NewAdminHandler(authService, auditService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService)
+	presenceHandler := handlers.NewPresenceHandler(authService, userService)
+	streamTicketService := service.NewStreamTicketService(rdb, cfg.Realtime.TicketTTL)
+	realtimeHandler := handlers.NewRealtimeHandler(hub, streamTicketService, accountService, cfg.Realtime)
+
+	authMiddleware := middlewares.AuthMiddleware(cfg, accountService, auditService)
+	presenceMiddleware := middlewares.TrackPresence(authService)
+	recentAuth := middlewares.RequireRecentAuth(time.Duration(cfg.StepUpMaxAgeSec) * time.Second)
 
-	roleRepo := repository.NewRoleRepository(db)
 	roleService := service.NewRoleService(roleRepo)
 	roleHandler := handlers.NewRoleHandler(roleService)
 
+	// OpenID Connect provider
+	oidcKey, err := cfg.OIDC.LoadSigningKey()
+	if err != nil {
+		panic("Failed to load OIDC signing key: " + err.Error())
+	}
+	oauthRepo := repository.NewOAuthRepository(db)
+	oidcService := service.NewOIDCService(oauthRepo, userRepo, accountService, rdb, cfg, oidcKey)
+	oidcHandler := handlers.NewOIDCHandler(oidcService)
+
+	r.GET("/.well-known/openid-configuration", oidcHandler.Discovery)
+
 	// Public routes
 	v1 := r.Group("/api/v1")
 	{
@@ -50,29 +134,195 @@ func SetupRouter(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine
 		v1.POST("/auth/logout", authHandler.Logout)
 		v1.POST("/auth/register", userHandler.Create)
 		v1.POST("/auth/forgot-password", userHandler.ForgotPassword)
+		v1.GET("/auth/email/confirm", emailChangeHandler.CheckConfirm)
+		v1.POST("/auth/email/confirm", emailChangeHandler.Confirm)
+		v1.GET("/auth/email/revert", emailChangeHandler.CheckRevert)
+		v1.POST("/auth/email/revert", emailChangeHandler.Revert)
+		v1.GET("/invitations/accept", invitationHandler.Show)
+		v1.POST("/invitations/accept", invitationHandler.Accept)
+		v1.POST("/auth/reauthenticate", authMiddleware, middlewares.DenyImpersonation(), authHandler.Reauthenticate)
 
-		v1.POST("/roles", roleHandler.CreateRole)
-		v1.GET("/roles", roleHandler.GetAllRoles)
-		v1.GET("/roles/:id", roleHandler.GetRoleByID)
-		v1.PUT("/roles/:id", roleHandler.UpdateRole)
-		v1.DELETE("/roles/:id", roleHandler.DeleteRole)
-
+		handlers.RegisterCrudRoutes(v1, "/roles", roleService,
+			authMiddleware, middlewares.RequirePermission(userService, service.PermissionManageRoles))
 		v1.GET("/roles/:id/permissions", roleHandler.GetPermissionsByRoleID)
 		v1.GET("/users/:id/permissions", userHandler.GetPermissionsByUserID)
+
+		// Signed download links
+		v1.GET("/files/:id/download", fileHandler.Download)
+		v1.GET("/storage/*key", fileHandler.ServeSigned)
+		v1.GET("/data-exports/:id/download", privacyHandler.DownloadSigned)
+
+		// OpenID Connect; userinfo is the only route that takes the tokens of relying parties
+		v1.GET("/oauth/jwks", oidcHandler.JWKS)
+		v1.POST("/oauth/token", oidcHandler.Token)
+		userInfoAuth := middlewares.UserInfoAuth(oidcService, accountService, authMiddleware)
+		v1.GET("/oauth/userinfo", userInfoAuth, oidcHandler.UserInfo)
+		v1.POST("/oauth/userinfo", userInfoAuth, oidcHandler.UserInfo)
+	}
+
+	registerOAuthRoutes(v1, oidcHandler, userService, authMiddleware, presenceMiddleware, recentAuth)
+
+	// Routes about the authenticated user
+	me := v1.Group("/me")
+	me.Use(authMiddleware, presenceMiddleware)
+	{
+		me.POST("/heartbeat", presenceHandler.Heartbeat)
+		me.GET("/logins", authHandler.LoginHistory)
+		me.GET("/profile", profileHandler.Get)
+		me.PUT("/profile", profileHandler.Update)
+		me.PUT("/avatar", profileHandler.UploadAvatar)
+		me.DELETE("/avatar", profileHandler.DeleteAvatar)
+		me.POST("/deletion", middlewares.DenyImpersonation(), recentAuth, accountHandler.ScheduleDeletion)
+		me.POST("/data-export", middlewares.DenyImpersonation(), privacyHandler.RequestExport)
+		me.GET("/data-exports", privacyHandler.ListExports)
+		me.GET("/data-exports/:id/download", middlewares.DenyImpersonation(), privacyHandler.DownloadExport)
+		me.GET("/email", emailChangeHandler.Pending)
+		me.PUT("/email", middlewares.DenyImpersonation(), recentAuth, emailChangeHandler.RequestChange)
+		me.DELETE("/email", middlewares.DenyImpersonation(), emailChangeHandler.Cancel)
+		me.GET("/consents", oidcHandler.ListConsents)
+		me.DELETE("/consents/:clientId", middlewares.DenyImpersonation(), oidcHandler.RevokeConsent)
+	}
+
+	// Real-time events (WebSocket with an SSE fallback)
+	// Browsers open streams with a single-use ticket, since they cannot send headers there
+	stream := v1.Group("/realtime")
+	{
+		stream.POST("/ticket", authMiddleware, realtimeHandler.Ticket)
+
+		streamAuth := middlewares.StreamAuth(streamTicketService, accountService, authMiddleware)
+		stream.GET("/ws", streamAuth, presenceMiddleware, realtimeHandler.WebSocket)
+		stream.GET("/sse", streamAuth, presenceMiddleware, realtimeHandler.SSE)
+	}
+
+	// Admin routes
+	invitations := v1.Group("/invitations")
+	invitations.Use(authMiddleware, presenceMiddleware, middlewares.DenyImpersonation(),
+		middlewares.RequirePermission(userService, service.PermissionInviteUsers))
+	{
+		invitations.POST("", invitationHandler.Create)
+		invitations.GET("", invitationHandler.List)
+		invitations.POST("/:id/resend", invitationHandler.Resend)
+		invitations.DELETE("/:id", invitationHandler.Revoke)
+	}
+
+	admin := v1.Group("/admin")
+	admin.Use(authMiddleware, presenceMiddleware, middlewares.DenyImpersonation())
+	{
+		admin.POST("/impersonate/:userId",
+			middlewares.RequirePermission(userService, service.PermissionImpersonateUsers),
+			adminHandler.Impersonate)
+		admin.PUT("/users/:userId/status",
+			middlewares.RequirePermission(userService, service.PermissionManageAccountStatus),
+			accountHandler.ChangeStatus)
+		admin.POST("/users/:userId/erase",
+			middlewares.RequirePermission(userService, service.PermissionEraseUsers), recentAuth,
+			privacyHandler.Erase)
+	}
+
+	// Uploads of the authenticated user
+	uploads := v1.Group("/files")
+	uploads.Use(authMiddleware, presenceMiddleware)
+	{
+		uploads.POST("", middlewares.DenyImpersonation(), fileHandler.Upload)
+		uploads.GET("", fileHandler.List)
+		uploads.GET("/:id", fileHandler.Get)
+		uploads.DELETE("/:id", middlewares.DenyImpersonation(), fileHandler.Delete)
+	}
+
+	// Custom profile fields: everyone can read the schema, admins define it
+	profileFields := v1.Group("/profile-fields")
+	profileFields.Use(authMiddleware, presenceMiddleware)
+	{
+		profileFields.GET("", profileHandler.ListFields)
+
+		manageFields := middlewares.RequirePermission(userService, service.PermissionManageProfileFields)
+		profileFields.POST("", middlewares.DenyImpersonation(), manageFields, profileHandler.CreateField)
+		profileFields.PUT("/:id", middlewares.DenyImpersonation(), manageFields, profileHandler.UpdateField)
+		profileFields.DELETE("/:id", middlewares.DenyImpersonation(), manageFields, profileHandler.DeleteField)
 	}
 
 	// Protected routes
 	auth := v1.Group("/users")
 
-	auth.Use(middlewares.AuthMiddleware(cfg)) // e.g. checks valid JWT
+	auth.Use(authMiddleware, presenceMiddleware) // e.g. checks valid JWT
 	{
 		auth.GET("/:id", userHandler.GetByID)
 		auth.GET("/", userHandler.List)
 		auth.GET("/getuser", authHandler.GetAuthUser)
+		auth.GET("/online", presenceHandler.OnlineUsers)
+		auth.GET("/presence", presenceHandler.Presence)
 
 		auth.PUT("/:id", userHandler.Update)
-		auth.DELETE("/:id", userHandler.Delete)
+		auth.DELETE("/:id", middlewares.DenyImpersonation(), recentAuth, userHandler.Delete)
+		auth.POST("/:id/restore", middlewares.DenyImpersonation(),
+			middlewares.RequirePermission(userService, service.PermissionManageUsers), userHandler.Restore)
 	}
 
+	// cmd/gen adds generated resources above this line
+
 	return r
 }
+
+// sweepPresence periodically announces users whose presence expired without a logout
+func sweepPresence(ctx context.Context, authService service.AuthService, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := authService.ExpireStalePresence(ctx); err != nil {
+			log.Printf("presence sweep failed: %v", err)
+		}
+	}
+}
+
+// purgeDeletedAccounts periodically deletes accounts whose deletion grace period ended
+func purgeDeletedAccounts(ctx context.Context, accountService service.AccountService, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		purged, err := accountService.PurgeDueDeletions(ctx)
+		if err != nil {
+			log.Printf("account purge failed: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("purged %d accounts scheduled for deletion", purged)
+		}
+	}
+}
+
+// purgeExpiredExports periodically removes data export archives past their expiry
+func purgeExpiredExports(ctx context.Context, privacyService service.PrivacyService, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := privacyService.PurgeExpiredExports(ctx); err != nil {
+			log.Printf("data export purge failed: %v", err)
+		}
+	}
+}
+
+// registerOAuthRoutes adds the routes of the OpenID Connect provider that need a
+// first-party login. None of them accept impersonation tokens: an authorization code
+// issued to one would let support staff sign in to other applications as the user.
+func registerOAuthRoutes(v1 *gin.RouterGroup, oidcHandler *handlers.OIDCHandler, userService service.UserService,
+	authMiddleware, presenceMiddleware, recentAuth gin.HandlerFunc) {
+	oauth := v1.Group("/oauth")
+	oauth.Use(authMiddleware, middlewares.DenyImpersonation(), presenceMiddleware)
+	{
+		oauth.GET("/authorize", oidcHandler.Authorize)
+		oauth.POST("/authorize", oidcHandler.Authorize)
+
+		manageClients := middlewares.RequirePermission(userService, service.PermissionManageOAuthClients)
+		oauth.POST("/clients", manageClients, recentAuth, oidcHandler.CreateClient)
+		oauth.GET("/clients", manageClients, oidcHandler.ListClients)
+		oauth.DELETE("/clients/:id", manageClients, oidcHandler.DeleteClient)
+	}
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/handlers"
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/middlewares"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type activeAccounts struct{ service.AccountService }
+
+func (activeAccounts) CheckActive(context.Context, uint) error { return nil }
+
+type discardAudit struct{ service.AuditService }
+
+func (discardAudit) Record(context.Context, *models.AuditLog) error { return nil }
+
+// consentedOIDC hands out a code to anyone, as Authorize does once the user consented
+type consentedOIDC struct {
+	service.OIDCService
+	calls int
+}
+
+func (s *consentedOIDC) Authorize(context.Context, uint, int64, service.AuthorizeRequest, *bool) (*service.AuthorizeResult, error) {
+	s.calls++
+	return &service.AuthorizeResult{RedirectTo: "https://app.example.com/callback?code=x"}, nil
+}
+
+func TestOAuthRoutesDenyImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := i18n.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{JWTAccessSecret: "access-secret", Cookie: &config.CookieConfig{}}
+	oidc := &consentedOIDC{}
+	noop := func(c *gin.Context) { c.Next() }
+
+	r := gin.New()
+	registerOAuthRoutes(r.Group("/api/v1"), handlers.NewOIDCHandler(oidc), nil,
+		middlewares.AuthMiddleware(cfg, activeAccounts{}, discardAudit{}), noop, noop)
+
+	token := func(claims jwt.MapClaims) string {
+		claims["user_id"] = 2
+		claims["iat"] = time.Now().Unix()
+		claims["exp"] = time.Now().Add(time.Minute).Unix()
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.JWTAccessSecret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	authorize := func(method, token string) int {
+		req := httptest.NewRequest(method, "/api/v1/oauth/authorize?client_id=app&response_type=code", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// The user's own login reaches the provider
+	if code := authorize(http.MethodGet, token(jwt.MapClaims{})); code != http.StatusOK {
+		t.Fatalf("GET /oauth/authorize as the user: got %d, want 200", code)
+	}
+
+	// An "act as" token gets no authorization code, with or without consent
+	impersonation := token(jwt.MapClaims{"act": map[string]interface{}{"sub": 1}})
+	oidc.calls = 0
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if code := authorize(method, impersonation); code != http.StatusForbidden {
+			t.Errorf("%s /oauth/authorize while impersonating: got %d, want 403", method, code)
+		}
+	}
+	if oidc.calls != 0 {
+		t.Fatalf("the provider was asked for a code %d times while impersonating", oidc.calls)
+	}
+}
@@ -0,0 +1,37 @@
+package seed
+
+import (
+	"errors"
+
+	"golang-api-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrUserExists = errors.New("a user with this email already exists")
+
+// CreateAdmin seeds the base set, so the admin role exists, then creates an
+// active user with that role
+func CreateAdmin(db *gorm.DB, name, email, password string) (*models.User, error) {
+	if err := Run(db, SetBase); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		fixture := UserFixture{Name: name, Email: email, Password: password, Roles: []string{RoleAdmin}}
+		if err := tx.Where("email = ?", normalizeEmail(email)).First(&user).Error; err == nil {
+			return ErrUserExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := upsertUser(tx, fixture); err != nil {
+			return err
+		}
+		return tx.Preload("Roles").Where("email = ?", normalizeEmail(email)).First(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
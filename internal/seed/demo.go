@@ -0,0 +1,46 @@
+package seed
+
+import (
+	"fmt"
+	"strings"
+
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(SetDemo, demoEmployees)
+}
+
+var (
+	demoFirstNames = []string{"Ada", "Ben", "Chloe", "Dev", "Elena", "Farid", "Grace", "Hiro", "Ines", "Jonas"}
+	demoLastNames  = []string{"Smith", "Garcia", "Tanaka", "Okafor", "Novak"}
+)
+
+// demoEmployees adds enough Acme and Globex accounts to page through lists
+func demoEmployees(tx *gorm.DB) error {
+	// One hash for all of them; hashing dozens of passwords would make seeding slow
+	hash, err := utils.HashPassword("password")
+	if err != nil {
+		return err
+	}
+
+	var fixtures Fixtures
+	for i, last := range demoLastNames {
+		for j, first := range demoFirstNames {
+			organization := "Acme"
+			if (i+j)%3 == 0 {
+				organization = "Globex"
+			}
+			fixtures.Users = append(fixtures.Users, UserFixture{
+				Name:         first + " " + last,
+				Email:        fmt.Sprintf("%s.%s@%s.example", strings.ToLower(first), strings.ToLower(last), strings.ToLower(organization)),
+				PasswordHash: hash,
+				Organization: organization,
+				Roles:        []string{"user"},
+			})
+		}
+	}
+	return apply(tx, &fixtures)
+}
@@ -0,0 +1,127 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures is the content of a fixture file, in YAML or JSON:
+//
+//	permissions:
+//	  - name: users.invite
+//	roles:
+//	  - name: admin
+//	    permissions: ["*"]          # every permission
+//	users:
+//	  - name: Admin
+//	    email: admin@example.com
+//	    password: password          # or password_hash, a bcrypt hash
+//	    roles: [admin]
+//
+// Roles reference permissions and users reference roles by name; the referenced
+// records may come from any fixture of the run or already be in the database.
+type Fixtures struct {
+	Permissions []PermissionFixture `yaml:"permissions" json:"permissions"`
+	Roles       []RoleFixture       `yaml:"roles" json:"roles"`
+	Users       []UserFixture       `yaml:"users" json:"users"`
+}
+
+type PermissionFixture struct {
+	Name string `yaml:"name" json:"name"`
+}
+
+type RoleFixture struct {
+	Name        string   `yaml:"name" json:"name"`
+	Permissions []string `yaml:"permissions" json:"permissions"`
+}
+
+type UserFixture struct {
+	Name         string   `yaml:"name" json:"name"`
+	Email        string   `yaml:"email" json:"email"`
+	Password     string   `yaml:"password" json:"password"`           // hashed when the user is created
+	PasswordHash string   `yaml:"password_hash" json:"password_hash"` // used as is, e.g. a cheap hash for tests
+	Status       string   `yaml:"status" json:"status"`               // see models.UserStatus*, active by default
+	Organization string   `yaml:"organization" json:"organization"`
+	Roles        []string `yaml:"roles" json:"roles"`
+}
+
+func (f *Fixtures) merge(other *Fixtures) {
+	f.Permissions = append(f.Permissions, other.Permissions...)
+	f.Roles = append(f.Roles, other.Roles...)
+	f.Users = append(f.Users, other.Users...)
+}
+
+// LoadPath reads a fixture file, or every fixture file of a directory in name order
+func LoadPath(p string) (*Fixtures, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{p}
+	if info.IsDir() {
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		files = nil
+		for _, entry := range entries {
+			if !entry.IsDir() && isFixtureFile(entry.Name()) {
+				files = append(files, filepath.Join(p, entry.Name()))
+			}
+		}
+		sort.Strings(files)
+	}
+
+	var fixtures Fixtures
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parseFixtures(file, content)
+		if err != nil {
+			return nil, err
+		}
+		fixtures.merge(f)
+	}
+	return &fixtures, nil
+}
+
+func isFixtureFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// parseFixtures decodes a file by its extension, rejecting unknown keys so typos
+// don't get silently ignored
+func parseFixtures(name string, content []byte) (*Fixtures, error) {
+	var f Fixtures
+	var err error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(content))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+	default:
+		return nil, fmt.Errorf("seed: %s is not a .yaml, .yml or .json file", name)
+	}
+	if err != nil && err != io.EOF { // io.EOF: the file is empty
+		return nil, fmt.Errorf("seed: %s: %w", name, err)
+	}
+	return &f, nil
+}
@@ -0,0 +1,261 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Statuses a user fixture may have
+var userStatuses = map[string]bool{
+	models.UserStatusPending:         true,
+	models.UserStatusActive:          true,
+	models.UserStatusSuspended:       true,
+	models.UserStatusDeactivated:     true,
+	models.UserStatusPendingDeletion: true,
+}
+
+// apply upserts the fixtures, permissions first so roles and users can reference
+// records from any file
+func apply(tx *gorm.DB, f *Fixtures) error {
+	for _, p := range f.Permissions {
+		if err := upsertPermission(tx, p); err != nil {
+			return err
+		}
+	}
+	for _, r := range f.Roles {
+		if err := upsertRole(tx, r); err != nil {
+			return err
+		}
+	}
+	for _, u := range f.Users {
+		if err := upsertUser(tx, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsertPermission creates the permission, or restores it when it was soft-deleted;
+// permission names stay unique across the trash
+func upsertPermission(tx *gorm.DB, p PermissionFixture) error {
+	if p.Name == "" {
+		return errors.New("seed: a permission has no name")
+	}
+
+	var permission models.Permission
+	err := tx.Unscoped().Where("name = ?", p.Name).First(&permission).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		permission = models.Permission{Name: p.Name}
+		if err := tx.Create(&permission).Error; err != nil {
+			return err
+		}
+		log.Printf("seed: created permission %s", p.Name)
+	case err != nil:
+		return err
+	case permission.DeletedAt.Valid:
+		if err := tx.Unscoped().Model(&permission).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		log.Printf("seed: restored permission %s", p.Name)
+	}
+	return nil
+}
+
+// upsertRole creates the role if there is no live one by that name and grants it the
+// permissions it is missing
+func upsertRole(tx *gorm.DB, r RoleFixture) error {
+	if r.Name == "" {
+		return errors.New("seed: a role has no name")
+	}
+
+	// 1. Find or create the role
+	var role models.Role
+	err := tx.Preload("Permissions").Where("name = ?", r.Name).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		role = models.Role{Name: r.Name}
+		if err := tx.Omit(clause.Associations).Create(&role).Error; err != nil {
+			return err
+		}
+		log.Printf("seed: created role %s", r.Name)
+	} else if err != nil {
+		return err
+	}
+
+	// 2. Grant the missing permissions
+	permissions, err := findPermissions(tx, r.Permissions)
+	if err != nil {
+		return fmt.Errorf("seed: role %s: %w", r.Name, err)
+	}
+	granted := map[uint]bool{}
+	for _, p := range role.Permissions {
+		granted[p.ID] = true
+	}
+	var missing []models.Permission
+	for _, p := range permissions {
+		if !granted[p.ID] {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if err := tx.Model(&role).Association("Permissions").Append(missing); err != nil {
+		return err
+	}
+	log.Printf("seed: granted %d permission(s) to role %s", len(missing), r.Name)
+	return nil
+}
+
+// findPermissions loads permissions by name, "*" standing for all of them
+func findPermissions(tx *gorm.DB, names []string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+	for _, name := range names {
+		if name == "*" {
+			err := tx.Find(&permissions).Error
+			return permissions, err
+		}
+	}
+
+	if err := tx.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	if name, ok := firstMissing(names, len(permissions), func(i int) string { return permissions[i].Name }); !ok {
+		return nil, fmt.Errorf("unknown permission %q", name)
+	}
+	return permissions, nil
+}
+
+// upsertUser creates the user if there is no live one with that email, otherwise
+// updates the fields the fixture sets; either way it adds the missing roles. The
+// password of an existing user is never changed.
+func upsertUser(tx *gorm.DB, u UserFixture) error {
+	email := normalizeEmail(u.Email)
+	if email == "" {
+		return errors.New("seed: a user has no email")
+	}
+	if u.Status != "" && !userStatuses[u.Status] {
+		return fmt.Errorf("seed: user %s has unknown status %q", email, u.Status)
+	}
+	roles, err := findRoles(tx, u.Roles)
+	if err != nil {
+		return fmt.Errorf("seed: user %s: %w", email, err)
+	}
+
+	// 1. Create the user, or bring the fixture's fields up to date
+	var user models.User
+	err = tx.Preload("Roles").Where("email = ?", email).First(&user).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if err := createUser(tx, &user, email, u); err != nil {
+			return err
+		}
+		log.Printf("seed: created user %s", email)
+	case err != nil:
+		return err
+	default:
+		updates := map[string]interface{}{}
+		if u.Name != "" && u.Name != user.Name {
+			updates["name"] = u.Name
+		}
+		if u.Organization != "" && u.Organization != user.Organization {
+			updates["organization"] = u.Organization
+		}
+		if u.Status != "" && u.Status != user.Status {
+			updates["status"] = u.Status
+			updates["status_changed_at"] = time.Now()
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&user).Updates(updates).Error; err != nil {
+				return err
+			}
+			log.Printf("seed: updated user %s", email)
+		}
+	}
+
+	// 2. Add the missing roles
+	assigned := map[uint]bool{}
+	for _, r := range user.Roles {
+		assigned[r.ID] = true
+	}
+	var missing []models.Role
+	for _, r := range roles {
+		if !assigned[r.ID] {
+			missing = append(missing, r)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return tx.Model(&user).Association("Roles").Append(missing)
+}
+
+func createUser(tx *gorm.DB, user *models.User, email string, u UserFixture) error {
+	hash := u.PasswordHash
+	if hash == "" {
+		if u.Password == "" {
+			return fmt.Errorf("seed: user %s needs a password or password_hash", email)
+		}
+		var err error
+		if hash, err = utils.HashPassword(u.Password); err != nil {
+			return err
+		}
+	}
+
+	*user = models.User{
+		Name:         u.Name,
+		Email:        email,
+		Password:     hash,
+		Status:       u.Status,
+		Organization: u.Organization,
+	}
+	if user.Status == "" {
+		user.Status = models.UserStatusActive
+	}
+	return tx.Omit(clause.Associations).Create(user).Error
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// findRoles loads live roles by name
+func findRoles(tx *gorm.DB, names []string) ([]models.Role, error) {
+	var roles []models.Role
+	if len(names) == 0 {
+		return roles, nil
+	}
+	if err := tx.Where("name IN ?", names).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	if name, ok := firstMissing(names, len(roles), func(i int) string { return roles[i].Name }); !ok {
+		return nil, fmt.Errorf("unknown role %q", name)
+	}
+	return roles, nil
+}
+
+// firstMissing returns the first of names not among the n found ones
+func firstMissing(names []string, n int, found func(i int) string) (string, bool) {
+	have := map[string]bool{}
+	for i := 0; i < n; i++ {
+		have[found(i)] = true
+	}
+	for _, name := range names {
+		if !have[name] {
+			return name, false
+		}
+	}
+	return "", true
+}
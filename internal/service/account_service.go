@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/repository"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// PermissionManageAccountStatus is required to suspend, deactivate, reactivate or delete accounts
+const PermissionManageAccountStatus = "users.manage_status"
+
+var (
+	ErrAccountPending         = errors.New("account is not activated yet")
+	ErrAccountSuspended       = errors.New("account is suspended")
+	ErrAccountDeactivated     = errors.New("account is deactivated")
+	ErrAccountPendingDeletion = errors.New("account is scheduled for deletion")
+	ErrAccountNotFound        = errors.New("account no longer exists")
+
+	ErrInvalidStatusTransition = errors.New("invalid account status transition")
+	ErrCannotChangeOwnStatus   = errors.New("you cannot change the status of your own account")
+)
+
+// statusTransitions lists the states an admin may move an account to from each state
+var statusTransitions = map[string][]string{
+	models.UserStatusPending:         {models.UserStatusActive, models.UserStatusSuspended, models.UserStatusDeactivated, models.UserStatusPendingDeletion},
+	models.UserStatusActive:          {models.UserStatusSuspended, models.UserStatusDeactivated, models.UserStatusPendingDeletion},
+	models.UserStatusSuspended:       {models.UserStatusActive, models.UserStatusDeactivated, models.UserStatusPendingDeletion},
+	models.UserStatusDeactivated:     {models.UserStatusActive, models.UserStatusPendingDeletion},
+	models.UserStatusPendingDeletion: {models.UserStatusActive, models.UserStatusSuspended, models.UserStatusDeactivated},
+}
+
+// AccountService manages the account lifecycle (see models.UserStatus*)
+type AccountService interface {
+	// ChangeStatus is the admin transition; pending_deletion schedules the deletion after the grace period
+	ChangeStatus(ctx context.Context, actorID, userID uint, status, reason string) (*models.User, error)
+
+	// ScheduleOwnDeletion lets users close their account; logging in again cancels it.
+	// Deletions scheduled by an admin through ChangeStatus can only be undone by an admin.
+	ScheduleOwnDeletion(ctx context.Context, userID uint) (*models.User, error)
+
+	// CheckActive returns nil if the account may use its tokens, otherwise the matching ErrAccount* error.
+	// The status is cached briefly in Redis because it runs on every authenticated request.
+	CheckActive(ctx context.Context, userID uint) error
+
+	// PurgeDueDeletions erases the accounts whose grace period ended
+	PurgeDueDeletions(ctx context.Context) (int, error)
+}
+
+type accountService struct {
+	userRepo repository.UserRepository
+	audit    AuditService
+	privacy  PrivacyService
+	rdb      *redis.Client
+	cfg      *config.Config
+}
+
+func NewAccountService(repo repository.UserRepository, audit AuditService, privacy PrivacyService, rdb *redis.Client, cfg *config.Config) AccountService {
+	return &accountService{
+		userRepo: repo,
+		audit:    audit,
+		privacy:  privacy,
+		rdb:      rdb,
+		cfg:      cfg,
+	}
+}
+
+func (s *accountService) ChangeStatus(ctx context.Context, actorID, userID uint, status, reason string) (*models.User, error) {
+	if actorID == userID {
+		return nil, ErrCannotChangeOwnStatus
+	}
+
+	// 1. Check the transition is allowed
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransition(currentStatus(user), status) {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	// 2. Apply it
+	if err := s.setStatus(ctx, actorID, user, status, reason); err != nil {
+		return nil, err
+	}
+
+	// 3. Keep a trail of who changed what and why
+	s.record(ctx, actorID, user.ID, "account."+status, reason)
+	return user, nil
+}
+
+func (s *accountService) ScheduleOwnDeletion(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setStatus(ctx, userID, user, models.UserStatusPendingDeletion, "requested by user"); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, userID, userID, "account."+models.UserStatusPendingDeletion, "requested by user")
+	return user, nil
+}
+
+func (s *accountService) CheckActive(ctx context.Context, userID uint) error {
+	key := accountStatusKey(userID)
+
+	status, err := s.rdb.Get(ctx, key).Result()
+	if err == redis.Nil {
+		user, err := s.userRepo.GetByID(ctx, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAccountNotFound
+		} else if err != nil {
+			return err
+		}
+		status = currentStatus(user)
+		if err := s.rdb.Set(ctx, key, status, s.cfg.AccountStatusCacheTTL).Err(); err != nil {
+			log.Printf("failed to cache status of user %d: %v", userID, err)
+		}
+	} else if err != nil {
+		return err
+	}
+
+	return accountStatusError(status)
+}
+
+func (s *accountService) PurgeDueDeletions(ctx context.Context) (int, error) {
+	// Every instance runs the purge loop; only one of them needs to do the work
+	acquired, err := s.rdb.SetNX(ctx, "account:purge:lock", 1, time.Minute).Result()
+	if err != nil || !acquired {
+		return 0, err
+	}
+
+	users, err := s.userRepo.GetUsersDueForDeletion(ctx, time.Now(), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	// A deletion request is an erasure request, so personal data goes too. One user
+	// that cannot be erased must not hold up the others; it is retried next time.
+	purged := 0
+	var errs []error
+	for _, user := range users {
+		if err := s.privacy.EraseUser(ctx, 0, user.ID, "scheduled deletion, grace period ended"); err != nil {
+			log.Printf("failed to erase user %d: %v", user.ID, err)
+			errs = append(errs, fmt.Errorf("failed to erase user %d: %w", user.ID, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
+
+// setStatus applies a transition made by actorID, who is remembered as the requester of a deletion
+func (s *accountService) setStatus(ctx context.Context, actorID uint, user *models.User, status, reason string) error {
+	var deletionAt *time.Time
+	var deletionBy uint
+	if status == models.UserStatusPendingDeletion {
+		at := time.Now().AddDate(0, 0, s.cfg.AccountDeletionGraceDays)
+		deletionAt = &at
+		deletionBy = actorID
+	}
+	if err := s.userRepo.UpdateStatus(ctx, user.ID, status, reason, deletionAt, deletionBy); err != nil {
+		return err
+	}
+	invalidateAccountStatus(ctx, s.rdb, user.ID)
+
+	now := time.Now()
+	user.Status = status
+	user.StatusReason = reason
+	user.StatusChangedAt = &now
+	user.DeletionScheduledAt = deletionAt
+	user.DeletionRequestedBy = deletionBy
+	return nil
+}
+
+func (s *accountService) record(ctx context.Context, actorID, userID uint, action, reason string) {
+	entry := &models.AuditLog{ActorID: actorID, UserID: userID, Action: action, Details: reason}
+	if err := s.audit.Record(ctx, entry); err != nil {
+		log.Printf("failed to audit %s for user %d: %v", action, userID, err)
+	}
+}
+
+func canTransition(from, to string) bool {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// currentStatus treats rows created before lifecycle states existed as active
+func currentStatus(user *models.User) string {
+	if user.Status == "" {
+		return models.UserStatusActive
+	}
+	return user.Status
+}
+
+// accountStatusError maps a lifecycle state to the error returned when the account tries to authenticate
+func accountStatusError(status string) error {
+	switch status {
+	case models.UserStatusActive, "":
+		return nil
+	case models.UserStatusPending:
+		return ErrAccountPending
+	case models.UserStatusSuspended:
+		return ErrAccountSuspended
+	case models.UserStatusDeactivated:
+		return ErrAccountDeactivated
+	case models.UserStatusPendingDeletion:
+		return ErrAccountPendingDeletion
+	default:
+		return fmt.Errorf("unknown account status %q", status)
+	}
+}
+
+func accountStatusKey(userID uint) string {
+	return fmt.Sprintf("account:%d:status", userID)
+}
+
+func invalidateAccountStatus(ctx context.Context, rdb *redis.Client, userID uint) {
+	if err := rdb.Del(ctx, accountStatusKey(userID)).Err(); err != nil {
+		log.Printf("failed to invalidate cached status of user %d: %v", userID, err)
+	}
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/repository"
+)
+
+type AuditService interface {
+	Record(ctx context.Context, entry *models.AuditLog) error
+	GetByUserID(ctx context.Context, userID uint) ([]models.AuditLog, error)
+}
+
+type auditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) AuditService {
+	return &auditService{repo: repo}
+}
+
+func (s *auditService) Record(ctx context.Context, entry *models.AuditLog) error {
+	// Keep oversized client input from failing the insert
+	if len(entry.UserAgent) > 255 {
+		entry.UserAgent = entry.UserAgent[:255]
+	}
+	if len(entry.Path) > 255 {
+		entry.Path = entry.Path[:255]
+	}
+	return s.repo.CreateAuditLog(ctx, entry)
+}
+
+func (s *auditService) GetByUserID(ctx context.Context, userID uint) ([]models.AuditLog, error) {
+	return s.repo.GetAuditLogsByUserID(ctx, userID)
+}
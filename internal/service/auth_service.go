@@ -2,106 +2,185 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
 	"time"
 
 	"golang-api-template/internal/config"
 	"golang-api-template/internal/models"
+	"golang-api-template/internal/realtime"
 	"golang-api-template/internal/repository"
+	"golang-api-template/internal/utils"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/redis/go-redis/v9"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type AuthService interface {
-	Login(email, password string) (string, string, *models.User, error)
-	RefreshToken(refreshToken string) (string, string, error)
-	Logout(refreshToken string) error
+	Login(ctx context.Context, email, password string) (string, string, *models.User, error)
+	RefreshToken(ctx context.Context, refreshToken string) (string, string, error)
+	Logout(ctx context.Context, refreshToken string) error
 	GetAuthUser(ctx context.Context) (*models.User, error)
-	TrackUserLogin(userID uint) error
-	TrackUserLogout(userID uint) error
-	IsUserOnline(userID uint) (bool, error)
+	TrackUserLogin(ctx context.Context, userID uint) error
+	TrackUserLogout(ctx context.Context, userID uint) error
+	IsUserOnline(ctx context.Context, userID uint) (bool, error)
+	AreUsersOnline(ctx context.Context, userIDs []uint) (map[uint]bool, error)
+	GetOnlineUserIDs(ctx context.Context) ([]uint, error)
+	Heartbeat(ctx context.Context, userID uint) error
+	ExpireStalePresence(ctx context.Context) error
+
+	// Reauthenticate returns a short-lived "elevated" access token with a fresh auth_time
+	Reauthenticate(ctx context.Context, userID uint, password, mfaCode string) (string, error)
+
+	// Impersonate issues a short-lived access token for targetID that carries the actor in an `act` claim
+	Impersonate(ctx context.Context, actorID, targetID uint) (string, error)
 }
 
+// PermissionImpersonateUsers is required to call the impersonation endpoint
+const PermissionImpersonateUsers = "users.impersonate"
+
+// Authentication method references (RFC 8176) carried in the `amr` claim
+const (
+	AMRPassword = "pwd"
+	AMROneTime  = "otp"
+)
+
+var ErrMFANotEnabled = errors.New("multi-factor authentication is not enabled for this account")
+
+// AuthContextClaims are the claims describing when and how the user authenticated
+func AuthContextClaims(authTime time.Time, methods ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"auth_time": authTime.Unix(),
+		"amr":       methods,
+	}
+}
+
+// authContextFrom copies auth_time and amr from existing claims
+func authContextFrom(claims map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, key := range []string{"auth_time", "amr"} {
+		if val, ok := claims[key]; ok {
+			out[key] = val
+		}
+	}
+	return out
+}
+
+var (
+	ErrCannotImpersonateSelf       = errors.New("you cannot impersonate yourself")
+	ErrCannotImpersonatePrivileged = errors.New("users who can impersonate others cannot be impersonated")
+)
+
 type authService struct {
-	userRepo repository.UserRepository
-	rdb      *redis.Client
-	cfg      *config.Config
+	userRepo       repository.UserRepository
+	rdb            *redis.Client
+	cfg            *config.Config
+	publisher      EventPublisher
+	authenticators []Authenticator
 }
 
-func NewAuthService(repo repository.UserRepository, rdb *redis.Client, cfg *config.Config) AuthService {
+// NewAuthService creates the auth service. Authenticators are tried in order on login;
+// without any, users are checked against their bcrypt password in the database.
+// Presence changes are pushed through publisher, which may be nil.
+func NewAuthService(repo repository.UserRepository, rdb *redis.Client, cfg *config.Config, publisher EventPublisher, authenticators ...Authenticator) AuthService {
+	if len(authenticators) == 0 {
+		authenticators = []Authenticator{NewDBAuthenticator(repo)}
+	}
+	if publisher == nil {
+		publisher = noopPublisher{}
+	}
 	return &authService{
-		userRepo: repo,
-		rdb:      rdb,
-		cfg:      cfg,
+		userRepo:       repo,
+		rdb:            rdb,
+		cfg:            cfg,
+		publisher:      publisher,
+		authenticators: authenticators,
 	}
 }
 
 // ----------------------------------------------------------
 // LOGIN
 // ----------------------------------------------------------
-func (s *authService) Login(email, password string) (string, string, *models.User, error) {
-	// 1. Find user by email
-	user, err := s.userRepo.GetUserByEmail(email)
+func (s *authService) Login(ctx context.Context, email, password string) (string, string, *models.User, error) {
+	// 1. Verify credentials against each identity source in turn
+	user, err := s.authenticate(ctx, email, password)
 	if err != nil {
-		return "", "", nil, fmt.Errorf("invalid credentials")
+		return "", "", nil, err
 	}
 
-	// 2. Compare password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", "", nil, fmt.Errorf("invalid credentials")
+	// 2. Only active accounts may log in; logging in cancels a scheduled deletion
+	if err := s.ensureCanLogin(ctx, user); err != nil {
+		return "", "", nil, err
 	}
 
-	// 3. Create access token
-	accessToken, err := s.createToken(user.ID, s.cfg.JWTAccessSecret, time.Minute*time.Duration(s.cfg.AccessTokenExpireMin))
+	// 3. Create access & refresh tokens that remember when and how the user authenticated
+	accessToken, refreshToken, err := s.IssueTokens(ctx, user.ID, AuthContextClaims(time.Now(), AMRPassword))
 	if err != nil {
 		return "", "", nil, err
 	}
 
-	// 4. Create refresh token
-	refreshToken, err := s.createToken(user.ID, s.cfg.JWTRefreshSecret, time.Hour*time.Duration(s.cfg.RefreshTokenExpireHrs))
-	if err != nil {
-		return "", "", nil, err
+	return accessToken, refreshToken, user, nil
+}
+
+// authenticate returns the user from the first authenticator that accepts the credentials.
+// Backend failures (e.g. directory unreachable) are logged and never leak to the client.
+func (s *authService) authenticate(ctx context.Context, login, password string) (*models.User, error) {
+	for _, a := range s.authenticators {
+		user, err := a.Authenticate(ctx, login, password)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			log.Printf("%s authenticator error: %v", a.Name(), err)
+		}
 	}
+	return nil, ErrInvalidCredentials
+}
 
-	// 5. Store the refresh token in Redis
-	ctx := context.Background()
-	// We store the token as key -> userID, so we can reference it later
-	err = s.rdb.Set(ctx, refreshToken, user.ID, time.Hour*time.Duration(s.cfg.RefreshTokenExpireHrs)).Err()
-	if err != nil {
-		return "", "", nil, fmt.Errorf("failed to store refresh token: %w", err)
+// ensureCanLogin rejects inactive accounts. A user who scheduled their account for
+// deletion gets it back by logging in before the grace period ends; a deletion an
+// admin scheduled stands.
+func (s *authService) ensureCanLogin(ctx context.Context, user *models.User) error {
+	if currentStatus(user) != models.UserStatusPendingDeletion || user.DeletionRequestedBy != user.ID {
+		return accountStatusError(currentStatus(user))
 	}
 
-	return accessToken, refreshToken, user, nil
+	if err := s.userRepo.UpdateStatus(ctx, user.ID, models.UserStatusActive, "deletion cancelled by login", nil, 0); err != nil {
+		return err
+	}
+	invalidateAccountStatus(ctx, s.rdb, user.ID)
+	user.Status = models.UserStatusActive
+	user.DeletionScheduledAt = nil
+	user.DeletionRequestedBy = 0
+	return nil
 }
 
 // ----------------------------------------------------------
 // REFRESH TOKEN
 // ----------------------------------------------------------
-func (s *authService) RefreshToken(refreshToken string) (string, string, error) {
-	// 1. Validate refresh token signature
-	claims, err := s.validateToken(refreshToken, s.cfg.JWTRefreshSecret)
+func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (string, string, error) {
+	// 1. Validate refresh token signature & check it is still in Redis
+	userID, claims, err := s.parseRefreshToken(ctx, refreshToken)
 	if err != nil {
-		return "", "", fmt.Errorf("invalid refresh token")
-	}
-
-	userID, ok := claims["user_id"].(float64)
-	if !ok {
-		return "", "", fmt.Errorf("invalid token claims")
+		return "", "", err
 	}
 
-	// 2. Check if refresh token is in Redis
-	ctx := context.Background()
-	val, err := s.rdb.Get(ctx, refreshToken).Result()
-	if err == redis.Nil || val == "" {
-		return "", "", fmt.Errorf("refresh token not found or expired")
+	// 2. Sessions of suspended, deactivated or deleted accounts end here
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", "", ErrAccountNotFound
 	} else if err != nil {
 		return "", "", err
 	}
+	if err := accountStatusError(currentStatus(user)); err != nil {
+		return "", "", err
+	}
 
-	// 3. Create new access token
-	accessToken, err := s.createToken(uint(userID), s.cfg.JWTAccessSecret, time.Minute*time.Duration(s.cfg.AccessTokenExpireMin))
+	// 3. Create new access token, keeping the original authentication time
+	accessToken, err := s.IssueAccessToken(userID, authContextFrom(claims))
 	if err != nil {
 		return "", "", err
 	}
@@ -116,22 +195,170 @@ func (s *authService) RefreshToken(refreshToken string) (string, string, error)
 // ----------------------------------------------------------
 // LOGOUT
 // ----------------------------------------------------------
-func (s *authService) Logout(refreshToken string) error {
+func (s *authService) Logout(ctx context.Context, refreshToken string) error {
+	// Find out who is logging out before the token is gone
+	userID, validErr := s.ValidateRefreshToken(ctx, refreshToken)
+
 	// remove the refresh token from Redis so it can’t be used
-	ctx := context.Background()
-	err := s.rdb.Del(ctx, refreshToken).Err()
-	return err
+	if err := s.rdb.Del(ctx, refreshToken).Err(); err != nil {
+		return err
+	}
+
+	if validErr == nil {
+		s.rdb.SRem(ctx, userRefreshTokensKey(userID), refreshToken)
+		return s.TrackUserLogout(ctx, userID)
+	}
+	return nil
+}
+
+// ----------------------------------------------------------
+// TOKEN ISSUING
+// ----------------------------------------------------------
+
+// IssueTokens creates an access/refresh token pair and stores the refresh token in Redis.
+// Extra claims are added to the access token; auth_time and amr are also kept in the
+// refresh token so refreshed access tokens still tell when the user really authenticated.
+func (s *authService) IssueTokens(ctx context.Context, userID uint, extra map[string]interface{}) (string, string, error) {
+	accessToken, err := s.IssueAccessToken(userID, extra)
+	if err != nil {
+		return "", "", err
+	}
+
+	// A random jti keeps refresh tokens unique even when issued in the same second
+	jti, err := utils.RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	refreshClaims := authContextFrom(extra)
+	refreshClaims["jti"] = jti
+
+	refreshExp := time.Hour * time.Duration(s.cfg.RefreshTokenExpireHrs)
+	refreshToken, err := s.createToken(userID, s.cfg.JWTRefreshSecret, refreshExp, refreshClaims)
+	if err != nil {
+		return "", "", err
+	}
+
+	// We store the token as key -> userID, so we can reference it later, and index it
+	// per user so all of a user's sessions can be listed and revoked
+	indexKey := userRefreshTokensKey(userID)
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, refreshToken, userID, refreshExp)
+	pipe.SAdd(ctx, indexKey, refreshToken)
+	pipe.Expire(ctx, indexKey, refreshExp)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// IssueAccessToken creates a short-lived access token
+func (s *authService) IssueAccessToken(userID uint, extra map[string]interface{}) (string, error) {
+	return s.createToken(userID, s.cfg.JWTAccessSecret, time.Minute*time.Duration(s.cfg.AccessTokenExpireMin), extra)
+}
+
+// ValidateRefreshToken checks the signature of a refresh token and that it has not been revoked
+func (s *authService) ValidateRefreshToken(ctx context.Context, refreshToken string) (uint, error) {
+	userID, _, err := s.parseRefreshToken(ctx, refreshToken)
+	return userID, err
+}
+
+func (s *authService) parseRefreshToken(ctx context.Context, refreshToken string) (uint, jwt.MapClaims, error) {
+	claims, err := s.validateToken(refreshToken, s.cfg.JWTRefreshSecret)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid refresh token")
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, nil, fmt.Errorf("invalid token claims")
+	}
+
+	val, err := s.rdb.Get(ctx, refreshToken).Result()
+	if err == redis.Nil || val == "" {
+		return 0, nil, fmt.Errorf("refresh token not found or expired")
+	} else if err != nil {
+		return 0, nil, err
+	}
+
+	return uint(userID), claims, nil
+}
+
+// ----------------------------------------------------------
+// STEP-UP RE-AUTHENTICATION
+// ----------------------------------------------------------
+
+// Reauthenticate confirms the identity of an already logged-in user with their password
+// (or an MFA code) and returns a short-lived access token with a fresh auth_time.
+func (s *authService) Reauthenticate(ctx context.Context, userID uint, password, mfaCode string) (string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("user not found")
+	}
+
+	var method string
+	switch {
+	case password != "":
+		authenticated, err := s.authenticate(ctx, user.Email, password)
+		if err != nil || authenticated.ID != user.ID {
+			return "", ErrInvalidCredentials
+		}
+		method = AMRPassword
+	case mfaCode != "":
+		// There is no second factor enrolment yet, so no code can be valid
+		return "", ErrMFANotEnabled
+	default:
+		return "", ErrInvalidCredentials
+	}
+
+	exp := time.Minute * time.Duration(s.cfg.StepUpTokenExpireMin)
+	return s.createToken(user.ID, s.cfg.JWTAccessSecret, exp, AuthContextClaims(time.Now(), method))
+}
+
+// ----------------------------------------------------------
+// IMPERSONATION
+// ----------------------------------------------------------
+func (s *authService) Impersonate(ctx context.Context, actorID, targetID uint) (string, error) {
+	if actorID == targetID {
+		return "", ErrCannotImpersonateSelf
+	}
+
+	target, err := s.userRepo.GetByID(ctx, targetID)
+	if err != nil {
+		return "", fmt.Errorf("user not found")
+	}
+
+	// Acting as another support user would let staff borrow each other's identity
+	permissions, err := s.userRepo.GetPermissionsByUserID(ctx, target.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, perm := range permissions {
+		if perm.Name == PermissionImpersonateUsers {
+			return "", ErrCannotImpersonatePrivileged
+		}
+	}
+
+	// No refresh token is issued, so the session ends when this token expires (RFC 8693 `act` claim)
+	exp := time.Minute * time.Duration(s.cfg.ImpersonationTokenExpireMin)
+	return s.createToken(target.ID, s.cfg.JWTAccessSecret, exp, map[string]interface{}{
+		"act": map[string]interface{}{"sub": actorID},
+	})
 }
 
 // ----------------------------------------------------------
 // JWT HELPERS
 // ----------------------------------------------------------
 
-func (s *authService) createToken(userID uint, secret string, exp time.Duration) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(exp).Unix(),
+func (s *authService) createToken(userID uint, secret string, exp time.Duration, extra map[string]interface{}) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{}
+	for k, v := range extra {
+		claims[k] = v
 	}
+	claims["user_id"] = userID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(exp).Unix()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
@@ -157,13 +384,13 @@ func (s *authService) validateToken(tokenStr, secret string) (jwt.MapClaims, err
 // GET AUTHENTICATED USER
 func (s *authService) GetAuthUser(ctx context.Context) (*models.User, error) {
 	// Get the user ID from the context
-	userID, ok := ctx.Value("AuthID").(uint)
-	if !ok {
+	userID := utils.AuthID(ctx)
+	if userID == 0 {
 		return nil, fmt.Errorf("user not found in context")
 	}
 
 	// Find the user by ID
-	user, err := s.userRepo.GetUserByID(userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found in the database")
 	}
@@ -174,48 +401,143 @@ func (s *authService) GetAuthUser(ctx context.Context) (*models.User, error) {
 // ----------------------------------------------------------Tracking User Online/Offline Status
 // ----------------------------------------------------------
 
-func (s *authService) TrackUserLogin(userID uint) error {
-	// Create a unique key for the user session
-	userKey := fmt.Sprintf("user:%d:online", userID)
+// Presence is kept in a sorted set scored by the unix time a user was last seen,
+// so a user is online while their score is within the presence window.
+const (
+	presenceKey           = "presence:online"
+	presencePersistPeriod = time.Minute
+)
 
-	// Set the key in Redis to mark the user as online
-	// The second parameter is the expiration time (e.g., 15 minutes)
-	err := s.rdb.Set(context.Background(), userKey, "online", 15*time.Minute).Err()
-	if err != nil {
+func (s *authService) TrackUserLogin(ctx context.Context, userID uint) error {
+	if err := s.Heartbeat(ctx, userID); err != nil {
 		return fmt.Errorf("could not track user login: %v", err)
 	}
+	return nil
+}
 
+// Track user logout by removing the user from the presence set
+func (s *authService) TrackUserLogout(ctx context.Context, userID uint) error {
+	member := strconv.FormatUint(uint64(userID), 10)
+	removed, err := s.rdb.ZRem(ctx, presenceKey, member).Result()
+	if err != nil {
+		return fmt.Errorf("could not track user logout: %v", err)
+	}
+	if removed > 0 {
+		s.publishPresence(ctx, userID, false)
+	}
 	return nil
 }
 
-// Track user logout by removing the Redis key
-func (s *authService) TrackUserLogout(userID uint) error {
-	// Create the same user key as used in login
-	userKey := fmt.Sprintf("user:%d:online", userID)
+// Heartbeat extends the user's presence and persists last_seen_at at most once a minute
+func (s *authService) Heartbeat(ctx context.Context, userID uint) error {
+	now := time.Now()
+	member := strconv.FormatUint(uint64(userID), 10)
+
+	// Read the previous score in the same round trip to detect offline -> online transitions
+	pipe := s.rdb.TxPipeline()
+	previous := pipe.ZScore(ctx, presenceKey, member)
+	pipe.ZAdd(ctx, presenceKey, redis.Z{Score: float64(now.Unix()), Member: member})
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return err
+	}
+	if previous.Val() <= float64(s.presenceCutoff().Unix()) {
+		s.publishPresence(ctx, userID, true)
+	}
+
+	// SETNX acts as a per-user throttle so busy clients don't write to the DB on every request
+	throttleKey := fmt.Sprintf("presence:%d:persisted", userID)
+	first, err := s.rdb.SetNX(ctx, throttleKey, 1, presencePersistPeriod).Result()
+	if err != nil || !first {
+		return err
+	}
+	return s.userRepo.UpdateLastSeen(ctx, userID, now)
+}
+
+// Check if a user is online
+func (s *authService) IsUserOnline(ctx context.Context, userID uint) (bool, error) {
+	statuses, err := s.AreUsersOnline(ctx, []uint{userID})
+	if err != nil {
+		return false, err
+	}
+	return statuses[userID], nil
+}
 
-	// Remove the key from Redis to mark the user as offline
-	err := s.rdb.Del(context.Background(), userKey).Err()
+// AreUsersOnline checks the presence of several users with a single ZMSCORE
+func (s *authService) AreUsersOnline(ctx context.Context, userIDs []uint) (map[uint]bool, error) {
+	statuses := make(map[uint]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return statuses, nil
+	}
+
+	members := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		members[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	scores, err := s.rdb.ZMScore(ctx, presenceKey, members...).Result()
 	if err != nil {
-		return fmt.Errorf("could not track user logout: %v", err)
+		return nil, fmt.Errorf("could not check if users are online: %v", err)
+	}
+
+	cutoff := float64(s.presenceCutoff().Unix())
+	for i, id := range userIDs {
+		// Missing members come back with a score of 0
+		statuses[id] = scores[i] > cutoff
+	}
+	return statuses, nil
+}
+
+// GetOnlineUserIDs lists users seen within the presence window, most recent first.
+// Stale members are left for ExpireStalePresence, which announces them as offline.
+func (s *authService) GetOnlineUserIDs(ctx context.Context) ([]uint, error) {
+	cutoff := strconv.FormatInt(s.presenceCutoff().Unix(), 10)
+
+	members, err := s.rdb.ZRevRangeByScore(ctx, presenceKey, &redis.ZRangeBy{Min: "(" + cutoff, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseUint(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
 	}
+	return ids, nil
+}
+
+// ExpireStalePresence removes users whose presence window has passed and announces them as offline.
+// Safe to run on every instance: only the instance whose ZREM succeeds publishes the event.
+func (s *authService) ExpireStalePresence(ctx context.Context) error {
+	cutoff := strconv.FormatInt(s.presenceCutoff().Unix(), 10)
 
+	members, err := s.rdb.ZRangeByScore(ctx, presenceKey, &redis.ZRangeBy{Min: "-inf", Max: cutoff}).Result()
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		removed, err := s.rdb.ZRem(ctx, presenceKey, member).Result()
+		if err != nil {
+			return err
+		}
+		if id, err := strconv.ParseUint(member, 10, 64); err == nil && removed > 0 {
+			s.publishPresence(ctx, uint(id), false)
+		}
+	}
 	return nil
 }
 
-// Check if a user is online
-func (s *authService) IsUserOnline(userID uint) (bool, error) {
-	// Create the user key to check
-	userKey := fmt.Sprintf("user:%d:online", userID)
-
-	// Check if the key exists in Redis
-	val, err := s.rdb.Get(context.Background(), userKey).Result()
-	if err == redis.Nil {
-		// If the key doesn't exist, the user is offline
-		return false, nil
-	} else if err != nil {
-		return false, fmt.Errorf("could not check if user is online: %v", err)
+func (s *authService) publishPresence(ctx context.Context, userID uint, online bool) {
+	eventType := EventPresenceOffline
+	if online {
+		eventType = EventPresenceOnline
 	}
+	if err := s.publisher.Publish(ctx, realtime.TopicPresence, eventType, PresenceChange{UserID: userID, Online: online}); err != nil {
+		log.Printf("failed to publish presence of user %d: %v", userID, err)
+	}
+}
 
-	// If the value is found, user is online
-	return val == "online", nil
+func (s *authService) presenceCutoff() time.Time {
+	return time.Now().Add(-time.Duration(s.cfg.PresenceTTLSec) * time.Second)
 }
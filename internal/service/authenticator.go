@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned by an Authenticator that does not accept the credentials.
+// authService.Login then tries the next authenticator in the chain.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator verifies a login/password pair against one identity source
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, login, password string) (*models.User, error)
+}
+
+// ----------------------------------------------------------
+// DATABASE (bcrypt) AUTHENTICATOR
+// ----------------------------------------------------------
+
+type dbAuthenticator struct {
+	userRepo repository.UserRepository
+}
+
+func NewDBAuthenticator(repo repository.UserRepository) Authenticator {
+	return &dbAuthenticator{userRepo: repo}
+}
+
+func (a *dbAuthenticator) Name() string {
+	return "local"
+}
+
+func (a *dbAuthenticator) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	// 1. Find user by email
+	user, err := a.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Directory users never have a usable local password
+	if user.AuthSource != "" && user.AuthSource != a.Name() {
+		return nil, ErrInvalidCredentials
+	}
+
+	// 2. Compare password
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+// Decoding is refused above this many pixels, so a tiny file cannot expand into gigabytes of memory
+const maxAvatarPixels = 40_000_000
+
+var (
+	ErrUnsupportedImage = errors.New("avatar must be a JPEG, PNG, GIF or WebP image")
+	ErrImageTooLarge    = errors.New("avatar dimensions are too large")
+)
+
+var avatarTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// resizeAvatar checks the real content type of data (the client supplied one is not
+// trusted), crops it to a centered square and scales it to size x size pixels.
+// JPEGs stay JPEG; other formats become PNG to keep transparency.
+func resizeAvatar(data []byte, size int) ([]byte, string, error) {
+	contentType := http.DetectContentType(data)
+	if !avatarTypes[contentType] {
+		return nil, "", ErrUnsupportedImage
+	}
+
+	// 1. Check the dimensions before decoding the pixels
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxAvatarPixels {
+		return nil, "", ErrImageTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+
+	// 2. Center crop to a square
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+
+	// 3. Scale
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+
+	var out bytes.Buffer
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: 85})
+	} else {
+		contentType = "image/png"
+		err = png.Encode(&out, dst)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return out.Bytes(), contentType, nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"golang-api-template/internal/repository"
+	"golang-api-template/internal/utils"
+)
+
+// Hooks wrap these (with %w) to reject a write; the generic handler answers them
+// with 409 and 422
+var (
+	ErrConflict     = errors.New("conflicts with an existing record")
+	ErrInvalidInput = errors.New("invalid input")
+)
+
+// CrudHooks customise a Crud service. Before* hooks can change the entity or abort
+// the operation by returning an error; After* hooks run once the row is written, and
+// their error is returned to the caller without undoing the write. Nil hooks are skipped.
+type CrudHooks[T any] struct {
+	BeforeCreate func(ctx context.Context, entity *T) error
+	AfterCreate  func(ctx context.Context, entity *T) error
+	BeforeUpdate func(ctx context.Context, entity *T) error
+	AfterUpdate  func(ctx context.Context, entity *T) error
+	// force is true when the row is removed for good instead of moved to the trash
+	BeforeDelete  func(ctx context.Context, entity *T, force bool) error
+	AfterDelete   func(ctx context.Context, entity *T, force bool) error
+	BeforeRestore func(ctx context.Context, entity *T) error
+	AfterRestore  func(ctx context.Context, entity *T) error
+}
+
+// Crud is the business layer over a repository.Crud. Resource services embed it,
+// put their rules into hooks and only add their own operations.
+type Crud[T any] interface {
+	Create(ctx context.Context, entity *T) error
+	Get(ctx context.Context, id uint) (*T, error)
+	List(ctx context.Context, p utils.PaginationParams, trashed string) ([]T, utils.PageInfo, error)
+	// Update saves entity, usually loaded with Get and then modified, as row id
+	Update(ctx context.Context, id uint, entity *T) error
+	// Delete moves the row to the trash, or removes it for good when force is set
+	Delete(ctx context.Context, id uint, force bool) error
+	Restore(ctx context.Context, id uint) (*T, error)
+}
+
+type crudService[T any] struct {
+	repo  repository.Crud[T]
+	hooks CrudHooks[T]
+}
+
+func NewCrud[T any](repo repository.Crud[T], hooks CrudHooks[T]) Crud[T] {
+	return &crudService[T]{repo: repo, hooks: hooks}
+}
+
+func (s *crudService[T]) Create(ctx context.Context, entity *T) error {
+	if err := runHook(ctx, s.hooks.BeforeCreate, entity); err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, entity); err != nil {
+		return err
+	}
+	return runHook(ctx, s.hooks.AfterCreate, entity)
+}
+
+func (s *crudService[T]) Get(ctx context.Context, id uint) (*T, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *crudService[T]) List(ctx context.Context, p utils.PaginationParams, trashed string) ([]T, utils.PageInfo, error) {
+	return s.repo.List(ctx, p, trashed)
+}
+
+func (s *crudService[T]) Update(ctx context.Context, id uint, entity *T) error {
+	if err := runHook(ctx, s.hooks.BeforeUpdate, entity); err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, id, entity); err != nil {
+		return err
+	}
+	return runHook(ctx, s.hooks.AfterUpdate, entity)
+}
+
+func (s *crudService[T]) Delete(ctx context.Context, id uint, force bool) error {
+	// 1. Load the row for the hooks; only a forced delete reaches rows in the trash
+	entity, err := s.repo.GetByID(ctx, id)
+	if err != nil && force {
+		entity, err = s.repo.GetDeletedByID(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 2. Delete
+	if s.hooks.BeforeDelete != nil {
+		if err := s.hooks.BeforeDelete(ctx, entity, force); err != nil {
+			return err
+		}
+	}
+	if force {
+		err = s.repo.ForceDelete(ctx, id)
+	} else {
+		err = s.repo.Delete(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	if s.hooks.AfterDelete != nil {
+		return s.hooks.AfterDelete(ctx, entity, force)
+	}
+	return nil
+}
+
+func (s *crudService[T]) Restore(ctx context.Context, id uint) (*T, error) {
+	entity, err := s.repo.GetDeletedByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := runHook(ctx, s.hooks.BeforeRestore, entity); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+
+	restored, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return restored, runHook(ctx, s.hooks.AfterRestore, restored)
+}
+
+func runHook[T any](ctx context.Context, hook func(context.Context, *T) error, entity *T) error {
+	if hook == nil {
+		return nil
+	}
+	return hook(ctx, entity)
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/repository"
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmailTaken              = errors.New("email is already taken by another user")
+	ErrEmailUnchanged          = errors.New("new email is the same as the current one")
+	ErrInvalidEmailChangeToken = errors.New("email change token is invalid or expired")
+)
+
+// EmailChangeService moves users to a new email address only after the new
+// address confirmed it, and lets the old address undo the change.
+type EmailChangeService interface {
+	RequestEmailChange(ctx context.Context, userID uint, newEmail, lang string) (*models.EmailChange, error)
+	CheckConfirmToken(ctx context.Context, token string) (*models.EmailChange, error)
+	ConfirmEmailChange(ctx context.Context, token string) (*models.EmailChange, error)
+	CheckRevertToken(ctx context.Context, token string) (*models.EmailChange, error)
+	RevertEmailChange(ctx context.Context, token string) (*models.EmailChange, error)
+	CancelEmailChange(ctx context.Context, userID uint) error
+	GetPendingEmailChange(ctx context.Context, userID uint) (*models.EmailChange, error)
+}
+
+type emailChangeService struct {
+	repo         repository.EmailChangeRepository
+	userRepo     repository.UserRepository
+	emailService *EmailService
+	cfg          *config.Config
+}
+
+func NewEmailChangeService(repo repository.EmailChangeRepository, userRepo repository.UserRepository, es *EmailService, cfg *config.Config) EmailChangeService {
+	return &emailChangeService{
+		repo:         repo,
+		userRepo:     userRepo,
+		emailService: es,
+		cfg:          cfg,
+	}
+}
+
+// RequestEmailChange stores a pending change, mails a confirmation link to the new
+// address and a notice with a revert link to the current one
+func (s *emailChangeService) RequestEmailChange(ctx context.Context, userID uint, newEmail, lang string) (*models.EmailChange, error) {
+	newEmail = strings.ToLower(strings.TrimSpace(newEmail))
+
+	// 1. Validate the new address
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return nil, ErrEmailUnchanged
+	}
+	if err := s.ensureEmailAvailable(ctx, newEmail, user.ID); err != nil {
+		return nil, err
+	}
+
+	// 2. Only the latest request counts
+	if err := s.repo.CancelPendingEmailChanges(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	// 3. Create the change with hashed one-time tokens
+	confirmToken, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	revertToken, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	change := &models.EmailChange{
+		UserID:           user.ID,
+		OldEmail:         user.Email,
+		NewEmail:         newEmail,
+		ConfirmTokenHash: utils.HashToken(confirmToken),
+		RevertTokenHash:  utils.HashToken(revertToken),
+		ExpiresAt:        now.Add(time.Hour * time.Duration(s.cfg.EmailChangeExpireHrs)),
+		RevertExpiresAt:  now.Add(time.Hour * time.Duration(s.cfg.EmailChangeRevertHrs)),
+	}
+	if err := s.repo.CreateEmailChange(ctx, change); err != nil {
+		return nil, err
+	}
+
+	// 4. Notify both addresses
+	confirmLink := s.cfg.AppURL + "/api/v1/auth/email/confirm?token=" + url.QueryEscape(confirmToken)
+	revertLink := s.cfg.AppURL + "/api/v1/auth/email/revert?token=" + url.QueryEscape(revertToken)
+	go func() {
+		if err := s.emailService.SendEmailChangeConfirmationEmail(change.NewEmail, confirmLink, lang); err != nil {
+			log.Printf("failed to send email change confirmation for user %d: %v", change.UserID, err)
+		}
+		if err := s.emailService.SendEmailChangeNoticeEmail(change.OldEmail, change.NewEmail, revertLink, lang); err != nil {
+			log.Printf("failed to send email change notice for user %d: %v", change.UserID, err)
+		}
+	}()
+
+	return change, nil
+}
+
+// CheckConfirmToken returns the pending change a confirmation token belongs to,
+// without applying it
+func (s *emailChangeService) CheckConfirmToken(ctx context.Context, token string) (*models.EmailChange, error) {
+	change, err := s.repo.GetEmailChangeByConfirmHash(ctx, utils.HashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !change.Pending()) {
+		return nil, ErrInvalidEmailChangeToken
+	} else if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// ConfirmEmailChange switches the user to the new address
+func (s *emailChangeService) ConfirmEmailChange(ctx context.Context, token string) (*models.EmailChange, error) {
+	change, err := s.CheckConfirmToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// The address may have been taken since the change was requested
+	if err := s.ensureEmailAvailable(ctx, change.NewEmail, change.UserID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	change.ConfirmedAt = &now
+	if err := s.repo.ApplyEmailChange(ctx, change, change.NewEmail); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// CheckRevertToken returns the change a revert token can still undo, without undoing it
+func (s *emailChangeService) CheckRevertToken(ctx context.Context, token string) (*models.EmailChange, error) {
+	change, err := s.repo.GetEmailChangeByRevertHash(ctx, utils.HashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidEmailChangeToken
+	} else if err != nil {
+		return nil, err
+	}
+	if change.RevertedAt != nil || time.Now().After(change.RevertExpiresAt) {
+		return nil, ErrInvalidEmailChangeToken
+	}
+	return change, nil
+}
+
+// RevertEmailChange undoes a change from the old address, whether it was
+// already confirmed or is still pending
+func (s *emailChangeService) RevertEmailChange(ctx context.Context, token string) (*models.EmailChange, error) {
+	change, err := s.CheckRevertToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureEmailAvailable(ctx, change.OldEmail, change.UserID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	change.RevertedAt = &now
+	if err := s.repo.ApplyEmailChange(ctx, change, change.OldEmail); err != nil {
+		return nil, err
+	}
+	// A hijacked session could have queued another change in the meantime
+	if err := s.repo.CancelPendingEmailChanges(ctx, change.UserID); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (s *emailChangeService) CancelEmailChange(ctx context.Context, userID uint) error {
+	return s.repo.CancelPendingEmailChanges(ctx, userID)
+}
+
+func (s *emailChangeService) GetPendingEmailChange(ctx context.Context, userID uint) (*models.EmailChange, error) {
+	return s.repo.GetPendingEmailChange(ctx, userID)
+}
+
+func (s *emailChangeService) ensureEmailAvailable(ctx context.Context, email string, userID uint) error {
+	existing, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err == nil && existing.ID != userID {
+		return ErrEmailTaken
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
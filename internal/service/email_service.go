@@ -3,6 +3,7 @@ package service
 import (
 	"fmt"
 	"net/smtp"
+	"time"
 
 	"golang-api-template/internal/config"
 	"golang-api-template/internal/i18n"
@@ -47,3 +48,50 @@ func (s *EmailService) SendPasswordResetEmail(to, resetLink, lang string) error
 	body := fmt.Sprintf(i18n.TT(lang, "PasswordResetEmailBody"), resetLink)
 	return s.SendEmail(to, subject, body)
 }
+
+// NewLoginAlert holds the details shown in a new device / suspicious login email
+type NewLoginAlert struct {
+	Time     time.Time
+	IP       string
+	Location string
+	Device   string
+}
+
+// SendNewLoginAlertEmail warns the user about a login from an unfamiliar device or network
+func (s *EmailService) SendNewLoginAlertEmail(to, lang string, alert NewLoginAlert) error {
+	subject := i18n.TT(lang, "NewLoginAlertEmailSubject")
+	body := fmt.Sprintf(i18n.TT(lang, "NewLoginAlertEmailBody"),
+		alert.Time.UTC().Format(time.RFC1123), alert.IP, alert.Location, alert.Device)
+	return s.SendEmail(to, subject, body)
+}
+
+// SendEmailChangeConfirmationEmail asks the new address to confirm an email change
+func (s *EmailService) SendEmailChangeConfirmationEmail(to, confirmLink, lang string) error {
+	subject := i18n.TT(lang, "EmailChangeConfirmEmailSubject")
+	body := fmt.Sprintf(i18n.TT(lang, "EmailChangeConfirmEmailBody"), confirmLink)
+	return s.SendEmail(to, subject, body)
+}
+
+// SendEmailChangeNoticeEmail tells the old address about an email change and how to undo it
+func (s *EmailService) SendEmailChangeNoticeEmail(to, newEmail, revertLink, lang string) error {
+	subject := i18n.TT(lang, "EmailChangeNoticeEmailSubject")
+	body := fmt.Sprintf(i18n.TT(lang, "EmailChangeNoticeEmailBody"), newEmail, revertLink)
+	return s.SendEmail(to, subject, body)
+}
+
+// SendInvitationEmail invites someone to create an account through the given link
+func (s *EmailService) SendInvitationEmail(to, organization, acceptLink string, expiresAt time.Time, lang string) error {
+	subject := i18n.TT(lang, "InvitationEmailSubject")
+	if organization == "" {
+		organization = i18n.TT(lang, "InvitationDefaultOrganization")
+	}
+	body := fmt.Sprintf(i18n.TT(lang, "InvitationEmailBody"), organization, acceptLink, expiresAt.UTC().Format(time.RFC1123))
+	return s.SendEmail(to, subject, body)
+}
+
+// SendDataExportReadyEmail tells the user their data export can be downloaded
+func (s *EmailService) SendDataExportReadyEmail(to, lang string, expiresAt time.Time) error {
+	subject := i18n.TT(lang, "DataExportReadyEmailSubject")
+	body := fmt.Sprintf(i18n.TT(lang, "DataExportReadyEmailBody"), expiresAt.UTC().Format(time.RFC1123))
+	return s.SendEmail(to, subject, body)
+}
@@ -0,0 +1,26 @@
+package service
+
+import "context"
+
+// EventPublisher pushes server events to connected clients (see internal/realtime)
+type EventPublisher interface {
+	Publish(ctx context.Context, topic, eventType string, data interface{}) error
+}
+
+type noopPublisher struct{}
+
+func (noopPublisher) Publish(context.Context, string, string, interface{}) error {
+	return nil
+}
+
+// Presence events, published on realtime.TopicPresence
+const (
+	EventPresenceOnline  = "presence.online"
+	EventPresenceOffline = "presence.offline"
+)
+
+// PresenceChange is the payload of presence events
+type PresenceChange struct {
+	UserID uint `json:"user_id"`
+	Online bool `json:"online"`
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/repository"
+	"golang-api-template/internal/storage"
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFileTooLarge       = errors.New("file is too large")
+	ErrFileTypeNotAllowed = errors.New("file type is not allowed")
+	ErrFileNotFound       = errors.New("file not found")
+)
+
+// FileService stores uploads in the storage backend and tracks them in the files table
+type FileService interface {
+	Upload(ctx context.Context, ownerID uint, header *multipart.FileHeader) (*models.File, error)
+	ListFiles(ctx context.Context, ownerID uint, p utils.PaginationParams) ([]models.File, utils.PageInfo, error)
+	GetFile(ctx context.Context, ownerID, id uint) (*models.File, error)
+	DeleteFile(ctx context.Context, ownerID, id uint) error
+	// DeleteUserFiles removes everything the user uploaded, e.g. when their data is erased
+	DeleteUserFiles(ctx context.Context, ownerID uint) error
+
+	// DownloadURL returns a signed link to the API's download route and when it expires
+	DownloadURL(file *models.File) (string, time.Time)
+	// OpenSigned checks a signed download link and opens the file; the caller closes the reader
+	OpenSigned(ctx context.Context, id uint, expires, signature string) (*models.File, io.ReadCloser, error)
+	// OpenSignedKey serves the signed links of the local backend's Presign
+	OpenSignedKey(ctx context.Context, key, expires, signature string) (io.ReadCloser, *storage.ObjectInfo, error)
+}
+
+type fileService struct {
+	repo    repository.FileRepository
+	backend storage.Backend
+	signer  *storage.Signer
+	cfg     *config.StorageConfig
+}
+
+func NewFileService(repo repository.FileRepository, backend storage.Backend, cfg *config.StorageConfig) FileService {
+	return &fileService{
+		repo:    repo,
+		backend: backend,
+		signer:  storage.NewSigner(cfg.SigningSecret),
+		cfg:     cfg,
+	}
+}
+
+// ----------------------------------------------------------
+// UPLOAD
+// ----------------------------------------------------------
+
+func (s *fileService) Upload(ctx context.Context, ownerID uint, header *multipart.FileHeader) (*models.File, error) {
+	if header.Size > s.cfg.UploadMaxBytes {
+		return nil, ErrFileTooLarge
+	}
+	src, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	// 1. The type is sniffed from the content; the client supplied Content-Type is ignored
+	reader := bufio.NewReaderSize(src, 512)
+	head, err := reader.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	contentType := http.DetectContentType(head)
+	if !s.allowedType(contentType) {
+		return nil, ErrFileTypeNotAllowed
+	}
+
+	// 2. Store it, hashing on the way
+	suffix, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("uploads/%d/%s", ownerID, suffix)
+	hash := sha256.New()
+	body := io.TeeReader(io.LimitReader(reader, s.cfg.UploadMaxBytes), hash)
+	if err := s.backend.Put(ctx, key, body, header.Size, contentType); err != nil {
+		return nil, err
+	}
+
+	// 3. Record the metadata
+	file := &models.File{
+		OwnerID:     ownerID,
+		Key:         key,
+		Name:        cleanFileName(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+	}
+	if err := s.repo.CreateFile(ctx, file); err != nil {
+		s.deleteObject(ctx, key)
+		return nil, err
+	}
+	return file, nil
+}
+
+// allowedType compares media types, so "text/plain" allows "text/plain; charset=utf-8"
+func (s *fileService) allowedType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range s.cfg.UploadAllowedTypes {
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanFileName keeps the base name only and drops characters that break headers
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
+
+// ----------------------------------------------------------
+// FILES
+// ----------------------------------------------------------
+
+func (s *fileService) ListFiles(ctx context.Context, ownerID uint, p utils.PaginationParams) ([]models.File, utils.PageInfo, error) {
+	return s.repo.GetFilesByOwnerID(ctx, ownerID, p)
+}
+
+func (s *fileService) GetFile(ctx context.Context, ownerID, id uint) (*models.File, error) {
+	file, err := s.repo.GetFileByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	if file.OwnerID != ownerID {
+		return nil, ErrFileNotFound
+	}
+	return file, nil
+}
+
+func (s *fileService) DeleteFile(ctx context.Context, ownerID, id uint) error {
+	file, err := s.GetFile(ctx, ownerID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.backend.Delete(ctx, file.Key); err != nil {
+		return err
+	}
+	return s.repo.DeleteFile(ctx, file.ID)
+}
+
+func (s *fileService) DeleteUserFiles(ctx context.Context, ownerID uint) error {
+	files, err := s.repo.GetAllFilesByOwnerID(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := s.DeleteFile(ctx, ownerID, file.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fileService) deleteObject(ctx context.Context, key string) {
+	if err := s.backend.Delete(ctx, key); err != nil {
+		log.Printf("failed to delete %s from storage: %v", key, err)
+	}
+}
+
+// ----------------------------------------------------------
+// SIGNED DOWNLOADS
+// ----------------------------------------------------------
+
+func (s *fileService) DownloadURL(file *models.File) (string, time.Time) {
+	expires := time.Now().Add(s.cfg.SignedURLTTL)
+	query := s.signer.Sign(fileResource(file.ID), expires)
+	return fmt.Sprintf("%s/api/v1/files/%d/download?%s", s.cfg.AppURL, file.ID, query.Encode()), expires
+}
+
+func (s *fileService) OpenSigned(ctx context.Context, id uint, expires, signature string) (*models.File, io.ReadCloser, error) {
+	// 1. The link itself is the authorization
+	if err := s.signer.Verify(fileResource(id), expires, signature); err != nil {
+		return nil, nil, err
+	}
+
+	// 2. Open the content
+	file, err := s.repo.GetFileByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrFileNotFound
+		}
+		return nil, nil, err
+	}
+	content, _, err := s.backend.Get(ctx, file.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, ErrFileNotFound
+		}
+		return nil, nil, err
+	}
+	return file, content, nil
+}
+
+func (s *fileService) OpenSignedKey(ctx context.Context, key, expires, signature string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	if err := s.signer.Verify("storage/"+key, expires, signature); err != nil {
+		return nil, nil, err
+	}
+	content, info, err := s.backend.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrFileNotFound
+	}
+	return content, info, err
+}
+
+func fileResource(id uint) string {
+	return fmt.Sprintf("files/%d", id)
+}
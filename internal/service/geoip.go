@@ -0,0 +1,47 @@
+package service
+
+import (
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// GeoLocator resolves an IP address to a coarse location
+type GeoLocator interface {
+	Lookup(ip string) (country, city string)
+}
+
+// NewGeoLocator opens an offline MaxMind/DB-IP city database.
+// Without a path every lookup simply returns an empty location.
+func NewGeoLocator(dbPath string) (GeoLocator, error) {
+	if dbPath == "" {
+		return noopGeoLocator{}, nil
+	}
+	reader, err := geoip2.Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &geoIPLocator{reader: reader}, nil
+}
+
+type noopGeoLocator struct{}
+
+func (noopGeoLocator) Lookup(string) (string, string) {
+	return "", ""
+}
+
+type geoIPLocator struct {
+	reader *geoip2.Reader
+}
+
+func (g *geoIPLocator) Lookup(ip string) (string, string) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", ""
+	}
+	record, err := g.reader.City(parsed)
+	if err != nil {
+		return "", ""
+	}
+	return record.Country.IsoCode, record.City.Names["en"]
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/repository"
+	"golang-api-template/internal/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+)
+
+// PermissionInviteUsers is required to manage invitations
+const PermissionInviteUsers = "users.invite"
+
+var (
+	ErrInvalidInvitation      = errors.New("invitation is invalid, expired or no longer pending")
+	ErrInvitationAlreadySent  = errors.New("a pending invitation already exists for this email")
+	ErrInvitationNotPending   = errors.New("invitation is no longer pending")
+	ErrRoleNotAssignable      = errors.New("you cannot grant a role with permissions you do not have")
+	ErrInvitationRoleNotFound = errors.New("role not found")
+)
+
+type InvitationService interface {
+	Invite(ctx context.Context, inviterID uint, email string, roleIDs []uint, organization, lang string) (*models.Invitation, error)
+	Resend(ctx context.Context, id uint, lang string) (*models.Invitation, error)
+	Revoke(ctx context.Context, id uint) error
+	ListPending(ctx context.Context, p utils.PaginationParams) ([]models.Invitation, utils.PageInfo, error)
+
+	// GetByToken lets the accept page show who was invited before the form is submitted
+	GetByToken(ctx context.Context, token string) (*models.Invitation, error)
+	Accept(ctx context.Context, token, name, password string) (*models.User, error)
+}
+
+type invitationService struct {
+	repo         repository.InvitationRepository
+	userRepo     repository.UserRepository
+	roleRepo     repository.RoleRepository
+	emailService *EmailService
+	cfg          *config.Config
+}
+
+func NewInvitationService(
+	repo repository.InvitationRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	es *EmailService,
+	cfg *config.Config,
+) InvitationService {
+	return &invitationService{
+		repo:         repo,
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		emailService: es,
+		cfg:          cfg,
+	}
+}
+
+func (s *invitationService) Invite(ctx context.Context, inviterID uint, email string, roleIDs []uint, organization, lang string) (*models.Invitation, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	// 1. The invitee must not have an account or another pending invitation
+	if _, err := s.userRepo.GetUserByEmail(ctx, email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if _, err := s.repo.GetPendingInvitationByEmail(ctx, email); err == nil {
+		return nil, ErrInvitationAlreadySent
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 2. Inviters can only hand out permissions they hold themselves
+	roles, err := s.assignableRoles(ctx, inviterID, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Store the invitation and email the link
+	invitation := &models.Invitation{
+		Email:        email,
+		Organization: strings.TrimSpace(organization),
+		Roles:        roles,
+		InvitedByID:  inviterID,
+	}
+	// The link embeds the ID, so it is only signed after the insert;
+	// this first call just fills in the token hash and expiry
+	if _, err := s.issueToken(invitation); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+	token, err := s.issueToken(invitation)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.send(ctx, invitation, token, lang); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// Resend issues a fresh link (invalidating the previous one) and extends the expiry
+func (s *invitationService) Resend(ctx context.Context, id uint, lang string) (*models.Invitation, error) {
+	invitation, err := s.repo.GetInvitationByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return nil, ErrInvitationNotPending
+	}
+
+	token, err := s.issueToken(invitation)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.send(ctx, invitation, token, lang); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (s *invitationService) Revoke(ctx context.Context, id uint) error {
+	invitation, err := s.repo.GetInvitationByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return ErrInvitationNotPending
+	}
+
+	now := time.Now()
+	invitation.RevokedAt = &now
+	return s.repo.UpdateInvitation(ctx, invitation)
+}
+
+func (s *invitationService) ListPending(ctx context.Context, p utils.PaginationParams) ([]models.Invitation, utils.PageInfo, error) {
+	return s.repo.GetPendingInvitations(ctx, p)
+}
+
+func (s *invitationService) GetByToken(ctx context.Context, token string) (*models.Invitation, error) {
+	return s.verifyToken(ctx, token)
+}
+
+// Accept creates the invited user with the pre-assigned roles
+func (s *invitationService) Accept(ctx context.Context, token, name, password string) (*models.User, error) {
+	invitation, err := s.verifyToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Someone may have registered the address after the invitation was sent
+	if _, err := s.userRepo.GetUserByEmail(ctx, invitation.Email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{
+		Name:         name,
+		Email:        invitation.Email,
+		Password:     hashedPassword,
+		Organization: invitation.Organization,
+	}
+
+	now := time.Now()
+	invitation.AcceptedAt = &now
+	if err := s.repo.AcceptInvitation(ctx, invitation, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *invitationService) assignableRoles(ctx context.Context, inviterID uint, roleIDs []uint) ([]models.Role, error) {
+	granted, err := s.userRepo.GetPermissionsByUserID(ctx, inviterID)
+	if err != nil {
+		return nil, err
+	}
+	held := map[string]bool{}
+	for _, perm := range granted {
+		held[perm.Name] = true
+	}
+
+	var roles []models.Role
+	seen := map[uint]bool{}
+	for _, id := range roleIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		role, err := s.roleRepo.GetByID(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationRoleNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		for _, perm := range role.Permissions {
+			if !held[perm.Name] {
+				return nil, ErrRoleNotAssignable
+			}
+		}
+		role.Permissions = nil // only the role itself is associated
+		roles = append(roles, *role)
+	}
+	return roles, nil
+}
+
+// issueToken signs a new invite link for the invitation and records its jti,
+// so only the most recently sent link works
+func (s *invitationService) issueToken(invitation *models.Invitation) (string, error) {
+	jti, err := utils.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	invitation.TokenHash = utils.HashToken(jti)
+	invitation.ExpiresAt = now.Add(time.Hour * time.Duration(s.cfg.InvitationExpireHrs))
+
+	claims := jwt.MapClaims{
+		"inv": invitation.ID,
+		"jti": jti,
+		"iat": now.Unix(),
+		"exp": invitation.ExpiresAt.Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.cfg.InvitationSecret))
+}
+
+func (s *invitationService) verifyToken(ctx context.Context, tokenStr string) (*models.Invitation, error) {
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		return []byte(s.cfg.InvitationSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidInvitation
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidInvitation
+	}
+	id, _ := claims["inv"].(float64)
+	jti, _ := claims["jti"].(string)
+	if id <= 0 || jti == "" {
+		return nil, ErrInvalidInvitation
+	}
+
+	invitation, err := s.repo.GetInvitationByID(ctx, uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidInvitation
+	} else if err != nil {
+		return nil, err
+	}
+	if invitation.TokenHash != utils.HashToken(jti) || !invitation.Pending() {
+		return nil, ErrInvalidInvitation
+	}
+	return invitation, nil
+}
+
+// send saves the invitation with its new token and emails the link
+func (s *invitationService) send(ctx context.Context, invitation *models.Invitation, token, lang string) error {
+	now := time.Now()
+	invitation.SentCount++
+	invitation.LastSentAt = &now
+	if err := s.repo.UpdateInvitation(ctx, invitation); err != nil {
+		return err
+	}
+
+	link := s.cfg.AppURL + "/api/v1/invitations/accept?token=" + url.QueryEscape(token)
+	id, email, organization, expiresAt := invitation.ID, invitation.Email, invitation.Organization, invitation.ExpiresAt
+	go func() {
+		if err := s.emailService.SendInvitationEmail(email, organization, link, expiresAt, lang); err != nil {
+			log.Printf("failed to send invitation %d: %v", id, err)
+		}
+	}()
+	return nil
+}
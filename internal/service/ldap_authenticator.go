@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/repository"
+	"golang-api-template/internal/utils"
+
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+)
+
+// ldapAuthenticator binds against an LDAP / Active Directory server and
+// provisions the matching models.User just-in-time on the first login.
+type ldapAuthenticator struct {
+	cfg      *config.LDAPConfig
+	userRepo repository.UserRepository
+	roleRepo repository.RoleRepository
+}
+
+func NewLDAPAuthenticator(cfg *config.LDAPConfig, userRepo repository.UserRepository, roleRepo repository.RoleRepository) Authenticator {
+	return &ldapAuthenticator{
+		cfg:      cfg,
+		userRepo: userRepo,
+		roleRepo: roleRepo,
+	}
+}
+
+func (a *ldapAuthenticator) Name() string {
+	return "ldap"
+}
+
+func (a *ldapAuthenticator) Authenticate(ctx context.Context, login, password string) (*models.User, error) {
+	// An empty password would be an "unauthenticated bind", which most servers accept
+	if login == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// 1. Look the user up with the service account
+	entry, err := a.findUser(conn, login)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Bind as the user to verify the password
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap bind failed: %w", err)
+	}
+
+	// 3. Provision / update the local user
+	return a.provision(ctx, entry)
+}
+
+func (a *ldapAuthenticator) connect() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: a.cfg.InsecureSkipVerify}
+	conn, err := ldap.DialURL(a.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ldap: %w", err)
+	}
+	conn.SetTimeout(10 * time.Second)
+
+	if a.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls failed: %w", err)
+		}
+	}
+
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap service bind failed: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (a *ldapAuthenticator) findUser(conn *ldap.Conn, login string) (*ldap.Entry, error) {
+	escaped := ldap.EscapeFilter(login)
+	filter := strings.ReplaceAll(a.cfg.UserFilter, "%s", escaped)
+
+	req := ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, // we only need to know whether the login is ambiguous
+		10, false,
+		filter,
+		[]string{"dn", a.cfg.EmailAttribute, a.cfg.NameAttribute, a.cfg.GroupAttribute},
+		nil,
+	)
+	res, err := conn.Search(req)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap search failed: %w", err)
+	}
+	if len(res.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return res.Entries[0], nil
+}
+
+func (a *ldapAuthenticator) provision(ctx context.Context, entry *ldap.Entry) (*models.User, error) {
+	email := strings.ToLower(entry.GetAttributeValue(a.cfg.EmailAttribute))
+	if email == "" {
+		log.Printf("ldap: entry %s has no %s attribute", entry.DN, a.cfg.EmailAttribute)
+		return nil, ErrInvalidCredentials
+	}
+	name := entry.GetAttributeValue(a.cfg.NameAttribute)
+
+	user, err := a.userRepo.GetUserByEmail(ctx, email)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Directory users get a random password so the local authenticator can never match
+		random, err := utils.RandomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		hashed, err := utils.HashPassword(random)
+		if err != nil {
+			return nil, err
+		}
+		user = &models.User{Name: name, Email: email, Password: hashed, AuthSource: a.Name()}
+		if err := a.userRepo.Create(ctx, user); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case user.AuthSource != a.Name():
+		// Never silently take over a local account that happens to share the email
+		log.Printf("ldap: refusing login for %s, a local account with that email exists", email)
+		return nil, ErrInvalidCredentials
+	default:
+		if name != "" && user.Name != name {
+			user.Name = name
+			if err := a.userRepo.Update(ctx, user.ID, user); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := a.syncRoles(ctx, user, entry.GetAttributeValues(a.cfg.GroupAttribute)); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// syncRoles replaces the user's roles with the ones mapped from their directory groups.
+// The directory is the source of truth, so roles granted locally are dropped.
+func (a *ldapAuthenticator) syncRoles(ctx context.Context, user *models.User, groups []string) error {
+	if len(a.cfg.GroupRoles) == 0 {
+		return nil
+	}
+
+	var roles []models.Role
+	seen := map[string]bool{}
+	for _, group := range groups {
+		roleName, ok := a.cfg.GroupRoles[strings.ToLower(group)]
+		if !ok || seen[roleName] {
+			continue
+		}
+		seen[roleName] = true
+
+		role, err := a.roleRepo.GetRoleByName(ctx, roleName)
+		if err != nil {
+			log.Printf("ldap: group %s maps to unknown role %q", group, roleName)
+			continue
+		}
+		roles = append(roles, *role)
+	}
+
+	if err := a.userRepo.ReplaceUserRoles(ctx, user, roles); err != nil {
+		return err
+	}
+	user.Roles = roles
+	return nil
+}
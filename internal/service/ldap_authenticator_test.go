@@ -0,0 +1,435 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/dbtest"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/repository"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+)
+
+const (
+	testLDAPBaseDN       = "dc=example,dc=com"
+	testLDAPBindDN       = "cn=svc,dc=example,dc=com"
+	testLDAPBindPassword = "svc-secret"
+	testLDAPAdminsGroup  = "cn=Admins,ou=groups,dc=example,dc=com"
+	testLDAPEditorsGroup = "cn=Editors,ou=groups,dc=example,dc=com"
+)
+
+// fakeDirectory is a minimal in-process LDAP server. It understands simple binds,
+// searches with equality, present, and, or and not filters, and unbinds.
+type fakeDirectory struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	entries []*fakeEntry
+	filters []string // every search filter received, decompiled
+}
+
+type fakeEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+func newFakeDirectory(t *testing.T, entries ...*fakeEntry) *fakeDirectory {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeDirectory{listener: listener, entries: entries}
+	go d.serve()
+	t.Cleanup(func() { listener.Close() })
+	return d
+}
+
+func (d *fakeDirectory) URL() string {
+	return "ldap://" + d.listener.Addr().String()
+}
+
+func (d *fakeDirectory) Filters() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.filters...)
+}
+
+func (d *fakeDirectory) serve() {
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			return
+		}
+		go d.handle(conn)
+	}
+}
+
+func (d *fakeDirectory) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := op.Children[1].Data.String()
+			password := op.Children[2].Data.String()
+			d.write(conn, messageID, ldap.ApplicationBindResponse, d.bind(dn, password))
+		case ldap.ApplicationSearchRequest:
+			d.search(conn, messageID, op)
+		default: // unbind, or anything we do not support
+			return
+		}
+	}
+}
+
+// SetAttribute replaces the values of an attribute of the entry with the given DN
+func (d *fakeDirectory) SetAttribute(dn, attribute string, values ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, entry := range d.entries {
+		if strings.EqualFold(entry.dn, dn) {
+			entry.attributes[attribute] = values
+		}
+	}
+}
+
+func (d *fakeDirectory) bind(dn, password string) uint16 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if strings.EqualFold(dn, testLDAPBindDN) && password == testLDAPBindPassword {
+		return ldap.LDAPResultSuccess
+	}
+	for _, entry := range d.entries {
+		if strings.EqualFold(entry.dn, dn) && password != "" && entry.password == password {
+			return ldap.LDAPResultSuccess
+		}
+	}
+	return ldap.LDAPResultInvalidCredentials
+}
+
+func (d *fakeDirectory) search(conn net.Conn, messageID int64, op *ber.Packet) {
+	baseDN := strings.ToLower(op.Children[0].Data.String())
+	sizeLimit := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+
+	decompiled, err := ldap.DecompileFilter(filter)
+	if err != nil {
+		d.write(conn, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.filters = append(d.filters, decompiled)
+
+	sent := int64(0)
+	for _, entry := range d.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.dn), baseDN) || !entry.matches(filter) {
+			continue
+		}
+		if sizeLimit > 0 && sent == sizeLimit {
+			d.write(conn, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSizeLimitExceeded)
+			return
+		}
+		d.writeEntry(conn, messageID, entry)
+		sent++
+	}
+	d.write(conn, messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess)
+}
+
+func (e *fakeEntry) matches(filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !e.matches(child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if e.matches(child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !e.matches(filter.Children[0])
+	case ldap.FilterPresent:
+		return len(e.values(filter.Data.String())) > 0
+	case ldap.FilterEqualityMatch:
+		want := filter.Children[1].Data.String()
+		for _, value := range e.values(filter.Children[0].Data.String()) {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func (e *fakeEntry) values(attribute string) []string {
+	for name, values := range e.attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+	return nil
+}
+
+func (d *fakeDirectory) write(conn net.Conn, messageID int64, tag ber.Tag, resultCode uint16) {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(resultCode), "resultCode"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	d.send(conn, messageID, result)
+}
+
+func (d *fakeDirectory) writeEntry(conn net.Conn, messageID int64, entry *fakeEntry) {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "objectName"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range entry.attributes {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "partialAttribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	result.AppendChild(attributes)
+	d.send(conn, messageID, result)
+}
+
+func (d *fakeDirectory) send(conn io.Writer, messageID int64, op *ber.Packet) {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	envelope.AppendChild(op)
+	conn.Write(envelope.Bytes())
+}
+
+// ----------------------------------------------------------
+// TESTS
+// ----------------------------------------------------------
+
+type ldapFixture struct {
+	db        *gorm.DB
+	directory *fakeDirectory
+	auth      Authenticator
+	userRepo  repository.UserRepository
+}
+
+func newLDAPFixture(t *testing.T) *ldapFixture {
+	t.Helper()
+	db := dbtest.Open(t)
+	userRepo := repository.NewUserRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	for _, name := range []string{"admin", "editor"} {
+		if err := roleRepo.Create(context.Background(), &models.Role{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	directory := newFakeDirectory(t,
+		&fakeEntry{
+			dn:       "uid=jane,ou=people,dc=example,dc=com",
+			password: "jane-secret",
+			attributes: map[string][]string{
+				"mail":     {"Jane@Example.com"},
+				"cn":       {"Jane Doe"},
+				"memberOf": {testLDAPAdminsGroup, "cn=Unmapped,ou=groups,dc=example,dc=com"},
+			},
+		},
+		&fakeEntry{
+			dn:       "uid=john,ou=people,dc=example,dc=com",
+			password: "john-secret",
+			attributes: map[string][]string{
+				"mail": {"john@example.com"},
+				"cn":   {"John Doe"},
+			},
+		},
+	)
+
+	cfg := &config.LDAPConfig{
+		Enabled:        true,
+		URL:            directory.URL(),
+		BindDN:         testLDAPBindDN,
+		BindPassword:   testLDAPBindPassword,
+		BaseDN:         testLDAPBaseDN,
+		UserFilter:     "(mail=%s)",
+		EmailAttribute: "mail",
+		NameAttribute:  "cn",
+		GroupAttribute: "memberOf",
+		GroupRoles: map[string]string{
+			strings.ToLower(testLDAPAdminsGroup):  "admin",
+			strings.ToLower(testLDAPEditorsGroup): "editor",
+		},
+	}
+	return &ldapFixture{
+		db:        db,
+		directory: directory,
+		auth:      NewLDAPAuthenticator(cfg, userRepo, roleRepo),
+		userRepo:  userRepo,
+	}
+}
+
+func (f *ldapFixture) roleNames(t *testing.T, userID uint) []string {
+	t.Helper()
+	var user models.User
+	if err := f.db.Preload("Roles").First(&user, userID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, role := range user.Roles {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+func TestLDAPAuthenticateProvisionsUser(t *testing.T) {
+	f := newLDAPFixture(t)
+	ctx := context.Background()
+
+	user, err := f.auth.Authenticate(ctx, "jane@example.com", "jane-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if user.ID == 0 || user.Email != "jane@example.com" || user.Name != "Jane Doe" || user.AuthSource != "ldap" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+
+	stored, err := f.userRepo.GetUserByEmail(ctx, "jane@example.com")
+	if err != nil {
+		t.Fatalf("user was not provisioned: %v", err)
+	}
+	if stored.ID != user.ID || stored.AuthSource != "ldap" {
+		t.Fatalf("unexpected stored user %+v", stored)
+	}
+
+	// The second login finds the same user instead of creating another one
+	again, err := f.auth.Authenticate(ctx, "jane@example.com", "jane-secret")
+	if err != nil {
+		t.Fatalf("second Authenticate: %v", err)
+	}
+	if again.ID != user.ID {
+		t.Fatalf("second login returned user %d, want %d", again.ID, user.ID)
+	}
+}
+
+func TestLDAPAuthenticateRejectsBadCredentials(t *testing.T) {
+	f := newLDAPFixture(t)
+	ctx := context.Background()
+
+	cases := map[string][2]string{
+		"wrong password": {"jane@example.com", "wrong"},
+		"empty password": {"jane@example.com", ""},
+		"unknown login":  {"nobody@example.com", "jane-secret"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := f.auth.Authenticate(ctx, c[0], c[1]); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("got %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+
+	if _, err := f.userRepo.GetUserByEmail(ctx, "jane@example.com"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("failed logins must not provision a user, got %v", err)
+	}
+}
+
+func TestLDAPServiceBindFailure(t *testing.T) {
+	f := newLDAPFixture(t)
+	a := f.auth.(*ldapAuthenticator)
+	cfg := *a.cfg
+	cfg.BindPassword = "wrong"
+	a.cfg = &cfg
+
+	_, err := f.auth.Authenticate(context.Background(), "jane@example.com", "jane-secret")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("a broken service account must be reported as an error, got %v", err)
+	}
+}
+
+func TestLDAPFilterEscaping(t *testing.T) {
+	f := newLDAPFixture(t)
+
+	// Unescaped, this login would turn the filter into (|(mail=*)(mail=*)) and match everyone
+	login := "*)(mail=*"
+	if _, err := f.auth.Authenticate(context.Background(), login, "jane-secret"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("got %v, want ErrInvalidCredentials", err)
+	}
+
+	filters := f.directory.Filters()
+	if len(filters) != 1 {
+		t.Fatalf("got %d searches, want 1", len(filters))
+	}
+	if want := `(mail=\2a\29\28mail=\2a)`; filters[0] != want {
+		t.Fatalf("filter = %s, want %s", filters[0], want)
+	}
+}
+
+func TestLDAPGroupRoleMapping(t *testing.T) {
+	f := newLDAPFixture(t)
+	ctx := context.Background()
+
+	user, err := f.auth.Authenticate(ctx, "jane@example.com", "jane-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if got := f.roleNames(t, user.ID); len(got) != 1 || got[0] != "admin" {
+		t.Fatalf("roles = %v, want [admin]", got)
+	}
+
+	// The directory is the source of truth: a changed group membership replaces the roles
+	f.directory.SetAttribute("uid=jane,ou=people,dc=example,dc=com", "memberOf", testLDAPEditorsGroup)
+	if _, err := f.auth.Authenticate(ctx, "jane@example.com", "jane-secret"); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if got := f.roleNames(t, user.ID); len(got) != 1 || got[0] != "editor" {
+		t.Fatalf("roles = %v, want [editor]", got)
+	}
+}
+
+func TestLDAPRefusesLocalAccountTakeover(t *testing.T) {
+	f := newLDAPFixture(t)
+	ctx := context.Background()
+
+	local := &models.User{Name: "Local John", Email: "john@example.com", Password: "hash", AuthSource: "local"}
+	if err := f.userRepo.Create(ctx, local); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.auth.Authenticate(ctx, "john@example.com", "john-secret"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("got %v, want ErrInvalidCredentials", err)
+	}
+
+	stored, err := f.userRepo.GetUserByEmail(ctx, "john@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.AuthSource != "local" || stored.Name != "Local John" {
+		t.Fatalf("local account was modified: %+v", stored)
+	}
+}
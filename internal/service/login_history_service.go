@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/repository"
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// LoginAttempt describes a login as seen by the HTTP layer
+type LoginAttempt struct {
+	Email          string
+	User           *models.User // nil when the login failed
+	Method         string
+	FailureReason  string
+	IP             string
+	UserAgent      string
+	AcceptLanguage string
+	DeviceID       string // optional, sent by clients that persist their own device id
+	Lang           string // locale for the alert email
+}
+
+type LoginHistoryService interface {
+	RecordLogin(ctx context.Context, attempt LoginAttempt) error
+	GetLoginHistory(ctx context.Context, userID uint, p utils.PaginationParams) ([]models.LoginEvent, utils.PageInfo, error)
+}
+
+type loginHistoryService struct {
+	repo         repository.LoginEventRepository
+	userRepo     repository.UserRepository
+	emailService *EmailService
+	geo          GeoLocator
+}
+
+func NewLoginHistoryService(repo repository.LoginEventRepository, userRepo repository.UserRepository, es *EmailService, geo GeoLocator) LoginHistoryService {
+	return &loginHistoryService{
+		repo:         repo,
+		userRepo:     userRepo,
+		emailService: es,
+		geo:          geo,
+	}
+}
+
+// RecordLogin persists the attempt and alerts the user when a successful login
+// comes from a device or network we have not seen for them before.
+func (s *loginHistoryService) RecordLogin(ctx context.Context, attempt LoginAttempt) error {
+	fingerprint := deviceFingerprint(attempt)
+	country, city := s.geo.Lookup(attempt.IP)
+
+	event := &models.LoginEvent{
+		Email:         attempt.Email,
+		Success:       attempt.User != nil,
+		Method:        attempt.Method,
+		FailureReason: attempt.FailureReason,
+		IP:            attempt.IP,
+		IPPrefix:      ipPrefix(attempt.IP),
+		UserAgent:     truncate(attempt.UserAgent, 255),
+		DeviceID:      fingerprint,
+		Country:       country,
+		City:          truncate(city, 100),
+	}
+
+	if attempt.User == nil {
+		// Failed attempts are still linked to the account when the email exists
+		if user, err := s.userRepo.GetUserByEmail(ctx, attempt.Email); err == nil {
+			event.UserID = user.ID
+		}
+		return s.repo.CreateLoginEvent(ctx, event)
+	}
+	event.UserID = attempt.User.ID
+
+	// Must be checked before the event is stored, or the current login would count as "seen"
+	suspicious, err := s.isUnfamiliar(ctx, attempt.User.ID, fingerprint, event.IPPrefix)
+	if err != nil {
+		return err
+	}
+	event.NewDevice = suspicious
+
+	if err := s.repo.CreateLoginEvent(ctx, event); err != nil {
+		return err
+	}
+	if err := s.rememberDevice(ctx, attempt.User.ID, fingerprint, event); err != nil {
+		return err
+	}
+
+	if suspicious {
+		go func() {
+			err := s.emailService.SendNewLoginAlertEmail(attempt.User.Email, attempt.Lang, NewLoginAlert{
+				Time:     event.CreatedAt,
+				IP:       event.IP,
+				Location: formatLocation(city, country),
+				Device:   event.UserAgent,
+			})
+			if err != nil {
+				log.Printf("failed to send new login alert to user %d: %v", attempt.User.ID, err)
+			}
+		}()
+	}
+	return nil
+}
+
+func (s *loginHistoryService) GetLoginHistory(ctx context.Context, userID uint, p utils.PaginationParams) ([]models.LoginEvent, utils.PageInfo, error) {
+	return s.repo.GetLoginEventsByUserID(ctx, userID, p)
+}
+
+// isUnfamiliar is true for a new device or network, except on the very first login
+func (s *loginHistoryService) isUnfamiliar(ctx context.Context, userID uint, fingerprint, prefix string) (bool, error) {
+	previous, err := s.repo.CountSuccessfulLogins(ctx, userID)
+	if err != nil || previous == 0 {
+		return false, err
+	}
+
+	_, err = s.repo.GetKnownDevice(ctx, userID, fingerprint)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	knownNetwork, err := s.repo.HasLoggedInFromNetwork(ctx, userID, prefix)
+	if err != nil {
+		return false, err
+	}
+	return !knownNetwork, nil
+}
+
+func (s *loginHistoryService) rememberDevice(ctx context.Context, userID uint, fingerprint string, event *models.LoginEvent) error {
+	device, err := s.repo.GetKnownDevice(ctx, userID, fingerprint)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		device = &models.KnownDevice{UserID: userID, Fingerprint: fingerprint}
+	} else if err != nil {
+		return err
+	}
+	device.UserAgent = event.UserAgent
+	device.LastIP = event.IP
+	device.LastSeenAt = time.Now()
+	return s.repo.SaveKnownDevice(ctx, device)
+}
+
+// deviceFingerprint prefers a client supplied device id and falls back to
+// hashing the headers a browser sends consistently
+func deviceFingerprint(attempt LoginAttempt) string {
+	source := attempt.DeviceID
+	if source == "" {
+		source = attempt.UserAgent + "|" + attempt.AcceptLanguage
+	}
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// ipPrefix returns the /24 (IPv4) or /48 (IPv6) network of an address
+func ipPrefix(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
+
+func formatLocation(city, country string) string {
+	parts := []string{}
+	if city != "" {
+		parts = append(parts, city)
+	}
+	if country != "" {
+		parts = append(parts, country)
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// truncate cuts s to at most max bytes without splitting a UTF-8 character
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"Yangon", 100, "Yangon"},
+		{"Yangon", 4, "Yang"},
+		{"München", 2, "M"},  // "ü" is two bytes
+		{"München", 3, "Mü"}, // cut right after it
+		{"ရန်ကုန်", 4, "ရ"},  // three-byte characters
+		{"😀x", 3, ""},
+	}
+	for _, c := range cases {
+		got := truncate(c.in, c.max)
+		if got != c.want || !utf8.ValidString(got) || len(got) > c.max {
+			t.Errorf("truncate(%q, %d) = %q, want %q", c.in, c.max, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,764 @@
+package service
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/repository"
+	"golang-api-template/internal/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// SupportedScopes are the scopes our OpenID Connect provider understands
+var SupportedScopes = []string{"openid", "profile", "email", "offline_access"}
+
+// PermissionManageOAuthClients is required to register relying parties. Holders see and
+// delete every client; anyone else calling the service only gets the clients they own.
+const PermissionManageOAuthClients = "oauth.manage_clients"
+
+var ErrOAuthClientNotFound = errors.New("oauth client not found")
+
+// accessTokenType is the JWT "typ" of the access tokens handed to relying parties
+// (RFC 9068); it keeps ID tokens, signed with the same key, from passing as one
+const accessTokenType = "at+jwt"
+
+// OAuthError maps directly onto an RFC 6749 error response
+type OAuthError struct {
+	Status      int
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(status int, code, description string) *OAuthError {
+	return &OAuthError{Status: status, Code: code, Description: description}
+}
+
+// AuthorizeRequest holds the parameters of an authorization request
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	Nonce               string `form:"nonce" json:"nonce"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Prompt              string `form:"prompt" json:"prompt"`
+}
+
+// AuthorizeResult is either a redirect back to the client or a request for consent
+type AuthorizeResult struct {
+	RedirectTo      string              `json:"redirect_to,omitempty"`
+	ConsentRequired bool                `json:"consent_required"`
+	Client          *models.OAuthClient `json:"client,omitempty"`
+	Scopes          []string            `json:"scopes,omitempty"`
+}
+
+// TokenRequest holds the parameters of a token endpoint request
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+}
+
+// TokenResponse is the RFC 6749 token endpoint response
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type OIDCService interface {
+	Discovery() map[string]interface{}
+	JWKS() map[string]interface{}
+
+	Authorize(ctx context.Context, userID uint, authTime int64, req AuthorizeRequest, approve *bool) (*AuthorizeResult, error)
+	Exchange(ctx context.Context, req TokenRequest) (*TokenResponse, error)
+	UserInfo(ctx context.Context, userID uint, scope string) (map[string]interface{}, error)
+
+	// ValidateAccessToken checks an access token issued to a relying party. These are
+	// signed with the provider key, so the first-party API never accepts them.
+	ValidateAccessToken(tokenStr string) (uint, jwt.MapClaims, error)
+
+	RegisterClient(ctx context.Context, ownerID uint, name string, redirectURIs, scopes []string, public bool) (*models.OAuthClient, string, error)
+	GetClients(ctx context.Context, actorID uint) ([]models.OAuthClient, error)
+	DeleteClient(ctx context.Context, actorID, id uint) error
+	GetConsents(ctx context.Context, userID uint) ([]models.OAuthConsent, error)
+	RevokeConsent(ctx context.Context, userID, clientID uint) error
+}
+
+type oidcService struct {
+	oauthRepo repository.OAuthRepository
+	userRepo  repository.UserRepository
+	accounts  AccountService
+	rdb       *redis.Client
+	cfg       *config.Config
+	key       *rsa.PrivateKey
+	keyID     string
+}
+
+// authCode is what we keep in Redis between the authorize and token requests
+type authCode struct {
+	ClientID            string `json:"client_id"`
+	UserID              uint   `json:"user_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	Nonce               string `json:"nonce"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	AuthTime            int64  `json:"auth_time"`
+}
+
+// refreshGrant is what we keep in Redis for a refresh token handed out to a relying party.
+// These tokens live apart from the first-party ones, so /auth/refresh never accepts them.
+type refreshGrant struct {
+	UserID   uint   `json:"user_id"`
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+	AuthTime int64  `json:"auth_time"`
+}
+
+func NewOIDCService(
+	oauthRepo repository.OAuthRepository,
+	userRepo repository.UserRepository,
+	accounts AccountService,
+	rdb *redis.Client,
+	cfg *config.Config,
+	key *rsa.PrivateKey,
+) OIDCService {
+	// The key ID is derived from the public modulus so it changes whenever the key does
+	sum := sha256.Sum256(key.PublicKey.N.Bytes())
+	return &oidcService{
+		oauthRepo: oauthRepo,
+		userRepo:  userRepo,
+		accounts:  accounts,
+		rdb:       rdb,
+		cfg:       cfg,
+		key:       key,
+		keyID:     base64.RawURLEncoding.EncodeToString(sum[:8]),
+	}
+}
+
+// ----------------------------------------------------------
+// DISCOVERY
+// ----------------------------------------------------------
+func (s *oidcService) Discovery() map[string]interface{} {
+	issuer := s.cfg.OIDC.Issuer
+	return map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/api/v1/oauth/authorize",
+		"token_endpoint":                        issuer + "/api/v1/oauth/token",
+		"userinfo_endpoint":                     issuer + "/api/v1/oauth/userinfo",
+		"jwks_uri":                              issuer + "/api/v1/oauth/jwks",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      SupportedScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported":                      []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "name", "email", "email_verified"},
+	}
+}
+
+func (s *oidcService) JWKS() map[string]interface{} {
+	pub := s.key.PublicKey
+	return map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": s.keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	}
+}
+
+// ----------------------------------------------------------
+// AUTHORIZE
+// ----------------------------------------------------------
+
+// Authorize validates an authorization request for an already authenticated user.
+// approve is nil when the user has not answered a consent prompt yet.
+func (s *oidcService) Authorize(ctx context.Context, userID uint, authTime int64, req AuthorizeRequest, approve *bool) (*AuthorizeResult, error) {
+	// 1. Client & redirect URI must be valid before we are allowed to redirect anywhere
+	client, err := s.oauthRepo.GetClientByClientID(ctx, req.ClientID)
+	if err != nil {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_request", "unknown client_id")
+	}
+	if !containsString(client.RedirectURIList(), req.RedirectURI) {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_request", "redirect_uri is not registered for this client")
+	}
+
+	// 2. From here on, errors are reported back to the client through the redirect
+	if req.ResponseType != "code" {
+		return s.redirectError(req, "unsupported_response_type", "only the code response type is supported")
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if !containsString(scopes, "openid") {
+		return s.redirectError(req, "invalid_scope", "the openid scope is required")
+	}
+	allowed := strings.Fields(client.Scopes)
+	for _, scope := range scopes {
+		if !containsString(SupportedScopes, scope) || (len(allowed) > 0 && !containsString(allowed, scope)) {
+			return s.redirectError(req, "invalid_scope", "scope "+scope+" is not allowed")
+		}
+	}
+
+	// 3. PKCE is mandatory for public clients, and only S256 is accepted: "plain" (also
+	// the default when the method is left out) puts the verifier itself in the URL
+	if req.CodeChallenge == "" && client.Public {
+		return s.redirectError(req, "invalid_request", "code_challenge is required for public clients")
+	}
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != "S256" {
+		return s.redirectError(req, "invalid_request", "code_challenge_method must be S256")
+	}
+
+	// 4. Consent
+	switch {
+	case approve != nil && !*approve:
+		return s.redirectError(req, "access_denied", "the user denied the request")
+	case approve != nil && *approve:
+		if err := s.saveConsent(ctx, userID, client.ID, scopes); err != nil {
+			return nil, err
+		}
+	default:
+		consent, err := s.oauthRepo.GetConsent(ctx, userID, client.ID)
+		granted := err == nil && containsAll(strings.Fields(consent.Scopes), scopes)
+		if !granted || req.Prompt == "consent" {
+			if req.Prompt == "none" {
+				return s.redirectError(req, "consent_required", "the user has not granted the requested scopes")
+			}
+			return &AuthorizeResult{ConsentRequired: true, Client: client, Scopes: scopes}, nil
+		}
+	}
+
+	// 5. Issue a single-use authorization code
+	code, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(authCode{
+		ClientID:            client.ClientID,
+		UserID:              userID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               strings.Join(scopes, " "),
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		AuthTime:            authTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	codeTTL := time.Duration(s.cfg.OIDC.AuthCodeExpireSec) * time.Second
+	if err := s.rdb.Set(ctx, authCodeKey(code), payload, codeTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store authorization code: %w", err)
+	}
+
+	return &AuthorizeResult{RedirectTo: buildRedirect(req.RedirectURI, url.Values{"code": {code}}, req.State)}, nil
+}
+
+func (s *oidcService) redirectError(req AuthorizeRequest, code, description string) (*AuthorizeResult, error) {
+	params := url.Values{"error": {code}, "error_description": {description}}
+	return &AuthorizeResult{RedirectTo: buildRedirect(req.RedirectURI, params, req.State)}, nil
+}
+
+func (s *oidcService) saveConsent(ctx context.Context, userID, clientID uint, scopes []string) error {
+	consent, err := s.oauthRepo.GetConsent(ctx, userID, clientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		consent = &models.OAuthConsent{UserID: userID, ClientID: clientID}
+	} else if err != nil {
+		return err
+	}
+
+	granted := strings.Fields(consent.Scopes)
+	for _, scope := range scopes {
+		if !containsString(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	consent.Scopes = strings.Join(granted, " ")
+	return s.oauthRepo.SaveConsent(ctx, consent)
+}
+
+// ----------------------------------------------------------
+// TOKEN
+// ----------------------------------------------------------
+func (s *oidcService) Exchange(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case "authorization_code":
+		return s.exchangeCode(ctx, client, req)
+	case "refresh_token":
+		return s.exchangeRefreshToken(ctx, client, req)
+	default:
+		return nil, newOAuthError(http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+	}
+}
+
+func (s *oidcService) authenticateClient(ctx context.Context, clientID, clientSecret string) (*models.OAuthClient, error) {
+	client, err := s.oauthRepo.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, newOAuthError(http.StatusUnauthorized, "invalid_client", "unknown client")
+	}
+	if !client.Public {
+		if clientSecret == "" || bcrypt.CompareHashAndPassword([]byte(client.ClientSecret), []byte(clientSecret)) != nil {
+			return nil, newOAuthError(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		}
+	}
+	return client, nil
+}
+
+func (s *oidcService) exchangeCode(ctx context.Context, client *models.OAuthClient, req TokenRequest) (*TokenResponse, error) {
+	// GETDEL makes the code single-use even under concurrent requests
+	payload, err := s.rdb.GetDel(ctx, authCodeKey(req.Code)).Result()
+	if err == redis.Nil {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "authorization code is invalid or expired")
+	} else if err != nil {
+		return nil, err
+	}
+
+	var code authCode
+	if err := json.Unmarshal([]byte(payload), &code); err != nil {
+		return nil, err
+	}
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "authorization code was issued to another client or redirect_uri")
+	}
+	if code.CodeChallenge != "" && !verifyCodeChallenge(code.CodeChallenge, req.CodeVerifier) {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "code_verifier does not match the code_challenge")
+	}
+
+	// The account may have been suspended since the code was issued
+	if err := s.accounts.CheckActive(ctx, code.UserID); err != nil {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", err.Error())
+	}
+	user, err := s.userRepo.GetByID(ctx, code.UserID)
+	if err != nil {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "user no longer exists")
+	}
+
+	accessToken, err := s.createAccessToken(user.ID, client.ClientID, code.Scope, code.AuthTime)
+	if err != nil {
+		return nil, err
+	}
+	// A refresh token is only issued when the relying party asked for offline access
+	var refreshToken string
+	if containsString(strings.Fields(code.Scope), "offline_access") {
+		refreshToken, err = s.issueRefreshToken(ctx, client, refreshGrant{
+			UserID:   user.ID,
+			ClientID: client.ClientID,
+			Scope:    code.Scope,
+			AuthTime: code.AuthTime,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	idToken, err := s.createIDToken(user, client.ClientID, code.Scope, code.Nonce, code.AuthTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    s.cfg.AccessTokenExpireMin * 60,
+		RefreshToken: refreshToken,
+		IDToken:      idToken,
+		Scope:        code.Scope,
+	}, nil
+}
+
+func (s *oidcService) exchangeRefreshToken(ctx context.Context, client *models.OAuthClient, req TokenRequest) (*TokenResponse, error) {
+	// 1. Refresh tokens are single use: take the grant out of Redis before anything else
+	key := refreshGrantKey(req.RefreshToken)
+	payload, err := s.rdb.GetDel(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "invalid or expired refresh token")
+	} else if err != nil {
+		return nil, err
+	}
+	var grant refreshGrant
+	if err := json.Unmarshal([]byte(payload), &grant); err != nil {
+		return nil, err
+	}
+	s.rdb.SRem(ctx, clientGrantsKey(grant.UserID, client.ID), key)
+	if grant.ClientID != client.ClientID {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "refresh token was issued to another client")
+	}
+
+	// 2. The account must still be usable and the consent still in place
+	if err := s.accounts.CheckActive(ctx, grant.UserID); err != nil {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", err.Error())
+	}
+	consent, err := s.oauthRepo.GetConsent(ctx, grant.UserID, client.ID)
+	if err != nil || !containsAll(strings.Fields(consent.Scopes), strings.Fields(grant.Scope)) {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "consent has been revoked")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, grant.UserID)
+	if err != nil {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "user no longer exists")
+	}
+
+	// 3. Issue a fresh set of tokens, including a new refresh token
+	accessToken, err := s.createAccessToken(user.ID, client.ClientID, grant.Scope, grant.AuthTime)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.issueRefreshToken(ctx, client, grant)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := s.createIDToken(user, client.ClientID, grant.Scope, "", grant.AuthTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    s.cfg.AccessTokenExpireMin * 60,
+		RefreshToken: refreshToken,
+		IDToken:      idToken,
+		Scope:        grant.Scope,
+	}, nil
+}
+
+// issueRefreshToken stores a new opaque refresh token for a relying party.
+// Each token is also indexed per user and client so revoking the consent can drop them all.
+func (s *oidcService) issueRefreshToken(ctx context.Context, client *models.OAuthClient, grant refreshGrant) (string, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(grant)
+	if err != nil {
+		return "", err
+	}
+
+	key := refreshGrantKey(token)
+	grantsKey := clientGrantsKey(grant.UserID, client.ID)
+	refreshExp := time.Hour * time.Duration(s.cfg.RefreshTokenExpireHrs)
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, key, payload, refreshExp)
+	pipe.SAdd(ctx, grantsKey, key)
+	pipe.Expire(ctx, grantsKey, refreshExp)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to store refresh grant: %w", err)
+	}
+	return token, nil
+}
+
+// ----------------------------------------------------------
+// USERINFO
+// ----------------------------------------------------------
+
+// UserInfo returns the claims of a user filtered by scope.
+// An empty scope means a first-party token, which may see every claim.
+func (s *oidcService) UserInfo(ctx context.Context, userID uint, scope string) (map[string]interface{}, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if scope == "" {
+		scope = strings.Join(SupportedScopes, " ")
+	}
+	return userClaims(user, strings.Fields(scope)), nil
+}
+
+func (s *oidcService) ValidateAccessToken(tokenStr string) (uint, jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodRS256 || t.Header["typ"] != accessTokenType {
+			return nil, errors.New("not a client access token")
+		}
+		return &s.key.PublicKey, nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || !claims.VerifyIssuer(s.cfg.OIDC.Issuer, true) {
+		return 0, nil, errors.New("invalid access token")
+	}
+	sub, _ := claims["sub"].(string)
+	userID, err := strconv.ParseUint(sub, 10, 64)
+	if err != nil || userID == 0 {
+		return 0, nil, errors.New("invalid access token subject")
+	}
+	return uint(userID), claims, nil
+}
+
+// ----------------------------------------------------------
+// CLIENTS & CONSENTS
+// ----------------------------------------------------------
+
+// RegisterClient creates a relying party. The plain client secret is only returned once.
+func (s *oidcService) RegisterClient(ctx context.Context, ownerID uint, name string, redirectURIs, scopes []string, public bool) (*models.OAuthClient, string, error) {
+	if len(redirectURIs) == 0 {
+		return nil, "", errors.New("at least one redirect uri is required")
+	}
+	for _, uri := range redirectURIs {
+		if u, err := url.Parse(uri); err != nil || u.Scheme == "" || u.Host == "" || u.Fragment != "" {
+			return nil, "", fmt.Errorf("invalid redirect uri %q", uri)
+		}
+	}
+	for _, scope := range scopes {
+		if !containsString(SupportedScopes, scope) {
+			return nil, "", fmt.Errorf("unsupported scope %q", scope)
+		}
+	}
+
+	clientID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	client := &models.OAuthClient{
+		ClientID:     clientID,
+		Name:         name,
+		RedirectURIs: strings.Join(redirectURIs, " "),
+		Scopes:       strings.Join(scopes, " "),
+		Public:       public,
+		OwnerID:      ownerID,
+	}
+
+	var secret string
+	if !public {
+		secret, err = utils.RandomToken(32)
+		if err != nil {
+			return nil, "", err
+		}
+		hashed, err := utils.HashPassword(secret)
+		if err != nil {
+			return nil, "", err
+		}
+		client.ClientSecret = hashed
+	}
+
+	if err := s.oauthRepo.CreateClient(ctx, client); err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+// GetClients lists every client for managers and the actor's own clients otherwise
+func (s *oidcService) GetClients(ctx context.Context, actorID uint) ([]models.OAuthClient, error) {
+	manager, err := s.canManageClients(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if manager {
+		return s.oauthRepo.GetAllClients(ctx)
+	}
+	return s.oauthRepo.GetClientsByOwnerID(ctx, actorID)
+}
+
+// DeleteClient removes a client and its consents; only managers may delete clients they don't own
+func (s *oidcService) DeleteClient(ctx context.Context, actorID, id uint) error {
+	client, err := s.oauthRepo.GetClientByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrOAuthClientNotFound
+	} else if err != nil {
+		return err
+	}
+	if client.OwnerID != actorID {
+		manager, err := s.canManageClients(ctx, actorID)
+		if err != nil {
+			return err
+		}
+		if !manager {
+			// Don't reveal that the client exists
+			return ErrOAuthClientNotFound
+		}
+	}
+	return s.oauthRepo.DeleteClient(ctx, id)
+}
+
+func (s *oidcService) canManageClients(ctx context.Context, userID uint) (bool, error) {
+	permissions, err := s.userRepo.GetPermissionsByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, perm := range permissions {
+		if perm.Name == PermissionManageOAuthClients {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *oidcService) GetConsents(ctx context.Context, userID uint) ([]models.OAuthConsent, error) {
+	return s.oauthRepo.GetConsentsByUserID(ctx, userID)
+}
+
+// RevokeConsent deletes the consent and every refresh token the client still holds for the user
+func (s *oidcService) RevokeConsent(ctx context.Context, userID, clientID uint) error {
+	if err := s.oauthRepo.DeleteConsent(ctx, userID, clientID); err != nil {
+		return err
+	}
+
+	grantsKey := clientGrantsKey(userID, clientID)
+	keys, err := s.rdb.SMembers(ctx, grantsKey).Result()
+	if err != nil {
+		return err
+	}
+	return s.rdb.Del(ctx, append(keys, grantsKey)...).Err()
+}
+
+// ----------------------------------------------------------
+// HELPERS
+// ----------------------------------------------------------
+
+// createAccessToken signs an access token for a relying party with the provider key.
+// Its audience is the client, and it only carries the granted scopes.
+func (s *oidcService) createAccessToken(userID uint, clientID, scope string, authTime int64) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       s.cfg.OIDC.Issuer,
+		"sub":       strconv.FormatUint(uint64(userID), 10),
+		"aud":       clientID,
+		"client_id": clientID,
+		"scope":     scope,
+		"iat":       now.Unix(),
+		"exp":       now.Add(time.Minute * time.Duration(s.cfg.AccessTokenExpireMin)).Unix(),
+	}
+	if authTime > 0 {
+		claims["auth_time"] = authTime
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["typ"] = accessTokenType
+	token.Header["kid"] = s.keyID
+	return token.SignedString(s.key)
+}
+
+func (s *oidcService) createIDToken(user *models.User, clientID, scope, nonce string, authTime int64) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{}
+	for k, v := range userClaims(user, strings.Fields(scope)) {
+		claims[k] = v
+	}
+	claims["iss"] = s.cfg.OIDC.Issuer
+	claims["aud"] = clientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(time.Minute * time.Duration(s.cfg.OIDC.IDTokenExpireMin)).Unix()
+	if authTime > 0 {
+		claims["auth_time"] = authTime
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.keyID
+	return token.SignedString(s.key)
+}
+
+func userClaims(user *models.User, scopes []string) map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+	}
+	if containsString(scopes, "profile") {
+		claims["name"] = user.Name
+	}
+	if containsString(scopes, "email") {
+		claims["email"] = user.Email
+		claims["email_verified"] = false
+	}
+	return claims
+}
+
+// verifyCodeChallenge checks an S256 challenge: BASE64URL(SHA256(verifier))
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if verifier == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func buildRedirect(redirectURI string, params url.Values, state string) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	if state != "" {
+		q.Set("state", state)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func authCodeKey(code string) string {
+	return "oidc:code:" + code
+}
+
+func refreshGrantKey(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return "oidc:refresh:" + base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func clientGrantsKey(userID, clientID uint) string {
+	return fmt.Sprintf("oidc:grants:%d:%d", userID, clientID)
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(list, values []string) bool {
+	for _, v := range values {
+		if !containsString(list, v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/realtime"
+	"golang-api-template/internal/repository"
+	"golang-api-template/internal/storage"
+	"golang-api-template/internal/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// PermissionEraseUsers is required to erase another user's personal data
+const PermissionEraseUsers = "users.erase"
+
+// Data export event, published on the user's private topic when the archive is ready
+const EventDataExportReady = "data_export.ready"
+
+var (
+	ErrExportInProgress    = errors.New("a data export is already being prepared")
+	ErrExportNotReady      = errors.New("data export is not ready or has expired")
+	ErrInvalidExportFormat = errors.New(`format must be "json" or "csv"`)
+)
+
+// PrivacyService answers subject-access (export) and erasure requests
+type PrivacyService interface {
+	// RequestExport queues an archive of the user's data, built in the background
+	RequestExport(ctx context.Context, userID uint, format, lang string) (*models.DataExport, error)
+	ListExports(ctx context.Context, userID uint) ([]models.DataExport, error)
+	// GetReadyExport returns an export whose archive can be downloaded
+	GetReadyExport(ctx context.Context, userID, id uint) (*models.DataExport, error)
+	// DownloadURL returns a signed link to the export's archive and when it expires
+	DownloadURL(export *models.DataExport) (string, time.Time)
+	// OpenSignedExport checks a signed download link and opens the archive; the caller closes the reader
+	OpenSignedExport(ctx context.Context, id uint, expires, signature string) (*models.DataExport, io.ReadCloser, error)
+	PurgeExpiredExports(ctx context.Context) (int, error)
+
+	// EraseUser anonymizes the user's personal data and records the erasure in the audit log
+	EraseUser(ctx context.Context, actorID, userID uint, reason string) error
+}
+
+type privacyService struct {
+	repo         repository.PrivacyRepository
+	audit        AuditService
+	profiles     ProfileService
+	files        FileService
+	backend      storage.Backend
+	signer       *storage.Signer
+	emailService *EmailService
+	publisher    EventPublisher
+	rdb          *redis.Client
+	cfg          *config.Config
+}
+
+func NewPrivacyService(
+	repo repository.PrivacyRepository,
+	audit AuditService,
+	profiles ProfileService,
+	files FileService,
+	backend storage.Backend,
+	es *EmailService,
+	publisher EventPublisher,
+	rdb *redis.Client,
+	cfg *config.Config,
+) PrivacyService {
+	if publisher == nil {
+		publisher = noopPublisher{}
+	}
+	return &privacyService{
+		repo:         repo,
+		audit:        audit,
+		profiles:     profiles,
+		files:        files,
+		backend:      backend,
+		signer:       storage.NewSigner(cfg.Storage.SigningSecret),
+		emailService: es,
+		publisher:    publisher,
+		rdb:          rdb,
+		cfg:          cfg,
+	}
+}
+
+// ----------------------------------------------------------
+// DATA EXPORT
+// ----------------------------------------------------------
+
+func (s *privacyService) RequestExport(ctx context.Context, userID uint, format, lang string) (*models.DataExport, error) {
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		return nil, ErrInvalidExportFormat
+	}
+
+	// 1. One export at a time per user
+	_, err := s.repo.GetUnfinishedDataExport(ctx, userID, time.Now().Add(-time.Hour))
+	if err == nil {
+		return nil, ErrExportInProgress
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 2. Queue it and build the archive in the background
+	export := &models.DataExport{UserID: userID, Format: format, Status: models.DataExportPending}
+	if err := s.repo.CreateDataExport(ctx, export); err != nil {
+		return nil, err
+	}
+	// The build outlives the request, so it must not be cancelled with it
+	job := *export
+	go s.buildExport(context.WithoutCancel(ctx), &job, lang)
+
+	return export, nil
+}
+
+func (s *privacyService) ListExports(ctx context.Context, userID uint) ([]models.DataExport, error) {
+	return s.repo.GetDataExportsByUserID(ctx, userID)
+}
+
+func (s *privacyService) GetReadyExport(ctx context.Context, userID, id uint) (*models.DataExport, error) {
+	export, err := s.repo.GetDataExport(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != models.DataExportReady || export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		return nil, ErrExportNotReady
+	}
+	return export, nil
+}
+
+func (s *privacyService) DownloadURL(export *models.DataExport) (string, time.Time) {
+	expires := time.Now().Add(s.cfg.Storage.SignedURLTTL)
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(expires) {
+		expires = *export.ExpiresAt
+	}
+	query := s.signer.Sign(exportResource(export.ID), expires)
+	return fmt.Sprintf("%s/api/v1/data-exports/%d/download?%s", s.cfg.AppURL, export.ID, query.Encode()), expires
+}
+
+func (s *privacyService) OpenSignedExport(ctx context.Context, id uint, expires, signature string) (*models.DataExport, io.ReadCloser, error) {
+	// 1. The link itself is the authorization
+	if err := s.signer.Verify(exportResource(id), expires, signature); err != nil {
+		return nil, nil, err
+	}
+
+	// 2. The export may have expired or been erased since the link was made
+	export, err := s.repo.GetDataExportByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != models.DataExportReady || export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		return nil, nil, ErrExportNotReady
+	}
+	content, _, err := s.backend.Get(ctx, export.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrExportNotReady
+	} else if err != nil {
+		return nil, nil, err
+	}
+	return export, content, nil
+}
+
+func exportResource(id uint) string {
+	return fmt.Sprintf("data-exports/%d", id)
+}
+
+func (s *privacyService) PurgeExpiredExports(ctx context.Context) (int, error) {
+	exports, err := s.repo.GetExpiredDataExports(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for i, export := range exports {
+		if err := s.deleteExport(ctx, export); err != nil {
+			return i, err
+		}
+	}
+	return len(exports), nil
+}
+
+func (s *privacyService) buildExport(ctx context.Context, export *models.DataExport, lang string) {
+	export.Status = models.DataExportProcessing
+	if err := s.repo.UpdateDataExport(ctx, export); err != nil {
+		log.Printf("data export %d: %v", export.ID, err)
+		return
+	}
+
+	data, err := s.repo.CollectUserData(ctx, export.UserID)
+	var (
+		sessions []Session
+		size     int64
+		key      string
+	)
+	if err == nil {
+		sessions, err = listSessions(ctx, s.rdb, export.UserID)
+	}
+	if err == nil {
+		size, key, err = s.storeArchive(ctx, export, data, sessions)
+	}
+	now := time.Now()
+	export.CompletedAt = &now
+	if err != nil {
+		log.Printf("data export %d failed: %v", export.ID, err)
+		export.Status = models.DataExportFailed
+		export.Error = "failed to build the archive"
+	} else {
+		expiresAt := now.Add(time.Hour * time.Duration(s.cfg.DataExportTTLHrs))
+		export.Status = models.DataExportReady
+		export.StorageKey = key
+		export.Size = size
+		export.ExpiresAt = &expiresAt
+	}
+	if err := s.repo.UpdateDataExport(ctx, export); err != nil {
+		log.Printf("data export %d: %v", export.ID, err)
+		return
+	}
+	if export.Status != models.DataExportReady {
+		return
+	}
+
+	// Let the user know, live if they are connected and by email otherwise
+	if err := s.publisher.Publish(ctx, realtime.UserTopic(export.UserID), EventDataExportReady, export); err != nil {
+		log.Printf("data export %d: failed to publish event: %v", export.ID, err)
+	}
+	if err := s.emailService.SendDataExportReadyEmail(data.User.Email, lang, *export.ExpiresAt); err != nil {
+		log.Printf("data export %d: failed to send email: %v", export.ID, err)
+	}
+}
+
+// storeArchive builds the archive in a temporary file and puts it in the storage
+// backend, returning its size and key
+func (s *privacyService) storeArchive(ctx context.Context, export *models.DataExport, data *repository.UserData, sessions []Session) (int64, string, error) {
+	file, err := os.CreateTemp("", "data-export-*.zip")
+	if err != nil {
+		return 0, "", err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := writeArchive(file, export.Format, data, sessions); err != nil {
+		return 0, "", err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, "", err
+	}
+
+	// A random part keeps keys unguessable even if the storage is ever exposed
+	suffix, err := utils.RandomToken(12)
+	if err != nil {
+		return 0, "", err
+	}
+	key := fmt.Sprintf("exports/%d/%d-%s.zip", export.UserID, export.ID, suffix)
+	if err := s.backend.Put(ctx, key, file, size, "application/zip"); err != nil {
+		return 0, "", err
+	}
+	return size, key, nil
+}
+
+// writeArchive writes the user's data and active sessions as a zip of JSON or CSV files
+func writeArchive(w io.Writer, format string, data *repository.UserData, sessions []Session) error {
+	sections := []struct {
+		name  string
+		items interface{}
+	}{
+		{"profile", []models.User{data.User}},
+		{"login_history", data.LoginEvents},
+		{"devices", data.KnownDevices},
+		{"sessions", sessions},
+		{"audit_log", data.AuditLogs},
+		{"oauth_consents", data.OAuthConsents},
+		{"email_changes", data.EmailChanges},
+		{"invitations_sent", data.Invitations},
+		{"files", data.Files},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, section := range sections {
+		w, err := archive.Create(section.name + "." + format)
+		if err != nil {
+			return err
+		}
+		if format == "csv" {
+			err = writeCSV(w, section.items)
+		} else {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(section.items)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// writeCSV flattens a slice of records through their JSON form, so the columns match
+// the JSON export. Nested values (e.g. roles) are written as JSON.
+func writeCSV(w io.Writer, items interface{}) error {
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return err
+	}
+
+	columns := map[string]bool{}
+	for _, row := range rows {
+		for key := range row {
+			columns[key] = true
+		}
+	}
+	header := make([]string, 0, len(columns))
+	for key := range columns {
+		header = append(header, key)
+	}
+	sort.Strings(header)
+
+	out := csv.NewWriter(w)
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for i, key := range header {
+			switch v := row[key].(type) {
+			case nil:
+			case string:
+				record[i] = v
+			case float64, bool:
+				record[i] = fmt.Sprint(v)
+			default:
+				nested, _ := json.Marshal(v)
+				record[i] = string(nested)
+			}
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func (s *privacyService) deleteExport(ctx context.Context, export models.DataExport) error {
+	if export.StorageKey != "" {
+		if err := s.backend.Delete(ctx, export.StorageKey); err != nil {
+			return err
+		}
+	}
+	return s.repo.DeleteDataExport(ctx, export.ID)
+}
+
+// ----------------------------------------------------------
+// ERASURE
+// ----------------------------------------------------------
+
+func (s *privacyService) EraseUser(ctx context.Context, actorID, userID uint, reason string) error {
+	// 1. Exports contain everything we are about to erase
+	exports, err := s.repo.GetDataExportsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if err := s.deleteExport(ctx, export); err != nil {
+			return err
+		}
+	}
+
+	// 2. Uploaded files live outside the database
+	if _, err := s.profiles.DeleteAvatar(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.files.DeleteUserFiles(ctx, userID); err != nil {
+		return err
+	}
+
+	// 3. Anonymize / delete the data across tables
+	if err := s.repo.EraseUser(ctx, userID); err != nil {
+		return err
+	}
+
+	// 4. End every session; open streams notice the new status on their next check
+	if err := revokeSessions(ctx, s.rdb, userID); err != nil {
+		return err
+	}
+	invalidateAccountStatus(ctx, s.rdb, userID)
+
+	// 5. Keep proof that the request was carried out, without any personal data
+	entry := &models.AuditLog{ActorID: actorID, UserID: userID, Action: "privacy.erasure", Details: reason}
+	if err := s.audit.Record(ctx, entry); err != nil {
+		log.Printf("failed to audit erasure of user %d: %v", userID, err)
+	}
+	return nil
+}
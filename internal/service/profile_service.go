@@ -0,0 +1,411 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	_ "time/tzdata" // timezones validate even on hosts without a zoneinfo database
+
+	"golang-api-template/internal/config"
+	"golang-api-template/internal/i18n"
+	"golang-api-template/internal/models"
+	"golang-api-template/internal/repository"
+	"golang-api-template/internal/storage"
+	"golang-api-template/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// PermissionManageProfileFields is required to define custom profile fields
+const PermissionManageProfileFields = "profiles.manage_fields"
+
+var (
+	ErrInvalidPhone         = errors.New("phone must be in international format, e.g. +14155552671")
+	ErrInvalidTimezone      = errors.New("unknown timezone")
+	ErrUnsupportedLocale    = errors.New("unsupported locale")
+	ErrInvalidCustomField   = errors.New("invalid custom field")
+	ErrInvalidProfileField  = errors.New("invalid profile field definition")
+	ErrProfileFieldKeyTaken = errors.New("a profile field with this key already exists")
+	ErrAvatarTooLarge       = errors.New("avatar file is too large")
+	ErrProfileFieldNotFound = errors.New("profile field not found")
+)
+
+var (
+	phonePattern    = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+)
+
+// CustomFieldError tells which custom field was rejected and why
+type CustomFieldError struct {
+	Key    string
+	Reason string
+}
+
+func (e *CustomFieldError) Error() string {
+	return fmt.Sprintf("custom field %q %s", e.Key, e.Reason)
+}
+
+func (e *CustomFieldError) Unwrap() error {
+	return ErrInvalidCustomField
+}
+
+// ProfileInput is a partial profile update: nil fields are left unchanged, and a
+// custom field set to null is cleared
+type ProfileInput struct {
+	DisplayName  *string                `json:"display_name"`
+	Phone        *string                `json:"phone"`
+	Timezone     *string                `json:"timezone"`
+	Locale       *string                `json:"locale"`
+	CustomFields map[string]interface{} `json:"custom_fields"`
+}
+
+// ProfileFieldInput defines or redefines a custom profile field
+type ProfileFieldInput struct {
+	Key       string   `json:"key" binding:"required"`
+	Label     string   `json:"label" binding:"required"`
+	Type      string   `json:"type" binding:"required"`
+	Required  bool     `json:"required"`
+	Options   []string `json:"options"`
+	MaxLength int      `json:"max_length"`
+}
+
+type ProfileService interface {
+	// GetProfile returns the user's profile, empty if they never saved one
+	GetProfile(ctx context.Context, userID uint) (*models.UserProfile, error)
+	UpdateProfile(ctx context.Context, userID uint, input ProfileInput) (*models.UserProfile, error)
+	// UploadAvatar validates, resizes and stores the image, replacing the previous avatar
+	UploadAvatar(ctx context.Context, userID uint, data []byte) (*models.UserProfile, error)
+	DeleteAvatar(ctx context.Context, userID uint) (*models.UserProfile, error)
+
+	GetProfileFields(ctx context.Context) ([]models.ProfileField, error)
+	CreateProfileField(ctx context.Context, input ProfileFieldInput) (*models.ProfileField, error)
+	UpdateProfileField(ctx context.Context, id uint, input ProfileFieldInput) (*models.ProfileField, error)
+	DeleteProfileField(ctx context.Context, id uint) error
+}
+
+type profileService struct {
+	repo  repository.ProfileRepository
+	files storage.Backend
+	cfg   *config.Config
+}
+
+func NewProfileService(repo repository.ProfileRepository, files storage.Backend, cfg *config.Config) ProfileService {
+	return &profileService{repo: repo, files: files, cfg: cfg}
+}
+
+// ----------------------------------------------------------
+// PROFILE
+// ----------------------------------------------------------
+
+func (s *profileService) GetProfile(ctx context.Context, userID uint) (*models.UserProfile, error) {
+	profile, err := s.repo.GetProfileByUserID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		profile = &models.UserProfile{UserID: userID, CustomFields: models.JSONMap{}}
+	} else if err != nil {
+		return nil, err
+	}
+	s.withAvatarURL(profile)
+	return profile, nil
+}
+
+func (s *profileService) UpdateProfile(ctx context.Context, userID uint, input ProfileInput) (*models.UserProfile, error) {
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Standard fields; empty strings clear them
+	if input.DisplayName != nil {
+		profile.DisplayName = strings.TrimSpace(*input.DisplayName)
+	}
+	if input.Phone != nil {
+		phone := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(*input.Phone)
+		if phone != "" && !phonePattern.MatchString(phone) {
+			return nil, ErrInvalidPhone
+		}
+		profile.Phone = phone
+	}
+	if input.Timezone != nil {
+		if *input.Timezone != "" {
+			if _, err := time.LoadLocation(*input.Timezone); err != nil || *input.Timezone == "Local" {
+				return nil, ErrInvalidTimezone
+			}
+		}
+		profile.Timezone = *input.Timezone
+	}
+	if input.Locale != nil {
+		if *input.Locale != "" && !i18n.IsSupported(*input.Locale) {
+			return nil, ErrUnsupportedLocale
+		}
+		profile.Locale = *input.Locale
+	}
+
+	// 2. Custom fields, checked against the admin defined schema
+	fields, err := s.repo.GetProfileFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+	custom, err := mergeCustomFields(fields, profile.CustomFields, input.CustomFields)
+	if err != nil {
+		return nil, err
+	}
+	profile.CustomFields = custom
+
+	// 3. Save
+	if err := s.repo.SaveProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// mergeCustomFields applies changes to current and validates the result. Values of
+// fields that were deleted since they were saved are dropped.
+func mergeCustomFields(fields []models.ProfileField, current models.JSONMap, changes map[string]interface{}) (models.JSONMap, error) {
+	schema := make(map[string]models.ProfileField, len(fields))
+	for _, field := range fields {
+		schema[field.Key] = field
+	}
+
+	merged := models.JSONMap{}
+	for key, value := range current {
+		if _, ok := schema[key]; ok {
+			merged[key] = value
+		}
+	}
+	for key, value := range changes {
+		field, ok := schema[key]
+		if !ok {
+			return nil, &CustomFieldError{Key: key, Reason: "is not defined"}
+		}
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		if err := validateCustomField(field, value); err != nil {
+			return nil, err
+		}
+		merged[key] = value
+	}
+
+	for _, field := range fields {
+		if value, ok := merged[field.Key]; field.Required && (!ok || value == "") {
+			return nil, &CustomFieldError{Key: field.Key, Reason: "is required"}
+		}
+	}
+	return merged, nil
+}
+
+func validateCustomField(field models.ProfileField, value interface{}) error {
+	invalid := func(reason string) error {
+		return &CustomFieldError{Key: field.Key, Reason: reason}
+	}
+
+	switch field.Type {
+	case models.ProfileFieldString:
+		str, ok := value.(string)
+		if !ok {
+			return invalid("must be a string")
+		}
+		if field.MaxLength > 0 && len([]rune(str)) > field.MaxLength {
+			return invalid(fmt.Sprintf("must be at most %d characters", field.MaxLength))
+		}
+	case models.ProfileFieldNumber:
+		if _, ok := value.(float64); !ok {
+			return invalid("must be a number")
+		}
+	case models.ProfileFieldBoolean:
+		if _, ok := value.(bool); !ok {
+			return invalid("must be true or false")
+		}
+	case models.ProfileFieldDate:
+		str, ok := value.(string)
+		if !ok {
+			return invalid("must be a date (YYYY-MM-DD)")
+		}
+		if _, err := time.Parse("2006-01-02", str); err != nil {
+			return invalid("must be a date (YYYY-MM-DD)")
+		}
+	case models.ProfileFieldEnum:
+		str, _ := value.(string)
+		for _, option := range field.Options {
+			if str == option {
+				return nil
+			}
+		}
+		return invalid("must be one of " + strings.Join(field.Options, ", "))
+	}
+	return nil
+}
+
+// ----------------------------------------------------------
+// AVATAR
+// ----------------------------------------------------------
+
+func (s *profileService) UploadAvatar(ctx context.Context, userID uint, data []byte) (*models.UserProfile, error) {
+	if int64(len(data)) > s.cfg.AvatarMaxBytes {
+		return nil, ErrAvatarTooLarge
+	}
+
+	// 1. Sniff, crop and resize
+	resized, contentType, err := resizeAvatar(data, s.cfg.AvatarSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Store under a new key, so caches never serve the previous image
+	suffix, err := utils.RandomToken(8)
+	if err != nil {
+		return nil, err
+	}
+	ext := "png"
+	if contentType == "image/jpeg" {
+		ext = "jpg"
+	}
+	key := fmt.Sprintf("public/avatars/%d/%s.%s", userID, suffix, ext)
+	if err := s.files.Put(ctx, key, bytes.NewReader(resized), int64(len(resized)), contentType); err != nil {
+		return nil, err
+	}
+
+	// 3. Point the profile at it and remove the old file
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	previous := profile.AvatarKey
+	profile.AvatarKey = key
+	if err := s.repo.SaveProfile(ctx, profile); err != nil {
+		s.removeAvatar(ctx, key)
+		return nil, err
+	}
+	s.removeAvatar(ctx, previous)
+
+	s.withAvatarURL(profile)
+	return profile, nil
+}
+
+func (s *profileService) DeleteAvatar(ctx context.Context, userID uint) (*models.UserProfile, error) {
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile.AvatarKey == "" {
+		return profile, nil
+	}
+
+	previous := profile.AvatarKey
+	profile.AvatarKey = ""
+	if err := s.repo.SaveProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	s.removeAvatar(ctx, previous)
+
+	s.withAvatarURL(profile)
+	return profile, nil
+}
+
+func (s *profileService) removeAvatar(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	if err := s.files.Delete(ctx, key); err != nil {
+		log.Printf("failed to delete avatar %s: %v", key, err)
+	}
+}
+
+func (s *profileService) withAvatarURL(profile *models.UserProfile) {
+	profile.AvatarURL = ""
+	if profile.AvatarKey != "" {
+		profile.AvatarURL = s.files.URL(profile.AvatarKey)
+	}
+}
+
+// ----------------------------------------------------------
+// CUSTOM FIELD SCHEMA
+// ----------------------------------------------------------
+
+func (s *profileService) GetProfileFields(ctx context.Context) ([]models.ProfileField, error) {
+	return s.repo.GetProfileFields(ctx)
+}
+
+func (s *profileService) CreateProfileField(ctx context.Context, input ProfileFieldInput) (*models.ProfileField, error) {
+	field := &models.ProfileField{}
+	if err := s.applyProfileField(ctx, field, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateProfileField(ctx, field); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+func (s *profileService) UpdateProfileField(ctx context.Context, id uint, input ProfileFieldInput) (*models.ProfileField, error) {
+	field, err := s.repo.GetProfileFieldByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProfileFieldNotFound
+		}
+		return nil, err
+	}
+	if err := s.applyProfileField(ctx, field, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateProfileField(ctx, field); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+func (s *profileService) DeleteProfileField(ctx context.Context, id uint) error {
+	if _, err := s.repo.GetProfileFieldByID(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrProfileFieldNotFound
+		}
+		return err
+	}
+	return s.repo.DeleteProfileField(ctx, id)
+}
+
+// applyProfileField validates the definition and copies it onto field
+func (s *profileService) applyProfileField(ctx context.Context, field *models.ProfileField, input ProfileFieldInput) error {
+	key := strings.ToLower(strings.TrimSpace(input.Key))
+	if !fieldKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: key must be lowercase letters, digits and underscores", ErrInvalidProfileField)
+	}
+	switch input.Type {
+	case models.ProfileFieldString, models.ProfileFieldNumber, models.ProfileFieldBoolean, models.ProfileFieldDate:
+		input.Options = nil
+	case models.ProfileFieldEnum:
+		if len(input.Options) == 0 {
+			return fmt.Errorf("%w: an enum needs options", ErrInvalidProfileField)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidProfileField, input.Type)
+	}
+	if input.MaxLength < 0 {
+		return fmt.Errorf("%w: max_length cannot be negative", ErrInvalidProfileField)
+	}
+
+	// Keys are unique
+	fields, err := s.repo.GetProfileFields(ctx)
+	if err != nil {
+		return err
+	}
+	for _, existing := range fields {
+		if existing.Key == key && existing.ID != field.ID {
+			return ErrProfileFieldKeyTaken
+		}
+	}
+
+	field.Key = key
+	field.Label = strings.TrimSpace(input.Label)
+	field.Type = input.Type
+	field.Required = input.Required
+	field.Options = input.Options
+	field.MaxLength = input.MaxLength
+	return nil
+}
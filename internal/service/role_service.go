@@ -1,46 +1,64 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"golang-api-template/internal/models"
 	"golang-api-template/internal/repository"
 )
 
+// RoleService is the generic CRUD for roles, whose hooks keep names unique and
+// sync the permissions sent with a role
 type RoleService interface {
-	CreateRole(role *models.Role) error
-	GetAllRoles() ([]models.Role, error)
-	GetRoleByID(id uint) (*models.Role, error)
-	UpdateRole(role *models.Role) error
-	DeleteRole(id uint) error
-	GetPermissionsByRoleID(roleID uint) ([]models.Permission, error)
+	Crud[models.Role]
+	GetPermissionsByRoleID(ctx context.Context, roleID uint) ([]models.Permission, error)
 }
 
+// PermissionManageRoles is required to create, change, delete and restore roles
+const PermissionManageRoles = "roles.manage"
+
+var ErrRoleNameTaken = errors.New("role name is already taken")
+
 type roleService struct {
+	Crud[models.Role]
 	repo repository.RoleRepository
 }
 
 func NewRoleService(repo repository.RoleRepository) RoleService {
-	return &roleService{repo}
-}
-
-func (s *roleService) CreateRole(role *models.Role) error {
-	return s.repo.CreateRole(role)
-}
-
-func (s *roleService) GetAllRoles() ([]models.Role, error) {
-	return s.repo.GetAllRoles()
+	s := &roleService{repo: repo}
+	s.Crud = NewCrud[models.Role](repo, CrudHooks[models.Role]{
+		BeforeCreate:  s.checkName,
+		AfterCreate:   s.syncPermissions,
+		BeforeUpdate:  s.checkName,
+		AfterUpdate:   s.syncPermissions,
+		BeforeRestore: s.checkName, // the name may have been reused in the meantime
+	})
+	return s
 }
 
-func (s *roleService) GetRoleByID(id uint) (*models.Role, error) {
-	return s.repo.GetRoleByID(id)
+func (s *roleService) GetPermissionsByRoleID(ctx context.Context, roleID uint) ([]models.Permission, error) {
+	return s.repo.GetPermissionsByRoleID(ctx, roleID)
 }
 
-func (s *roleService) UpdateRole(role *models.Role) error {
-	return s.repo.UpdateRole(role)
+// checkName rejects names held by another live role
+func (s *roleService) checkName(ctx context.Context, role *models.Role) error {
+	if role.Name == "" {
+		return fmt.Errorf("%w: role name is required", ErrInvalidInput)
+	}
+	existing, err := s.repo.GetRoleByName(ctx, role.Name)
+	if err == nil && existing.ID != role.ID {
+		return fmt.Errorf("%w: %w", ErrRoleNameTaken, ErrConflict)
+	}
+	return nil
 }
 
-func (s *roleService) DeleteRole(id uint) error {
-	return s.repo.DeleteRole(id)
-}
-func (s *roleService) GetPermissionsByRoleID(roleID uint) ([]models.Permission, error) {
-	return s.repo.GetPermissionsByRoleID(roleID)
+// syncPermissions makes the role's permissions exactly the ones sent by ID
+func (s *roleService) syncPermissions(ctx context.Context, role *models.Role) error {
+	ids := make([]uint, len(role.Permissions))
+	for i, perm := range role.Permissions {
+		ids[i] = perm.ID
+	}
+	return s.repo.ReplaceRolePermissions(ctx, role, ids)
 }
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// Session is a login that can still be refreshed: a first-party refresh token or the
+// refresh token of a relying party. Data exports list them; erasure revokes them.
+type Session struct {
+	ID              string     `json:"id"`
+	ClientID        string     `json:"client_id,omitempty"` // set for relying parties
+	Scope           string     `json:"scope,omitempty"`
+	IssuedAt        *time.Time `json:"issued_at,omitempty"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	AuthenticatedAt *time.Time `json:"authenticated_at,omitempty"`
+}
+
+// userRefreshTokensKey indexes the first-party refresh tokens of a user,
+// which are stored as token -> user ID
+func userRefreshTokensKey(userID uint) string {
+	return fmt.Sprintf("user:%d:refresh_tokens", userID)
+}
+
+// listSessions returns the sessions the user still holds, dropping expired
+// tokens from the indexes on the way
+func listSessions(ctx context.Context, rdb *redis.Client, userID uint) ([]Session, error) {
+	sessions := []Session{}
+
+	// 1. First-party refresh tokens; their claims tell when they were issued
+	indexKey := userRefreshTokensKey(userID)
+	tokens, err := rdb.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		if n, err := rdb.Exists(ctx, token).Result(); err != nil {
+			return nil, err
+		} else if n == 0 {
+			rdb.SRem(ctx, indexKey, token)
+			continue
+		}
+
+		claims := jwt.MapClaims{}
+		if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+			continue
+		}
+		jti, _ := claims["jti"].(string)
+		session := Session{ID: jti}
+		if iat, ok := claims["iat"].(float64); ok {
+			issuedAt := time.Unix(int64(iat), 0)
+			session.IssuedAt = &issuedAt
+		}
+		if exp, ok := claims["exp"].(float64); ok {
+			session.ExpiresAt = time.Unix(int64(exp), 0)
+		}
+		if authTime, ok := claims["auth_time"].(float64); ok {
+			at := time.Unix(int64(authTime), 0)
+			session.AuthenticatedAt = &at
+		}
+		sessions = append(sessions, session)
+	}
+
+	// 2. Refresh tokens of relying parties, indexed per client
+	err = forEachClientGrantSet(ctx, rdb, userID, func(grantsKey string) error {
+		keys, err := rdb.SMembers(ctx, grantsKey).Result()
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			payload, err := rdb.Get(ctx, key).Result()
+			if err == redis.Nil {
+				rdb.SRem(ctx, grantsKey, key)
+				continue
+			} else if err != nil {
+				return err
+			}
+			ttl, err := rdb.TTL(ctx, key).Result()
+			if err != nil {
+				return err
+			}
+
+			var grant refreshGrant
+			if err := json.Unmarshal([]byte(payload), &grant); err != nil {
+				continue
+			}
+			authenticatedAt := time.Unix(grant.AuthTime, 0)
+			sessions = append(sessions, Session{
+				ID:              strings.TrimPrefix(key, "oidc:refresh:"),
+				ClientID:        grant.ClientID,
+				Scope:           grant.Scope,
+				ExpiresAt:       time.Now().Add(ttl).Truncate(time.Second),
+				AuthenticatedAt: &authenticatedAt,
+			})
+		}
+		return nil
+	})
+	return sessions, err
+}
+
+// revokeSessions deletes every refresh token of the user, first-party and relying party
+func revokeSessions(ctx context.Context, rdb *redis.Client, userID uint) error {
+	indexKey := userRefreshTokensKey(userID)
+	tokens, err := rdb.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return err
+	}
+	if err := rdb.Del(ctx, append(tokens, indexKey)...).Err(); err != nil {
+		return err
+	}
+
+	return forEachClientGrantSet(ctx, rdb, userID, func(grantsKey string) error {
+		keys, err := rdb.SMembers(ctx, grantsKey).Result()
+		if err != nil {
+			return err
+		}
+		return rdb.Del(ctx, append(keys, grantsKey)...).Err()
+	})
+}
+
+// forEachClientGrantSet calls fn with the clientGrantsKey of every client the user
+// granted a refresh token to. Exports and erasures are rare enough for a SCAN.
+func forEachClientGrantSet(ctx context.Context, rdb *redis.Client, userID uint, fn func(grantsKey string) error) error {
+	iter := rdb.Scan(ctx, 0, fmt.Sprintf("oidc:grants:%d:*", userID), 100).Iterator()
+	for iter.Next(ctx) {
+		if err := fn(iter.Val()); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang-api-template/internal/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrInvalidStreamTicket = errors.New("stream ticket is invalid, expired or already used")
+
+// StreamTicketService hands out the short-lived, single-use tickets that browsers put
+// in the URL of a WebSocket or EventSource, since they cannot send an Authorization header.
+// Access tokens never appear in URLs, where proxies and access logs would keep them.
+type StreamTicketService interface {
+	// Issue stores the claims of the access token that asked for the ticket
+	Issue(ctx context.Context, claims jwt.MapClaims) (string, error)
+	// Redeem returns those claims and invalidates the ticket
+	Redeem(ctx context.Context, ticket string) (jwt.MapClaims, error)
+}
+
+type streamTicketService struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewStreamTicketService(rdb *redis.Client, ttl time.Duration) StreamTicketService {
+	return &streamTicketService{rdb: rdb, ttl: ttl}
+}
+
+func (s *streamTicketService) Issue(ctx context.Context, claims jwt.MapClaims) (string, error) {
+	ticket, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	if err := s.rdb.Set(ctx, streamTicketKey(ticket), payload, s.ttl).Err(); err != nil {
+		return "", fmt.Errorf("failed to store stream ticket: %w", err)
+	}
+	return ticket, nil
+}
+
+func (s *streamTicketService) Redeem(ctx context.Context, ticket string) (jwt.MapClaims, error) {
+	payload, err := s.rdb.GetDel(ctx, streamTicketKey(ticket)).Result()
+	if err == redis.Nil {
+		return nil, ErrInvalidStreamTicket
+	} else if err != nil {
+		return nil, err
+	}
+
+	var claims jwt.MapClaims
+	if err := json.Unmarshal([]byte(payload), &claims); err != nil {
+		return nil, err
+	}
+	// The stream may not outlive the access token the ticket was bought with
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, ErrInvalidStreamTicket
+	}
+	return claims, nil
+}
+
+func streamTicketKey(ticket string) string {
+	return "realtime:ticket:" + utils.HashToken(ticket)
+}
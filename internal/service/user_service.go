@@ -1,9 +1,11 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"golang-api-template/internal/config"
@@ -14,114 +16,158 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// PermissionManageUsers lets a user edit and restore other users' accounts
+const PermissionManageUsers = "users.manage"
+
+var ErrUserErased = errors.New("the user's data was erased, so the account cannot be restored")
+
+// UserService is the generic CRUD for users, whose hooks prepare new accounts and
+// keep emails unique, plus the account operations around it
 type UserService interface {
-	CreateUser(name, email, password string) (*models.User, error)
-	GetUserByID(id uint) (*models.User, error)
-	GetAllUsers(p utils.PaginationParams) ([]models.User, int64, error)
-	UpdateUser(id uint, name, email, password string) (*models.User, error)
-	DeleteUser(id uint) error
-	GetPermissionsByUserID(userID uint) ([]models.Permission, error)
-
-	FindByEmail(email string) (*models.User, error)
-	GeneratePasswordResetToken(user *models.User) (string, error)
-	ResetPassword(token, newPassword string) error
+	Crud[models.User]
+	CreateUser(ctx context.Context, name, email, password string) (*models.User, error)
+	UpdateUser(ctx context.Context, id uint, name, password string) (*models.User, error)
+	GetPermissionsByUserID(ctx context.Context, userID uint) ([]models.Permission, error)
+	HasPermission(ctx context.Context, userID uint, permission string) (bool, error)
+	GetUsersByIDs(ctx context.Context, ids []uint) ([]models.User, error)
+
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	GeneratePasswordResetToken(ctx context.Context, user *models.User) (string, error)
+	ResetPassword(ctx context.Context, token, newPassword string) error
 }
 
 type userService struct {
+	Crud[models.User]
 	repo repository.UserRepository
 }
 
 func NewUserService(r repository.UserRepository) UserService {
-	return &userService{
-		repo: r,
-	}
+	s := &userService{repo: r}
+	s.Crud = NewCrud[models.User](r, CrudHooks[models.User]{
+		BeforeCreate:  s.prepareNew,
+		BeforeRestore: s.checkRestorable,
+		AfterRestore:  s.reactivate,
+	})
+	return s
 }
 
 // CREATE
-func (s *userService) CreateUser(name, email, password string) (*models.User, error) {
-	// Check if user already exists by email
-	_, err := s.repo.GetUserByEmail(email)
-	if err == nil {
-		return nil, errors.New("email is already taken")
-	}
-
-	// Hash password
-	hashedPassword, err := utils.HashPassword(password)
-	if err != nil {
-		return nil, err
-	}
-
+func (s *userService) CreateUser(ctx context.Context, name, email, password string) (*models.User, error) {
 	user := &models.User{
 		Name:     name,
 		Email:    email,
-		Password: string(hashedPassword),
+		Password: password,
 	}
-
-	err = s.repo.CreateUser(user)
-	if err != nil {
+	if err := s.Create(ctx, user); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-// READ (single)
-func (s *userService) GetUserByID(id uint) (*models.User, error) {
-	return s.repo.GetUserByID(id)
-}
-
-// READ (all with pagination)
-func (s *userService) GetAllUsers(p utils.PaginationParams) ([]models.User, int64, error) {
-	return s.repo.GetUsers(p)
-}
-
 // UPDATE
-func (s *userService) UpdateUser(id uint, name, email, password string) (*models.User, error) {
+// UpdateUser changes the name and password. Email changes go through
+// EmailChangeService so the new address has to be confirmed first.
+func (s *userService) UpdateUser(ctx context.Context, id uint, name, password string) (*models.User, error) {
 	// 1. Find the user
-	user, err := s.repo.GetUserByID(id)
+	user, err := s.Get(ctx, id)
 	if err != nil {
-		return nil, errors.New("user not found")
-	}
-
-	// 2. Update fields
-	user.Name = name
-	// Check if new email is already taken by another user
-	if user.Email != email {
-		if _, err := s.repo.GetUserByEmail(email); err == nil {
-			return nil, errors.New("email is already taken by another user")
-		}
-		user.Email = email
+		return nil, err
 	}
 
+	// 2. Hash the new password, if there is one
+	var hashedPassword string
 	if password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 		if err != nil {
 			return nil, err
 		}
-		user.Password = string(hashedPassword)
+		hashedPassword = string(hashed)
 	}
 
-	// 3. Save updated user
-	err = s.repo.UpdateUser(user)
-	if err != nil {
+	// 3. Write only these columns; a full-row update would overwrite a suspension or
+	// deletion request made since the user was read
+	if err := s.repo.UpdateNameAndPassword(ctx, id, name, hashedPassword); err != nil {
 		return nil, err
 	}
+	user.Name = name
+	if hashedPassword != "" {
+		user.Password = hashedPassword
+	}
 	return user, nil
 }
 
-// DELETE
-func (s *userService) DeleteUser(id uint) error {
-	return s.repo.DeleteUser(id)
+// prepareNew normalises the email of a new user, checks it is free and hashes the password
+func (s *userService) prepareNew(ctx context.Context, user *models.User) error {
+	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
+	if err := s.checkEmail(ctx, user); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	return nil
+}
+
+// checkEmail rejects emails held by another live user
+func (s *userService) checkEmail(ctx context.Context, user *models.User) error {
+	existing, err := s.repo.GetUserByEmail(ctx, user.Email)
+	if err == nil && existing.ID != user.ID {
+		return fmt.Errorf("%w: %w", ErrEmailTaken, ErrConflict)
+	}
+	return nil
+}
+
+// checkRestorable refuses erased users, and users whose email belongs to a new account by now
+func (s *userService) checkRestorable(ctx context.Context, user *models.User) error {
+	if user.StatusReason == models.UserErasedReason {
+		return fmt.Errorf("%w: %w", ErrUserErased, ErrConflict)
+	}
+	return s.checkEmail(ctx, user)
+}
+
+// reactivate makes sure the scheduled deletion purge does not pick a restored user up again
+func (s *userService) reactivate(ctx context.Context, user *models.User) error {
+	if user.Status != models.UserStatusPendingDeletion {
+		return nil
+	}
+	if err := s.repo.UpdateStatus(ctx, user.ID, models.UserStatusActive, "restored", nil, 0); err != nil {
+		return err
+	}
+	user.Status = models.UserStatusActive
+	user.StatusReason = "restored"
+	user.DeletionScheduledAt = nil
+	return nil
+}
+
+func (s *userService) GetPermissionsByUserID(ctx context.Context, userID uint) ([]models.Permission, error) {
+	return s.repo.GetPermissionsByUserID(ctx, userID)
+}
+
+func (s *userService) HasPermission(ctx context.Context, userID uint, permission string) (bool, error) {
+	permissions, err := s.repo.GetPermissionsByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, perm := range permissions {
+		if perm.Name == permission {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
-func (s *userService) GetPermissionsByUserID(userID uint) ([]models.Permission, error) {
-	return s.repo.GetPermissionsByUserID(userID)
+func (s *userService) GetUsersByIDs(ctx context.Context, ids []uint) ([]models.User, error) {
+	return s.repo.GetUsersByIDs(ctx, ids)
 }
 
-func (s *userService) FindByEmail(email string) (*models.User, error) {
-	return s.repo.FindByEmail(email)
+func (s *userService) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.repo.FindByEmail(ctx, email)
 }
 
-func (s *userService) GeneratePasswordResetToken(user *models.User) (string, error) {
+func (s *userService) GeneratePasswordResetToken(ctx context.Context, user *models.User) (string, error) {
 	b := make([]byte, 16)
 	_, err := rand.Read(b)
 	if err != nil {
@@ -131,19 +177,19 @@ func (s *userService) GeneratePasswordResetToken(user *models.User) (string, err
 	expiry := time.Now().Add(config.GetResetTokenExpiry()) // Assuming config package has this method
 
 	// Store the token in the database with an expiration time
-	if err := s.repo.SaveResetToken(user.ID, token, expiry); err != nil {
+	if err := s.repo.SaveResetToken(ctx, user.ID, token, expiry); err != nil {
 		return "", err // handle database error
 	}
 
 	return token, nil
 }
-func (s *userService) ResetPassword(token, newPassword string) error {
-	user, err := s.repo.FindByToken(token)
+func (s *userService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	user, err := s.repo.FindByToken(ctx, token)
 	if err != nil {
 		return err
 	}
 
 	// Additional checks, such as token expiration, can be implemented here
 	hashedPassword, _ := utils.HashPassword(newPassword) // Assuming you have a HashPassword method
-	return s.repo.UpdatePassword(user.ID, hashedPassword)
+	return s.repo.UpdatePassword(ctx, user.ID, hashedPassword)
 }
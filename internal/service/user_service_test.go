@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang-api-template/internal/dbtest"
+	"golang-api-template/internal/repository"
+)
+
+// TestRestoreErasedUser checks that erasure cannot be undone through the trash: the
+// anonymized row of an erased user is soft-deleted, but restoring it is refused
+func TestRestoreErasedUser(t *testing.T) {
+	db := dbtest.Open(t)
+	users := NewUserService(repository.NewUserRepository(db))
+	ctx := context.Background()
+
+	erased, err := users.CreateUser(ctx, "Erased", "erase-me@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.NewPrivacyRepository(db).EraseUser(ctx, erased.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Restore(ctx, erased.ID); !errors.Is(err, ErrUserErased) || !errors.Is(err, ErrConflict) {
+		t.Fatalf("Restore of an erased user: got %v, want ErrUserErased", err)
+	}
+	if _, err := users.Get(ctx, erased.ID); err == nil {
+		t.Fatal("the erased user is live again")
+	}
+
+	// Users who were only deleted can still be restored
+	deleted, err := users.CreateUser(ctx, "Deleted", "deleted@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Delete(ctx, deleted.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Restore(ctx, deleted.ID); err != nil {
+		t.Fatalf("Restore of a deleted user: %v", err)
+	}
+}
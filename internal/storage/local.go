@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// localBackend keeps files on the local disk. Public keys are served by the router
+// as static files; everything else through signed links to the API.
+type localBackend struct {
+	dir       string
+	publicURL string
+	signedURL string
+	signer    *Signer
+}
+
+func NewLocal(dir, publicURL, signedURL string, signer *Signer) (Backend, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &localBackend{
+		dir:       dir,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		signedURL: strings.TrimRight(signedURL, "/"),
+		signer:    signer,
+	}, nil
+}
+
+func (b *localBackend) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	dest, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+func (b *localBackend) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	info, err := b.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, _ := b.path(key)
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+	return file, info, nil
+}
+
+func (b *localBackend) Delete(_ context.Context, key string) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (b *localBackend) Stat(_ context.Context, key string) (*ObjectInfo, error) {
+	p, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if fi.IsDir() {
+		return nil, ErrNotFound
+	}
+	return objectInfo(key, fi), nil
+}
+
+func (b *localBackend) List(_ context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.WalkDir(b.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(b.dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, *objectInfo(key, fi))
+		return nil
+	})
+	return objects, err
+}
+
+// Presign links to the API's signed storage route
+func (b *localBackend) Presign(_ context.Context, key string, ttl time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	query := b.signer.Sign("storage/"+key, time.Now().Add(ttl))
+	return b.signedURL + "/" + key + "?" + query.Encode(), nil
+}
+
+func (b *localBackend) URL(key string) string {
+	return b.publicURL + "/" + key
+}
+
+func (b *localBackend) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(b.dir, filepath.FromSlash(key)), nil
+}
+
+func objectInfo(key string, fi fs.FileInfo) *ObjectInfo {
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &ObjectInfo{Key: key, Size: fi.Size(), ContentType: contentType, ModTime: fi.ModTime()}
+}
+
+func notFound(err error) error {
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
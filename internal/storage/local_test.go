@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalBackend(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewLocal(filepath.Join(dir, "storage"), "http://localhost/static", "http://localhost/api/v1/storage", NewSigner("secret"))
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	testBackend(t, b)
+
+	// Nothing was written outside the storage directory
+	if _, err := os.Stat(filepath.Join(dir, "escape")); !os.IsNotExist(err) {
+		t.Fatalf("a file escaped the storage directory: %v", err)
+	}
+}
+
+func TestLocalPresign(t *testing.T) {
+	signer := NewSigner("secret")
+	b, err := NewLocal(t.TempDir(), "http://localhost/static", "http://localhost/api/v1/storage", signer)
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	content := []byte("private")
+	if err := b.Put(context.Background(), "uploads/1/doc.txt", bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	link, err := b.Presign(context.Background(), "uploads/1/doc.txt", time.Minute)
+	if err != nil {
+		t.Fatalf("Presign: %v", err)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", link, err)
+	}
+	if u.Path != "/api/v1/storage/uploads/1/doc.txt" {
+		t.Fatalf("Presign path = %q", u.Path)
+	}
+
+	// The storage route verifies the link against the same resource
+	query := u.Query()
+	if err := signer.Verify("storage/uploads/1/doc.txt", query.Get("expires"), query.Get("signature")); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err := signer.Verify("storage/uploads/1/other.txt", query.Get("expires"), query.Get("signature")); err != ErrInvalidSignature {
+		t.Fatalf("link verified for another key: %v", err)
+	}
+
+	if got := b.URL("public/avatars/1.png"); !strings.HasPrefix(got, "http://localhost/static/public/avatars/1.png") {
+		t.Fatalf("URL = %q", got)
+	}
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"golang-api-template/internal/config"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// newTestS3 creates a throwaway bucket on the S3-compatible server in
+// STORAGE_TEST_S3_ENDPOINT, e.g. a local MinIO:
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	STORAGE_TEST_S3_ENDPOINT=localhost:9000 go test ./internal/storage
+func newTestS3(t *testing.T) *s3Backend {
+	t.Helper()
+	endpoint := os.Getenv("STORAGE_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("STORAGE_TEST_S3_ENDPOINT is not set")
+	}
+	cfg := &config.S3Config{
+		Endpoint:  endpoint,
+		Region:    "us-east-1",
+		Bucket:    fmt.Sprintf("storage-test-%d", time.Now().UnixNano()),
+		AccessKey: envOr("STORAGE_TEST_S3_ACCESS_KEY", "minioadmin"),
+		SecretKey: envOr("STORAGE_TEST_S3_SECRET_KEY", "minioadmin"),
+		UseSSL:    os.Getenv("STORAGE_TEST_S3_USE_SSL") == "true",
+		PathStyle: true,
+	}
+	backend, err := NewS3(cfg, "")
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	b := backend.(*s3Backend)
+
+	ctx := context.Background()
+	if err := b.client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+		t.Fatalf("MakeBucket: %v", err)
+	}
+	t.Cleanup(func() {
+		for obj := range b.client.ListObjects(ctx, cfg.Bucket, minio.ListObjectsOptions{Recursive: true}) {
+			b.client.RemoveObject(ctx, cfg.Bucket, obj.Key, minio.RemoveObjectOptions{})
+		}
+		if err := b.client.RemoveBucket(ctx, cfg.Bucket); err != nil {
+			t.Logf("removing bucket %s: %v", cfg.Bucket, err)
+		}
+	})
+	return b
+}
+
+func TestS3Backend(t *testing.T) {
+	testBackend(t, newTestS3(t))
+}
+
+func TestS3Presign(t *testing.T) {
+	b := newTestS3(t)
+	ctx := context.Background()
+	content := []byte("private")
+	if err := b.Put(ctx, "uploads/1/doc.txt", bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	link, err := b.Presign(ctx, "uploads/1/doc.txt", time.Minute)
+	if err != nil {
+		t.Fatalf("Presign: %v", err)
+	}
+	res, err := http.Get(link)
+	if err != nil {
+		t.Fatalf("GET %s: %v", link, err)
+	}
+	got, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || !bytes.Equal(got, content) {
+		t.Fatalf("GET presigned link = %d %q", res.StatusCode, got)
+	}
+
+	// Without the signature the private bucket refuses the download
+	res, err = http.Get(b.URL("uploads/1/doc.txt"))
+	if err != nil {
+		t.Fatalf("GET %s: %v", b.URL("uploads/1/doc.txt"), err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("GET unsigned URL = %d, want 403", res.StatusCode)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignerVerify(t *testing.T) {
+	signer := NewSigner("secret")
+	query := signer.Sign("files/42", time.Now().Add(time.Minute))
+	expires, signature := query.Get("expires"), query.Get("signature")
+	tampered := []byte(signature)
+	tampered[0] ^= 1
+
+	if err := signer.Verify("files/42", expires, signature); err != nil {
+		t.Fatalf("Verify of a fresh link: %v", err)
+	}
+
+	cases := map[string]struct {
+		signer    *Signer
+		resource  string
+		expires   string
+		signature string
+	}{
+		"other resource":      {signer, "files/43", expires, signature},
+		"extended expiry":     {signer, "files/42", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10), signature},
+		"tampered signature":  {signer, "files/42", expires, string(tampered)},
+		"missing signature":   {signer, "files/42", expires, ""},
+		"other secret":        {NewSigner("other"), "files/42", expires, signature},
+		"non-numeric expires": {signer, "files/42", "soon", signer.mac("files/42", "soon")},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := c.signer.Verify(c.resource, c.expires, c.signature); !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("got %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestSignerExpiry(t *testing.T) {
+	signer := NewSigner("secret")
+	query := signer.Sign("files/42", time.Now().Add(-time.Second))
+
+	if err := signer.Verify("files/42", query.Get("expires"), query.Get("signature")); !errors.Is(err, ErrURLExpired) {
+		t.Fatalf("got %v, want ErrURLExpired", err)
+	}
+}
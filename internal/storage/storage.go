@@ -0,0 +1,71 @@
+// Package storage abstracts where uploaded files (avatars, attachments) are kept.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"golang-api-template/internal/config"
+)
+
+// PublicPrefix marks keys that are served to anyone without a signed URL
+const PublicPrefix = "public/"
+
+var (
+	ErrInvalidKey = errors.New("invalid storage key")
+	ErrNotFound   = errors.New("file not found in storage")
+)
+
+// ObjectInfo describes a stored file
+type ObjectInfo struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	ModTime     time.Time `json:"mod_time"`
+}
+
+// Backend stores files under slash separated keys such as "uploads/12/abc"
+type Backend interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns ErrNotFound if the key does not exist; the caller closes the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// Delete succeeds if the key does not exist
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Presign returns a URL that allows downloading the file until ttl has passed
+	Presign(ctx context.Context, key string, ttl time.Duration) (string, error)
+	// URL returns the permanent URL of a key under PublicPrefix
+	URL(key string) string
+}
+
+// New returns the backend selected by STORAGE_DRIVER
+func New(cfg *config.StorageConfig) (Backend, error) {
+	switch cfg.Driver {
+	case "local", "":
+		publicURL := cfg.PublicURL
+		if publicURL == "" {
+			publicURL = cfg.AppURL + "/files"
+		}
+		signer := NewSigner(cfg.SigningSecret)
+		return NewLocal(cfg.LocalDir, publicURL, cfg.AppURL+"/api/v1/storage", signer)
+	case "s3":
+		return NewS3(cfg.S3, cfg.PublicURL)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// cleanKey rejects keys that could escape the storage root
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if key == "" || cleaned != key || strings.HasPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCleanKey(t *testing.T) {
+	valid := []string{
+		"avatar.png",
+		"uploads/12/abc",
+		"public/avatars/7.png",
+		"a..b/c",
+	}
+	for _, key := range valid {
+		if got, err := cleanKey(key); err != nil || got != key {
+			t.Errorf("cleanKey(%q) = %q, %v; want the key back", key, got, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"/etc/passwd",
+		"../secret",
+		"uploads/../../secret",
+		"uploads/./abc",
+		"uploads//abc",
+		"uploads/abc/",
+		".",
+		"..",
+	}
+	for _, key := range invalid {
+		if got, err := cleanKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("cleanKey(%q) = %q, %v; want ErrInvalidKey", key, got, err)
+		}
+	}
+}
+
+// testBackend runs the behaviour every Backend must share against b
+func testBackend(t *testing.T, b Backend) {
+	ctx := context.Background()
+	content := []byte("hello storage")
+
+	// Put and Get
+	if err := b.Put(ctx, "uploads/1/hello.txt", bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	r, info, err := b.Get(ctx, "uploads/1/hello.txt")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("reading object: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("Get returned %q, want %q", got, content)
+	}
+	if info.Key != "uploads/1/hello.txt" || info.Size != int64(len(content)) {
+		t.Fatalf("Get info = %+v", info)
+	}
+
+	// Stat
+	info, err = b.Stat(ctx, "uploads/1/hello.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != int64(len(content)) || info.ContentType == "" || info.ModTime.IsZero() {
+		t.Fatalf("Stat info = %+v", info)
+	}
+
+	// List only returns keys under the prefix
+	other := []byte("other")
+	if err := b.Put(ctx, "uploads/2/other.txt", bytes.NewReader(other), int64(len(other)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	objects, err := b.List(ctx, "uploads/1/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != "uploads/1/hello.txt" {
+		t.Fatalf("List(uploads/1/) = %+v", objects)
+	}
+	objects, err = b.List(ctx, "uploads/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("List(uploads/) returned %d objects, want 2", len(objects))
+	}
+
+	// Delete, also of keys that do not exist
+	for _, key := range []string{"uploads/1/hello.txt", "uploads/2/other.txt", "uploads/3/missing.txt"} {
+		if err := b.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete(%s): %v", key, err)
+		}
+	}
+	if _, err := b.Stat(ctx, "uploads/1/hello.txt"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat after Delete = %v, want ErrNotFound", err)
+	}
+	if _, _, err := b.Get(ctx, "uploads/1/hello.txt"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete = %v, want ErrNotFound", err)
+	}
+
+	// Keys that could escape the storage root are refused everywhere
+	if err := b.Put(ctx, "../escape", bytes.NewReader(content), int64(len(content)), "text/plain"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Put(../escape) = %v, want ErrInvalidKey", err)
+	}
+	if _, err := b.Presign(ctx, "uploads/../../escape", 0); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Presign(uploads/../../escape) = %v, want ErrInvalidKey", err)
+	}
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "context"
+
+// ContextKey is the type of the values this app stores in a context.Context; a type
+// of its own keeps them apart from the keys of other packages
+type ContextKey string
+
+// AuthIDKey holds the ID of the authenticated user, set by middlewares.AuthMiddleware
+const AuthIDKey ContextKey = "AuthID"
+
+// WithAuthID returns a copy of ctx carrying the authenticated user's ID
+func WithAuthID(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, AuthIDKey, userID)
+}
+
+// AuthID returns the authenticated user's ID, or 0 outside authenticated requests
+func AuthID(ctx context.Context) uint {
+	userID, _ := ctx.Value(AuthIDKey).(uint)
+	return userID
+}
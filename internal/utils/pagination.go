@@ -2,22 +2,81 @@ package utils
 
 import (
 	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
 
+	"golang-api-template/internal/config"
 	"golang-api-template/pkg/response"
 
 	"github.com/gin-gonic/gin"
 )
 
-// PaginationParams holds the request query parameters for paging.
+// Settings from PaginationConfig; ConfigurePagination replaces them at startup
+var (
+	defaultLimit     = 10
+	maxLimit         = 100
+	paginationFormat = config.PaginationFormatMinimal
+	cursorSecret     = []byte("cursor-secret-example")
+	baseURL          = "http://localhost:8080"
+)
+
+// ConfigurePagination applies the page size limits, envelope format, cursor secret and base URL
+func ConfigurePagination(cfg *config.PaginationConfig) {
+	if cfg.DefaultLimit > 0 {
+		defaultLimit = cfg.DefaultLimit
+	}
+	if cfg.MaxLimit > 0 {
+		maxLimit = cfg.MaxLimit
+	}
+	paginationFormat = cfg.Format
+	if cfg.CursorSecret != "" {
+		cursorSecret = []byte(cfg.CursorSecret)
+	}
+	if cfg.BaseURL != "" {
+		baseURL = cfg.BaseURL
+	}
+}
+
+// PaginationParams holds the request query parameters for paging, filtering and sorting.
 type PaginationParams struct {
 	Page  int
 	Limit int
-	Query string
+	Query string // free text search from `q`
+
+	Filters []Filter
+	Sort    []SortField
+
+	// Keyset pagination: UseCursor is set when the request has a `cursor` parameter
+	// (empty for the first page), Cursor is the token from a previous response
+	UseCursor bool
+	Cursor    string
+
+	// Total is how the total is computed: TotalExact, TotalEstimate or TotalNone.
+	// Page-number requests default to exact counts, cursor requests to none.
+	Total string
+}
+
+// Values of the `total` query parameter
+const (
+	TotalExact    = "exact"
+	TotalEstimate = "estimate" // cheap, approximate count from table statistics
+	TotalNone     = "none"
+)
+
+// PageInfo describes the page a list query returned
+type PageInfo struct {
+	Total          *int64 // nil when not counted
+	TotalEstimated bool
+	NextCursor     string // empty on the last page
+	PrevCursor     string // empty on the first page
 }
 
 // ParsePagination reads `page` and `limit` from the query string
-// (e.g., ?page=2&limit=10). Defaults are page=1, limit=10 if not provided.
+// (e.g., ?page=2&limit=10). Defaults are page=1 and the configured default limit;
+// limits above PAGINATION_MAX_LIMIT are lowered to it.
+// It also reads `q`, `sort=-created_at,name` and `filter[field][op]=value`;
+// repositories validate those against their whitelist of fields.
 func ParsePagination(c *gin.Context) PaginationParams {
 	page, _ := strconv.Atoi(c.Query("page"))
 	limit, _ := strconv.Atoi(c.Query("limit"))
@@ -26,12 +85,30 @@ func ParsePagination(c *gin.Context) PaginationParams {
 		page = 1
 	}
 	if limit < 1 {
-		limit = 10
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	cursor, useCursor := c.GetQuery("cursor")
+
+	total := c.Query("total")
+	if total != TotalExact && total != TotalEstimate && total != TotalNone {
+		total = TotalExact
+		if useCursor {
+			total = TotalNone
+		}
 	}
 
 	return PaginationParams{
-		Page:  page,
-		Limit: limit,
+		Page:      page,
+		Limit:     limit,
+		Query:     strings.TrimSpace(c.Query("q")),
+		Filters:   parseFilters(c.Request.URL.Query()),
+		Sort:      parseSort(c.Query("sort")),
+		UseCursor: useCursor,
+		Cursor:    cursor,
+		Total:     total,
 	}
 }
 
@@ -40,95 +117,184 @@ func (p PaginationParams) Offset() int {
 	return (p.Page - 1) * p.Limit
 }
 
-// LaravelPaginatedResponse is a helper to return a JSON response
-// resembling Laravel's pagination structure.
+// PaginatedResponse is the response of every list endpoint.
 //
 // - httpCode: HTTP status code (e.g., 200, 400).
 // - message: short message (e.g., "List of users").
 // - items: the slice/array of data items for the current page.
-// - currentPage: the current page number (e.g. p.Page).
-// - perPage: items shown per page (e.g. p.Limit).
-// - total: total number of records across all pages.
-// - basePath: the base URL path for constructing prev/next links (e.g., "/api/v1/users").
+// - p: the parsed request parameters (page, limit, cursor).
+// - info: the total and cursors returned by the repository.
+//
+// Links to the first/prev/next/last pages are absolute URLs under APP_URL, or under
+// the address given by the X-Forwarded-* headers of a trusted proxy (see
+// RequestBaseURL), and are also sent in an RFC 8288 `Link` header. The body uses the PAGINATION_FORMAT envelope:
 //
-// Additional fields included:
+// "minimal": {"items": [...], "pagination": {"page", "limit", "total", "next_cursor", "prev_cursor"}}
+//
+// "laravel", for page-number requests Laravel's LengthAwarePaginator fields:
 // - `from`: starting record number in this page
 // - `to`: last record number in this page
 // - `last_page`: total number of pages
 // - `prev_page_url` / `next_page_url`
 // - `first_page_url` / `last_page_url`
+// and for cursor requests Laravel's CursorPaginator fields:
+// `next_cursor`, `prev_cursor`, `next_page_url`, `prev_page_url`, plus `total` when counted.
 func PaginatedResponse(
 	c *gin.Context,
 	httpCode int,
 	message string,
 	items interface{},
-	currentPage int,
-	perPage int,
-	total int,
-	basePath string,
+	p PaginationParams,
+	info PageInfo,
 ) {
-	// Avoid division by zero
-	if perPage < 1 {
-		perPage = 1
+	links := buildPageLinks(c, items, p, info)
+	if header := links.header(); header != "" {
+		c.Header("Link", header)
 	}
 
-	lastPage := (total + perPage - 1) / perPage // simple ceiling division
-
-	// from and to represent the item indices (in a 1-based index sense)
-	from := (currentPage-1)*perPage + 1
-	to := from + perPage - 1
-	if to > total {
-		to = total
-	}
-	if from > total {
-		from = 0
-		to = 0
+	var data gin.H
+	if paginationFormat == config.PaginationFormatLaravel {
+		data = laravelPage(c, items, p, info, links)
+	} else {
+		data = minimalPage(items, p, info)
 	}
+	response.Success(c, httpCode, message, data)
+}
 
-	// Build URLs
-	// We'll assume query params remain the same except for `page`.
-	// You can get the query string from c.Request.URL.Query().
-	// For simplicity, let's just create minimal links.
-	var prevPageURL, nextPageURL, firstPageURL, lastPageURL string
+// pageLinks are the absolute URLs of the neighbouring pages; empty when there is none
+type pageLinks struct {
+	path, first, prev, next, last string
+	lastPage                      int
+}
 
-	// We'll keep the existing query parameters, but replace "page" only.
+func buildPageLinks(c *gin.Context, items interface{}, p PaginationParams, info PageInfo) pageLinks {
+	// We'll keep the existing query parameters, replacing only the page or cursor
+	path := RequestBaseURL(c) + c.Request.URL.Path
 	queryParams := c.Request.URL.Query()
+	withParam := func(key, value string) string {
+		queryParams.Set(key, value)
+		return fmt.Sprintf("%s?%s", path, queryParams.Encode())
+	}
+	links := pageLinks{path: path}
 
-	// first page
-	queryParams.Set("page", "1")
-	firstPageURL = fmt.Sprintf("%s?%s", basePath, queryParams.Encode())
+	if p.UseCursor {
+		queryParams.Del("page")
+		if info.NextCursor != "" {
+			links.next = withParam("cursor", info.NextCursor)
+		}
+		if info.PrevCursor != "" {
+			links.prev = withParam("cursor", info.PrevCursor)
+		}
+		links.first = withParam("cursor", "")
+		return links
+	}
 
-	// last page
-	queryParams.Set("page", strconv.Itoa(lastPage))
-	lastPageURL = fmt.Sprintf("%s?%s", basePath, queryParams.Encode())
+	pageURL := func(page int) string { return withParam("page", strconv.Itoa(page)) }
+	links.first = pageURL(1)
+	if p.Page > 1 {
+		links.prev = pageURL(p.Page - 1)
+	}
+	if info.Total == nil {
+		// Without a total we only know there is a next page when this one is full
+		if itemCount(items) >= p.Limit {
+			links.next = pageURL(p.Page + 1)
+		}
+		return links
+	}
 
-	// prev page
-	if currentPage > 1 {
-		queryParams.Set("page", strconv.Itoa(currentPage-1))
-		prevPageURL = fmt.Sprintf("%s?%s", basePath, queryParams.Encode())
+	links.lastPage = int((*info.Total + int64(p.Limit) - 1) / int64(p.Limit)) // simple ceiling division
+	if links.lastPage < 1 {
+		links.lastPage = 1
+	}
+	links.last = pageURL(links.lastPage)
+	if p.Page < links.lastPage {
+		links.next = pageURL(p.Page + 1)
 	}
+	return links
+}
 
-	// next page
-	if currentPage < lastPage {
-		queryParams.Set("page", strconv.Itoa(currentPage+1))
-		nextPageURL = fmt.Sprintf("%s?%s", basePath, queryParams.Encode())
+// header formats the links as an RFC 8288 Link header
+func (l pageLinks) header() string {
+	var parts []string
+	for _, link := range []struct{ rel, url string }{
+		{"first", l.first}, {"prev", l.prev}, {"next", l.next}, {"last", l.last},
+	} {
+		if link.url != "" {
+			parts = append(parts, fmt.Sprintf(`<%s>; rel="%s"`, link.url, link.rel))
+		}
 	}
+	return strings.Join(parts, ", ")
+}
+
+func minimalPage(items interface{}, p PaginationParams, info PageInfo) gin.H {
+	meta := gin.H{"limit": p.Limit}
+	if p.UseCursor {
+		meta["next_cursor"] = info.NextCursor
+		meta["prev_cursor"] = info.PrevCursor
+	} else {
+		meta["page"] = p.Page
+	}
+	if info.Total != nil {
+		meta["total"] = *info.Total
+		if info.TotalEstimated {
+			meta["total_estimated"] = true
+		}
+	}
+	return gin.H{"items": items, "pagination": meta}
+}
 
-	// Construct response data in Laravel style
+// laravelPage builds Laravel's paginator envelope
+func laravelPage(c *gin.Context, items interface{}, p PaginationParams, info PageInfo, links pageLinks) gin.H {
 	data := gin.H{
-		"current_page":   currentPage,
-		"data":           items,
-		"first_page_url": firstPageURL,
-		"from":           from,
-		"last_page":      lastPage,
-		"last_page_url":  lastPageURL,
-		"next_page_url":  nextPageURL,
-		"path":           basePath,
-		"per_page":       perPage,
-		"prev_page_url":  prevPageURL,
-		"to":             to,
-		"total":          total,
+		"data":          items,
+		"path":          links.path,
+		"per_page":      p.Limit,
+		"next_page_url": nullableURL(links.next),
+		"prev_page_url": nullableURL(links.prev),
+	}
+	if info.Total != nil {
+		data["total"] = *info.Total
+		if info.TotalEstimated {
+			data["total_estimated"] = true
+		}
+	}
+	if p.UseCursor {
+		data["next_cursor"] = nullableURL(info.NextCursor)
+		data["prev_cursor"] = nullableURL(info.PrevCursor)
+		return data
 	}
 
-	response.Success(c, httpCode, message, data)
+	data["current_page"] = p.Page
+	data["first_page_url"] = links.first
+	if info.Total == nil {
+		return data
+	}
+
+	// from and to represent the item indices (in a 1-based index sense)
+	var from, to interface{}
+	if count := itemCount(items); count > 0 {
+		from = p.Offset() + 1
+		to = p.Offset() + count
+	}
+	data["from"] = from
+	data["to"] = to
+	data["last_page"] = links.lastPage
+	data["last_page_url"] = links.last
+	return data
+}
+
+// nullableURL turns "no link" into JSON null, as Laravel does
+func nullableURL(link string) interface{} {
+	if link == "" {
+		return nil
+	}
+	return link
+}
+
+func itemCount(items interface{}) int {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return 0
+	}
+	return v.Len()
 }
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trustedProxies are the networks set by ConfigureTrustedProxies
+var trustedProxies []*net.IPNet
+
+// ConfigureTrustedProxies sets the reverse proxies whose X-Forwarded-* headers
+// RequestBaseURL believes; entries are IPs or CIDRs, as for gin's SetTrustedProxies
+func ConfigureTrustedProxies(proxies []string) error {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("trusted proxy %q is not an IP or CIDR", proxy)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("trusted proxy %q is not an IP or CIDR", proxy)
+		}
+		networks = append(networks, network)
+	}
+	trustedProxies = networks
+	return nil
+}
+
+// fromTrustedProxy reports whether the request's direct peer is a trusted proxy
+func fromTrustedProxy(c *gin.Context) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestBaseURL returns the scheme, host and path prefix the client used to reach
+// the API. Behind a trusted proxy these come from its X-Forwarded-Proto, -Host and
+// -Prefix headers; otherwise, and for headers the proxy does not send, from APP_URL.
+func RequestBaseURL(c *gin.Context) string {
+	if !fromTrustedProxy(c) {
+		return baseURL
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+
+	if proto := forwardedHeader(c, "X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		base.Scheme = proto
+	}
+	if host := forwardedHeader(c, "X-Forwarded-Host"); host != "" {
+		base.Host = host
+	}
+	if prefix := strings.TrimRight(forwardedHeader(c, "X-Forwarded-Prefix"), "/"); prefix != "" {
+		if !strings.HasPrefix(prefix, "/") {
+			prefix = "/" + prefix
+		}
+		base.Path = prefix
+	}
+	return strings.TrimRight(base.String(), "/")
+}
+
+// forwardedHeader returns the first (client-facing) value of a proxy header
+func forwardedHeader(c *gin.Context, name string) string {
+	value, _, _ := strings.Cut(c.GetHeader(name), ",")
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Filter operators accepted in `filter[field][op]=value`
+const (
+	FilterEq   = "eq"
+	FilterNe   = "ne"
+	FilterLike = "like" // contains, ignoring case
+	FilterGt   = "gt"
+	FilterGte  = "gte"
+	FilterLt   = "lt"
+	FilterLte  = "lte"
+	FilterIn   = "in"   // comma separated values
+	FilterNull = "null" // "true" or "false"
+)
+
+// Filter is one `filter[field][op]=value` query parameter
+type Filter struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// SortField is one entry of `sort=-created_at,name`
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+var filterParam = regexp.MustCompile(`^filter\[([A-Za-z0-9_]+)\](?:\[([A-Za-z]+)\])?$`)
+
+// parseFilters reads every `filter[field]=value` (meaning eq) and `filter[field][op]=value`
+// parameter. Fields and operators are checked later against the resource's whitelist.
+func parseFilters(query url.Values) []Filter {
+	var filters []Filter
+	for key, values := range query {
+		m := filterParam.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+		op := strings.ToLower(m[2])
+		if op == "" {
+			op = FilterEq
+		}
+		for _, value := range values {
+			filters = append(filters, Filter{Field: m[1], Operator: op, Value: value})
+		}
+	}
+	// Map iteration is random; a stable order keeps the generated SQL (and query plans) stable
+	sort.Slice(filters, func(i, j int) bool {
+		if filters[i].Field != filters[j].Field {
+			return filters[i].Field < filters[j].Field
+		}
+		return filters[i].Operator < filters[j].Operator
+	})
+	return filters
+}
+
+// parseSort reads `sort=-created_at,name`; a leading "-" sorts descending
+func parseSort(value string) []SortField {
+	var fields []SortField
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		fields = append(fields, field)
+	}
+	return fields
+}
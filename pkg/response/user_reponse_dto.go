@@ -2,8 +2,19 @@ package response
 
 // AuthUserResponse is the structure for the authenticated user's response
 type AuthUserResponse struct {
-	ID    uint   `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID      uint             `json:"id"`
+	Name    string           `json:"name"`
+	Email   string           `json:"email"`
+	Profile *ProfileResponse `json:"profile"`
 	// Add any other fields you need to send in the response
 }
+
+// ProfileResponse is the user's profile as embedded in AuthUserResponse
+type ProfileResponse struct {
+	DisplayName  string                 `json:"display_name"`
+	Phone        string                 `json:"phone"`
+	Timezone     string                 `json:"timezone"`
+	Locale       string                 `json:"locale"`
+	AvatarURL    string                 `json:"avatar_url"`
+	CustomFields map[string]interface{} `json:"custom_fields"`
+}